@@ -0,0 +1,380 @@
+package apikeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gcli2apigo/internal/storage"
+	"gcli2apigo/internal/usage"
+)
+
+// Errors returned by Authorize
+var (
+	ErrKeyInactive          = errors.New("API key is revoked or expired")
+	ErrModelNotAllowed      = errors.New("model is not allowed for this API key")
+	ErrRequestQuotaExceeded = errors.New("daily request quota exceeded for this API key")
+	ErrTokenQuotaExceeded   = errors.New("daily token quota exceeded for this API key")
+)
+
+// ErrKeyNotFound is returned by Update, Revoke and Delete for an unknown key ID
+var ErrKeyNotFound = errors.New("API key not found")
+
+// idPrefix marks identities returned for named keys by auth.AuthenticateUser
+const idPrefix = "key_"
+
+// APIKey is a named API key issued to a team or client.
+// The key itself is never stored; only its SHA-256 hash is kept on disk.
+type APIKey struct {
+	ID                string     `json:"id"`
+	Label             string     `json:"label"`
+	KeyHash           string     `json:"key_hash"`
+	KeyPrefix         string     `json:"key_prefix"`               // First characters of the key, for display
+	DailyRequestLimit int        `json:"daily_request_limit"`      // 0 means unlimited
+	DailyTokenLimit   int        `json:"daily_token_limit"`        // 0 means unlimited
+	AllowedModels     []string   `json:"allowed_models,omitempty"` // Empty means all models; "prefix*" matches by prefix
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	Revoked           bool       `json:"revoked"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+}
+
+// KeyOptions holds the editable settings of an API key
+type KeyOptions struct {
+	Label             string     `json:"label"`
+	DailyRequestLimit int        `json:"daily_request_limit"`
+	DailyTokenLimit   int        `json:"daily_token_limit"`
+	AllowedModels     []string   `json:"allowed_models"`
+	ExpiresAt         *time.Time `json:"expires_at"`
+}
+
+// IsActive reports whether the key is neither revoked nor expired
+func (k *APIKey) IsActive() bool {
+	if k.Revoked {
+		return false
+	}
+	return k.ExpiresAt == nil || time.Now().Before(*k.ExpiresAt)
+}
+
+// AllowsModel reports whether the key may be used with the given model
+func (k *APIKey) AllowsModel(model string) bool {
+	if len(k.AllowedModels) == 0 {
+		return true
+	}
+
+	model = strings.TrimPrefix(model, "models/")
+	for _, allowed := range k.AllowedModels {
+		allowed = strings.TrimPrefix(allowed, "models/")
+		if prefix, ok := strings.CutSuffix(allowed, "*"); ok {
+			if strings.HasPrefix(model, prefix) {
+				return true
+			}
+		} else if model == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyStore manages named API keys
+type KeyStore struct {
+	keys   map[string]*APIKey // Keyed by ID
+	byHash map[string]string  // Key hash -> ID
+	mu     sync.RWMutex
+	saveMu sync.Mutex // Held from snapshot to write so an older snapshot never overwrites a newer one
+	store  storage.Store
+}
+
+var (
+	globalKeyStore *KeyStore
+	keyStoreOnce   sync.Once
+)
+
+// GetKeyStore returns the global API key store instance
+func GetKeyStore() *KeyStore {
+	keyStoreOnce.Do(func() {
+		globalKeyStore = NewKeyStore()
+		globalKeyStore.Load()
+	})
+	return globalKeyStore
+}
+
+// NewKeyStore creates a new API key store
+func NewKeyStore() *KeyStore {
+	return &KeyStore{
+		keys:   make(map[string]*APIKey),
+		byHash: make(map[string]string),
+		store:  storage.GetStore(),
+	}
+}
+
+// hashKey returns the hex-encoded SHA-256 hash of a key
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomHex returns n random bytes encoded as hex
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Create issues a new API key. The plaintext key is returned once and cannot be recovered later.
+func (ks *KeyStore) Create(opts KeyOptions) (string, *APIKey, error) {
+	if strings.TrimSpace(opts.Label) == "" {
+		return "", nil, fmt.Errorf("label is required")
+	}
+
+	secret, err := randomHex(24)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate key: %v", err)
+	}
+	idSuffix, err := randomHex(6)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate key ID: %v", err)
+	}
+
+	plaintext := "gcli-" + secret
+	key := &APIKey{
+		ID:        idPrefix + idSuffix,
+		KeyHash:   hashKey(plaintext),
+		KeyPrefix: plaintext[:9],
+		CreatedAt: time.Now(),
+	}
+	applyOptions(key, opts)
+
+	ks.mu.Lock()
+	ks.keys[key.ID] = key
+	ks.byHash[key.KeyHash] = key.ID
+	copied := *key
+	ks.mu.Unlock()
+
+	log.Printf("[INFO] Created API key %s (%s)", key.ID, key.Label)
+	return plaintext, &copied, ks.Save()
+}
+
+// Update changes the label, quotas, allowed models and expiry of a key
+func (ks *KeyStore) Update(id string, opts KeyOptions) (*APIKey, error) {
+	ks.mu.Lock()
+	key, exists := ks.keys[id]
+	if !exists {
+		ks.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+	applyOptions(key, opts)
+	copied := *key
+	ks.mu.Unlock()
+
+	log.Printf("[INFO] Updated API key %s (%s)", id, copied.Label)
+	return &copied, ks.Save()
+}
+
+// applyOptions copies editable settings onto a key
+func applyOptions(key *APIKey, opts KeyOptions) {
+	if label := strings.TrimSpace(opts.Label); label != "" {
+		key.Label = label
+	}
+	key.DailyRequestLimit = max(opts.DailyRequestLimit, 0)
+	key.DailyTokenLimit = max(opts.DailyTokenLimit, 0)
+	key.AllowedModels = opts.AllowedModels
+	key.ExpiresAt = opts.ExpiresAt
+}
+
+// Revoke disables a key without deleting its record
+func (ks *KeyStore) Revoke(id string) error {
+	ks.mu.Lock()
+	key, exists := ks.keys[id]
+	if !exists {
+		ks.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+	now := time.Now()
+	key.Revoked = true
+	key.RevokedAt = &now
+	ks.mu.Unlock()
+
+	log.Printf("[INFO] Revoked API key: %s", id)
+	return ks.Save()
+}
+
+// Delete removes a key permanently
+func (ks *KeyStore) Delete(id string) error {
+	ks.mu.Lock()
+	key, exists := ks.keys[id]
+	if !exists {
+		ks.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+	delete(ks.byHash, key.KeyHash)
+	delete(ks.keys, id)
+	ks.mu.Unlock()
+
+	log.Printf("[INFO] Deleted API key: %s", id)
+	return ks.Save()
+}
+
+// Get returns a copy of the key with the given ID
+func (ks *KeyStore) Get(id string) (*APIKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	key, exists := ks.keys[id]
+	if !exists {
+		return nil, false
+	}
+	copied := *key
+	return &copied, true
+}
+
+// Lookup returns a copy of the active key matching the plaintext key
+func (ks *KeyStore) Lookup(plaintext string) (*APIKey, bool) {
+	if plaintext == "" {
+		return nil, false
+	}
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	id, exists := ks.byHash[hashKey(plaintext)]
+	if !exists {
+		return nil, false
+	}
+	key := ks.keys[id]
+	if !key.IsActive() {
+		return nil, false
+	}
+	copied := *key
+	return &copied, true
+}
+
+// List returns copies of all keys ordered by creation time
+func (ks *KeyStore) List() []APIKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	keys := make([]APIKey, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		keys = append(keys, *key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys
+}
+
+// IsKeyID reports whether an identity returned by auth.AuthenticateUser refers to a named key
+func IsKeyID(identity string) bool {
+	return strings.HasPrefix(identity, idPrefix)
+}
+
+// Authorize checks a named key's allowed models and daily quotas before a request is sent.
+// It returns the key ID to attribute usage to, or "" when the identity is not a named key
+// (e.g. the shared PASSWORD), which is not subject to quotas.
+func (ks *KeyStore) Authorize(identity string, model string) (string, error) {
+	if !IsKeyID(identity) {
+		return "", nil
+	}
+
+	key, exists := ks.Get(identity)
+	if !exists {
+		// e.g. a key deleted after the request was authenticated
+		return "", nil
+	}
+	if !key.IsActive() {
+		return "", ErrKeyInactive
+	}
+	if !key.AllowsModel(model) {
+		return "", ErrModelNotAllowed
+	}
+
+	keyUsage := usage.GetTracker().GetKeyUsage(key.ID)
+	if key.DailyRequestLimit > 0 && keyUsage.RequestCount >= key.DailyRequestLimit {
+		return "", ErrRequestQuotaExceeded
+	}
+	if key.DailyTokenLimit > 0 && keyUsage.TokenCount >= key.DailyTokenLimit {
+		return "", ErrTokenQuotaExceeded
+	}
+
+	return key.ID, nil
+}
+
+// HTTPStatus maps an Authorize error to an HTTP status code
+func HTTPStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrKeyInactive):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrModelNotAllowed):
+		return http.StatusForbidden
+	case errors.Is(err, ErrRequestQuotaExceeded), errors.Is(err, ErrTokenQuotaExceeded):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Save persists the API keys to the store
+func (ks *KeyStore) Save() error {
+	ks.saveMu.Lock()
+	defer ks.saveMu.Unlock()
+
+	// Marshal to JSON
+	ks.mu.RLock()
+	data, err := json.MarshalIndent(ks.keys, "", "  ")
+	ks.mu.RUnlock()
+	if err != nil {
+		log.Printf("[ERROR] Failed to marshal API keys: %v", err)
+		return err
+	}
+
+	if err := ks.store.SaveState(map[string][]byte{storage.StateAPIKeys: data}); err != nil {
+		log.Printf("[ERROR] Failed to write API keys: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// Load reads the API keys from the store
+func (ks *KeyStore) Load() error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	data, _, err := ks.store.LoadState(storage.StateAPIKeys)
+	if errors.Is(err, storage.ErrNotFound) {
+		log.Printf("[INFO] API key file does not exist, starting fresh")
+		return nil
+	}
+	if err != nil {
+		log.Printf("[ERROR] Failed to read API keys: %v", err)
+		return err
+	}
+
+	// Unmarshal JSON
+	keys := make(map[string]*APIKey)
+	if err := json.Unmarshal(data, &keys); err != nil {
+		log.Printf("[ERROR] Failed to unmarshal API keys: %v", err)
+		return err
+	}
+
+	ks.keys = keys
+	ks.byHash = make(map[string]string, len(keys))
+	for id, key := range keys {
+		ks.byHash[key.KeyHash] = id
+	}
+
+	log.Printf("[INFO] Loaded %d API keys", len(ks.keys))
+	return nil
+}
@@ -0,0 +1,457 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gcli2apigo/internal/banlist"
+	"gcli2apigo/internal/config"
+
+	"golang.org/x/oauth2"
+)
+
+// The stress tests below are meant to be run with the race detector:
+//
+//	go test -race ./internal/auth/
+
+func TestMain(m *testing.M) {
+	// The ban list, usage tracker and credential files all live under the working directory
+	dir, err := os.MkdirTemp("", "gcli2apigo-auth-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := os.Chdir(dir); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	config.OAuthCredsFolder = filepath.Join(dir, "oauth_creds")
+	config.ScriptDir = dir
+	os.Setenv("CREDENTIALS_WATCH_INTERVAL_SECONDS", "0")
+	os.Setenv("CREDENTIAL_RATE_LIMIT_RPS", "1000")
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// stressIterations returns how many operations each worker of a stress test performs
+func stressIterations() int {
+	if testing.Short() {
+		return 50
+	}
+	return 300
+}
+
+func testToken(accessToken string, expiry time.Time) *oauth2.Token {
+	token := &oauth2.Token{
+		AccessToken:  accessToken,
+		RefreshToken: "refresh",
+		TokenType:    "Bearer",
+		Expiry:       expiry,
+	}
+	return token.WithExtra(map[string]interface{}{
+		"client_id":     "client",
+		"client_secret": "secret",
+		"token_uri":     "",
+	})
+}
+
+func testEntry(index int) *CredentialEntry {
+	projectID := fmt.Sprintf("stress-project-%d", index)
+	return NewCredentialEntry(testToken("initial", time.Now().Add(time.Hour)), projectID, projectID+".json")
+}
+
+// writeCredentialFile writes a credential file in the format ValidateCredential reads
+func writeCredentialFile(t *testing.T, folder string, projectID string) string {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{
+		"client_id":     "client",
+		"client_secret": "secret",
+		"refresh_token": "refresh",
+		"project_id":    projectID,
+		"token":         "initial",
+		"expiry":        time.Now().Add(time.Hour).Format(time.RFC3339),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(folder, projectID+".json")
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// checkEntry reports a selected credential that is not fully formed
+func checkEntry(t *testing.T, entry *CredentialEntry) {
+	if entry == nil {
+		t.Error("selected credential is nil")
+		return
+	}
+	token := entry.Token()
+	if token == nil || token.RefreshToken == "" || entry.ProjectID == "" {
+		t.Errorf("selected credential %q has an incomplete token", entry.FilePath)
+	}
+}
+
+func TestSyncCredentialsKeepsUnchangedEntries(t *testing.T) {
+	pool := NewCredentialPool()
+	kept, changed, dropped := testEntry(1), testEntry(2), testEntry(3)
+	for _, entry := range []*CredentialEntry{kept, changed, dropped} {
+		if err := pool.AddCredential(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The in-memory token of kept was refreshed after its file was read
+	refreshed := testToken("refreshed", time.Now().Add(2*time.Hour))
+	kept.SetToken(refreshed)
+
+	changedOnDisk := testEntry(2)
+	changedOnDisk.SetToken(&oauth2.Token{RefreshToken: "rotated"})
+	added := testEntry(4)
+
+	a, u, r := pool.SyncCredentials([]*CredentialEntry{testEntry(1), changedOnDisk, added})
+	if a != 1 || u != 1 || r != 1 {
+		t.Fatalf("SyncCredentials = %d added, %d updated, %d removed; want 1, 1, 1", a, u, r)
+	}
+
+	credentials := pool.Credentials()
+	if len(credentials) != 3 {
+		t.Fatalf("pool has %d credentials, want 3", len(credentials))
+	}
+	if credentials[0] != kept || kept.Token() != refreshed {
+		t.Error("unchanged credential was replaced or lost its refreshed token")
+	}
+	if credentials[1] != changedOnDisk {
+		t.Error("changed credential was not replaced")
+	}
+	if credentials[2] != added {
+		t.Error("new credential was not appended")
+	}
+}
+
+func TestCredentialPoolConcurrentReloadRefreshBanSelect(t *testing.T) {
+	const credentialCount = 8
+	base := make([]*CredentialEntry, credentialCount)
+	for i := range base {
+		base[i] = testEntry(i)
+	}
+
+	pool := NewCredentialPool()
+	pool.SyncCredentials(base)
+
+	iterations := stressIterations()
+	bl := banlist.GetBanList()
+	strategies := []SelectionStrategy{StrategyQuotaAware, StrategyRoundRobin, StrategyRandom, StrategyLeastUsed}
+	var wg sync.WaitGroup
+
+	// Reloads alternate between the full set, a subset and a set with rewritten files
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			switch i % 3 {
+			case 0:
+				pool.SyncCredentials(base)
+			case 1:
+				pool.SyncCredentials(base[:credentialCount/2])
+			default:
+				rewritten := make([]*CredentialEntry, credentialCount)
+				for j := range rewritten {
+					rewritten[j] = testEntry(j)
+					rewritten[j].SetToken(testToken(fmt.Sprintf("disk-%d", i), time.Now().Add(3*time.Hour)))
+				}
+				pool.SyncCredentials(rewritten)
+			}
+		}
+	}()
+
+	// Refreshes replace the tokens of whatever credentials are currently in the pool
+	for w := 0; w < 2; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				for _, entry := range pool.Credentials() {
+					old := entry.Token()
+					entry.SetToken(testToken(fmt.Sprintf("refreshed-%d-%d", w, i), old.Expiry.Add(time.Minute)))
+				}
+			}
+		}(w)
+	}
+
+	// Bans and unbans credentials while they are being selected
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations/5; i++ {
+			projectID := base[i%credentialCount].ProjectID
+			bl.Ban(projectID, banlist.BanOptions{Reason: "stress"})
+			bl.Unban(projectID)
+		}
+	}()
+
+	// Selections with every strategy, with and without excluded credentials
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			strategy := strategies[w%len(strategies)]
+			for i := 0; i < iterations; i++ {
+				exclude := map[string]bool{base[i%credentialCount].ProjectID: true}
+				entry, err := pool.SelectCredential("gemini-2.5-pro", strategy, exclude)
+				if err != nil {
+					// Every credential may be banned for a moment
+					continue
+				}
+				checkEntry(t, entry)
+				_ = entry.Token().AccessToken
+				pool.HasQuotaForModel("gemini-2.5-flash")
+				if available, total := pool.GetAvailableCredentialCount(), pool.Size(); available > total {
+					t.Errorf("available credentials %d exceed total %d", available, total)
+				}
+			}
+		}(w)
+	}
+
+	wg.Wait()
+
+	// A final full reload restores every credential
+	pool.SyncCredentials(base)
+	if size := pool.Size(); size != credentialCount {
+		t.Fatalf("pool has %d credentials after final reload, want %d", size, credentialCount)
+	}
+}
+
+func TestOnboardUserConcurrentWithRefresh(t *testing.T) {
+	// loadCodeAssist reports the project as onboarded; the token endpoint hands out new tokens
+	var tokenRequests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/token" {
+			tokenRequests.Add(1)
+			fmt.Fprint(w, `{"access_token":"onboard-refreshed","token_type":"Bearer","expires_in":3600}`)
+			return
+		}
+		fmt.Fprint(w, `{"currentTier":{"id":"free-tier"}}`)
+	}))
+	defer server.Close()
+	t.Setenv("GEMINI_API_ENDPOINT", server.URL)
+	t.Setenv("OAUTH2_ENDPOINT", server.URL)
+
+	const credentialCount = 4
+	entries := make([]*CredentialEntry, credentialCount)
+	for i := range entries {
+		entries[i] = testEntry(200 + i)
+		entries[i].SetToken(testToken("expired", time.Now().Add(-time.Hour)))
+	}
+
+	iterations := stressIterations() / 5
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	// Refreshes replace the tokens until onboarding is finished, expired again every other time
+	refreshed := make(chan struct{})
+	go func() {
+		defer close(refreshed)
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			for _, entry := range entries {
+				expiry := time.Now().Add(time.Hour)
+				if i%2 == 0 {
+					expiry = time.Now().Add(-time.Hour)
+				}
+				entry.SetToken(testToken(fmt.Sprintf("refreshed-%d", i), expiry))
+			}
+		}
+	}()
+
+	// The request path onboards with whatever token it read, expired or not, and clears the
+	// onboarding cache after a 401 so every call reaches the server
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				entry := entries[(w+i)%credentialCount]
+				token := entry.Token()
+				accessToken := token.AccessToken
+				if err := OnboardUser(token, entry.ProjectID); err != nil {
+					t.Errorf("OnboardUser: %v", err)
+					return
+				}
+				if token.AccessToken != accessToken {
+					t.Error("OnboardUser modified the shared token")
+				}
+				ResetOnboardingState()
+			}
+		}(w)
+	}
+
+	wg.Wait()
+	close(done)
+	<-refreshed
+
+	if n := tokenRequests.Load(); n != 0 {
+		t.Errorf("OnboardUser refreshed tokens %d times; refreshing is left to the refresh manager", n)
+	}
+}
+
+func TestRateLimitedPoolConcurrentSelectWhileReloading(t *testing.T) {
+	const credentialCount = 6
+	base := make([]*CredentialEntry, credentialCount)
+	for i := range base {
+		base[i] = testEntry(100 + i)
+	}
+
+	limitedPool := NewRateLimitedCredentialPool(time.Millisecond)
+	limitedPool.SyncCredentials(base)
+
+	iterations := stressIterations()
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			if i%2 == 0 {
+				limitedPool.SyncCredentials(base[1:])
+			} else {
+				limitedPool.SyncCredentials(base)
+			}
+			if i%50 == 0 {
+				limitedPool.ResetRateLimits()
+			}
+		}
+	}()
+
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				entry, err := limitedPool.SelectCredentialWithRateLimit("gemini-2.5-flash", StrategyRoundRobin, nil)
+				if err != nil {
+					t.Errorf("SelectCredentialWithRateLimit: %v", err)
+					return
+				}
+				checkEntry(t, entry)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestGlobalPoolConcurrentReloadRefreshSaveSelect(t *testing.T) {
+	folder := config.OAuthCredsFolder
+	if err := os.MkdirAll(folder, 0700); err != nil {
+		t.Fatal(err)
+	}
+	const credentialCount = 5
+	for i := 0; i < credentialCount; i++ {
+		writeCredentialFile(t, folder, fmt.Sprintf("global-project-%d", i))
+	}
+
+	if err := InitializeCredentialPool(); err != nil {
+		t.Fatal(err)
+	}
+	if total := GetCredentialPoolTotal(); total != credentialCount {
+		t.Fatalf("initialized pool has %d credentials, want %d", total, credentialCount)
+	}
+
+	iterations := stressIterations() / 5
+	var wg sync.WaitGroup
+	var selections atomic.Int64
+
+	// Dashboard reloads and watcher reloads run at the same time
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			ReloadCredentialPool()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			syncCredentialPool("watcher")
+		}
+	}()
+
+	// A provisioning job adds and removes a credential file
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			path := writeCredentialFile(t, folder, "global-project-extra")
+			os.Remove(path)
+		}
+	}()
+
+	// Requests refresh the token of the credential they selected and persist it
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			entry, err := GetCredentialForModel("gemini-2.5-flash", nil)
+			if err != nil {
+				t.Errorf("GetCredentialForModel: %v", err)
+				return
+			}
+			entry.SetToken(testToken(fmt.Sprintf("saved-%d", i), time.Now().Add(time.Hour+time.Duration(i)*time.Second)))
+			if err := SaveRefreshedToken(entry); err != nil && entry.FilePath != filepath.Join(folder, "global-project-extra.json") {
+				t.Errorf("SaveRefreshedToken: %v", err)
+			}
+		}
+	}()
+
+	for w := 0; w < 3; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations*2; i++ {
+				entry, err := GetCredentialForModel("gemini-2.5-pro", map[string]bool{"global-project-0": true})
+				if err != nil {
+					t.Errorf("GetCredentialForModel: %v", err)
+					return
+				}
+				checkEntry(t, entry)
+				selections.Add(1)
+				if GetCredentialPoolSize() > GetCredentialPoolTotal()+1 {
+					t.Error("available credentials exceed the pool size")
+				}
+				HasQuotaForModel("gemini-2.5-pro")
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// Saved tokens must leave every credential file readable
+	ReloadCredentialPool()
+	if total := GetCredentialPoolTotal(); total != credentialCount {
+		t.Fatalf("pool has %d credentials after the stress run, want %d", total, credentialCount)
+	}
+	if selections.Load() == 0 {
+		t.Fatal("no credentials were selected")
+	}
+}
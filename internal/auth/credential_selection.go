@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"strings"
+
+	"gcli2apigo/internal/banlist"
+	"gcli2apigo/internal/breaker"
+	"gcli2apigo/internal/config"
+	"gcli2apigo/internal/usage"
+)
+
+// SelectionStrategy controls the order in which credentials are tried for a request
+type SelectionStrategy string
+
+const (
+	// StrategyRoundRobin cycles through credentials in pool order
+	StrategyRoundRobin SelectionStrategy = "round-robin"
+	// StrategyRandom picks a random credential for each request
+	StrategyRandom SelectionStrategy = "random"
+	// StrategyLeastUsed prefers the credential with the fewest requests today for the model class
+	StrategyLeastUsed SelectionStrategy = "least-used"
+	// StrategyQuotaAware round-robins over credentials that still have daily quota for the model,
+	// skipping exhausted ones; when all are exhausted it falls back to the least-used credential
+	StrategyQuotaAware SelectionStrategy = "quota-aware"
+)
+
+// GetSelectionStrategy returns the configured credential selection strategy.
+// Unknown values fall back to quota-aware.
+func GetSelectionStrategy() SelectionStrategy {
+	value := strings.ToLower(strings.TrimSpace(config.GetCredentialSelectionStrategy()))
+	switch strategy := SelectionStrategy(value); strategy {
+	case StrategyRoundRobin, StrategyRandom, StrategyLeastUsed, StrategyQuotaAware:
+		return strategy
+	default:
+		log.Printf("[WARN] Unknown CREDENTIAL_SELECTION_STRATEGY %q, using %s", value, StrategyQuotaAware)
+		return StrategyQuotaAware
+	}
+}
+
+// usageCount returns today's request count of a project for the class of the given model
+func usageCount(stats *usage.ProjectUsage, isProModel bool) int {
+	if isProModel {
+		return stats.ProModelCount
+	}
+	return stats.OverallCount
+}
+
+// isQuotaExhausted reports whether a project has used up its daily quota for the model class.
+// Counters reset at the tracker's next reset time, so exhausted credentials become eligible again then.
+func isQuotaExhausted(stats *usage.ProjectUsage, isProModel bool) bool {
+	if stats.OverallCount >= usage.OverallDailyLimit {
+		return true
+	}
+	return isProModel && stats.ProModelCount >= usage.ProModelDailyLimit
+}
+
+// isSelectable reports whether a credential is neither banned nor cooling down in the circuit breaker
+func isSelectable(projectID string) bool {
+	return !banlist.GetBanList().IsBanned(projectID) && breaker.GetCircuitBreaker().Allow(projectID)
+}
+
+// availableCredentials returns the selectable credentials not listed in exclude.
+// If every selectable credential is excluded, all selectable credentials are returned
+// so the caller can detect that it has run out of untried credentials.
+func (cp *CredentialPool) availableCredentials(exclude map[string]bool) []*CredentialEntry {
+	credentials := cp.snapshot()
+	selectable := make([]*CredentialEntry, 0, len(credentials))
+	untried := make([]*CredentialEntry, 0, len(credentials))
+	for _, cred := range credentials {
+		if !isSelectable(cred.ProjectID) {
+			continue
+		}
+		selectable = append(selectable, cred)
+		if !exclude[cred.ProjectID] {
+			untried = append(untried, cred)
+		}
+	}
+
+	if len(untried) == 0 {
+		return selectable
+	}
+	return untried
+}
+
+// orderCredentials returns the candidates in the order the strategy prefers them.
+// offset rotates the starting point for the round-robin based strategies.
+// The quota-aware strategy leaves out exhausted credentials unless no others remain.
+func (cp *CredentialPool) orderCredentials(candidates []*CredentialEntry, modelName string, strategy SelectionStrategy, offset int) []*CredentialEntry {
+	ordered := make([]*CredentialEntry, 0, len(candidates))
+	if len(candidates) == 0 {
+		return ordered
+	}
+
+	if strategy == StrategyRandom {
+		cp.rngMu.Lock()
+		for _, idx := range cp.rng.Perm(len(candidates)) {
+			ordered = append(ordered, candidates[idx])
+		}
+		cp.rngMu.Unlock()
+		return ordered
+	}
+
+	start := offset % len(candidates)
+	ordered = append(ordered, candidates[start:]...)
+	ordered = append(ordered, candidates[:start]...)
+	if strategy == StrategyRoundRobin {
+		return ordered
+	}
+
+	tracker := usage.GetTracker()
+	isProModel := usage.IsProModel(modelName)
+	stats := make(map[string]*usage.ProjectUsage, len(ordered))
+	for _, cred := range ordered {
+		stats[cred.ProjectID] = tracker.GetUsage(cred.ProjectID)
+	}
+
+	switch strategy {
+	case StrategyLeastUsed:
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return usageCount(stats[ordered[i].ProjectID], isProModel) < usageCount(stats[ordered[j].ProjectID], isProModel)
+		})
+	case StrategyQuotaAware:
+		withQuota := make([]*CredentialEntry, 0, len(ordered))
+		exhausted := make([]*CredentialEntry, 0)
+		for _, cred := range ordered {
+			if isQuotaExhausted(stats[cred.ProjectID], isProModel) {
+				exhausted = append(exhausted, cred)
+			} else {
+				withQuota = append(withQuota, cred)
+			}
+		}
+
+		if len(exhausted) > 0 {
+			sort.SliceStable(exhausted, func(i, j int) bool {
+				return usageCount(stats[exhausted[i].ProjectID], isProModel) < usageCount(stats[exhausted[j].ProjectID], isProModel)
+			})
+			if config.IsDebugEnabled() {
+				log.Printf("[DEBUG] %d of %d credential(s) exhausted for model %s until %s",
+					len(exhausted), len(ordered), modelName, tracker.GetNextResetTime().Format("2006-01-02 15:04 MST"))
+			}
+		}
+		if len(withQuota) > 0 {
+			ordered = withQuota
+		} else {
+			ordered = exhausted
+		}
+	}
+
+	return ordered
+}
+
+// HasQuotaForModel reports whether any selectable credential still has daily quota for the model.
+// It also returns true when no credential is selectable, so quota never hides a pool outage.
+func (cp *CredentialPool) HasQuotaForModel(modelName string) bool {
+	candidates := cp.availableCredentials(nil)
+	if len(candidates) == 0 {
+		return true
+	}
+
+	tracker := usage.GetTracker()
+	isProModel := usage.IsProModel(modelName)
+	for _, cred := range candidates {
+		if !isQuotaExhausted(tracker.GetUsage(cred.ProjectID), isProModel) {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectCredential returns the preferred credential for a model without rate limiting.
+// Credentials in exclude (keyed by project ID) are skipped while untried ones remain.
+func (cp *CredentialPool) SelectCredential(modelName string, strategy SelectionStrategy, exclude map[string]bool) (*CredentialEntry, error) {
+	candidates := cp.availableCredentials(exclude)
+	if len(candidates) == 0 {
+		if cp.Size() == 0 {
+			return nil, errors.New("no credentials available in pool")
+		}
+		return nil, errors.New("no unbanned credentials available in pool (banned or cooling down)")
+	}
+
+	cp.rngMu.Lock()
+	offset := cp.nextIndex
+	cp.nextIndex++
+	cp.rngMu.Unlock()
+
+	return cp.orderCredentials(candidates, modelName, strategy, offset)[0], nil
+}
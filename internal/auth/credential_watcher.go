@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"log"
+	"maps"
+	"sync"
+	"time"
+
+	"gcli2apigo/internal/config"
+	"gcli2apigo/internal/storage"
+)
+
+var credentialWatcherOnce sync.Once
+
+// startCredentialWatcher polls the credential store and reloads the pool when credentials are
+// added, changed or removed, e.g. by a provisioning job writing to the credentials folder directly.
+// It is disabled when CREDENTIALS_WATCH_INTERVAL_SECONDS is 0.
+func startCredentialWatcher(store storage.Store) {
+	interval := config.GetCredentialsWatchInterval()
+	if interval <= 0 {
+		log.Printf("[INFO] Credentials folder watcher disabled")
+		return
+	}
+
+	credentialWatcherOnce.Do(func() {
+		log.Printf("[INFO] Watching %s for credential changes every %v", store, interval)
+		go watchCredentials(store, interval)
+	})
+}
+
+// watchCredentials compares the stored credentials' stamps on every tick and syncs the pool when they differ
+func watchCredentials(store storage.Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastStamps, _ := store.CredentialStamps()
+	for range ticker.C {
+		// A missing or unreadable folder has no credentials
+		stamps, _ := store.CredentialStamps()
+		if maps.Equal(stamps, lastStamps) {
+			continue
+		}
+		lastStamps = stamps
+
+		log.Printf("[INFO] Credentials changed in %s, reloading credential pool", store)
+		syncCredentialPool("watcher")
+	}
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// RefreshHealth is a snapshot of the OAuth token refreshes of a credential,
+// from both the background refresher and refreshes made during requests
+type RefreshHealth struct {
+	LastAttempt         *time.Time
+	LastSuccess         *time.Time
+	LastError           string // Error of the last attempt; empty once a refresh succeeds
+	ConsecutiveFailures int
+}
+
+var (
+	refreshHealth   = make(map[string]*RefreshHealth) // Keyed by project ID
+	refreshHealthMu sync.RWMutex
+)
+
+// RecordRefreshResult records the outcome of a token refresh attempt for a credential
+func RecordRefreshResult(projectID string, err error) {
+	now := time.Now()
+
+	refreshHealthMu.Lock()
+	defer refreshHealthMu.Unlock()
+
+	health, exists := refreshHealth[projectID]
+	if !exists {
+		health = &RefreshHealth{}
+		refreshHealth[projectID] = health
+	}
+
+	health.LastAttempt = &now
+	if err != nil {
+		health.LastError = err.Error()
+		health.ConsecutiveFailures++
+		return
+	}
+	health.LastSuccess = &now
+	health.LastError = ""
+	health.ConsecutiveFailures = 0
+}
+
+// GetRefreshHealth returns the token refresh health of a credential.
+// The zero value means no refresh has been attempted since startup.
+func GetRefreshHealth(projectID string) RefreshHealth {
+	refreshHealthMu.RLock()
+	defer refreshHealthMu.RUnlock()
+
+	if health, exists := refreshHealth[projectID]; exists {
+		return *health
+	}
+	return RefreshHealth{}
+}
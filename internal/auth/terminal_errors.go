@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"gcli2apigo/internal/banlist"
+
+	"golang.org/x/oauth2"
+)
+
+// APIError is returned by Code Assist API calls that fail with a non-200 status
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error: %d - %s", e.StatusCode, e.Body)
+}
+
+// ClassifyTerminalError returns the ban reason for errors that will keep failing until the
+// credential is re-authorized: a revoked or expired refresh token (invalid_grant) or a
+// 403 PERMISSION_DENIED from Code Assist for a suspended project.
+func ClassifyTerminalError(err error) (string, bool) {
+	if err == nil {
+		return "", false
+	}
+
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) && retrieveErr.ErrorCode == "invalid_grant" {
+		return banlist.ReasonInvalidGrant, true
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden && strings.Contains(apiErr.Body, "PERMISSION_DENIED") {
+		return banlist.ReasonPermissionDenied, true
+	}
+
+	// Errors that lost their type while being wrapped with %v
+	if strings.Contains(err.Error(), "invalid_grant") {
+		return banlist.ReasonInvalidGrant, true
+	}
+	return "", false
+}
+
+// BanIfTerminal bans a project automatically when err is a terminal error.
+// It returns true if the project was banned.
+func BanIfTerminal(projectID string, err error) bool {
+	reason, terminal := ClassifyTerminalError(err)
+	if !terminal {
+		return false
+	}
+
+	log.Printf("[WARN] Credential %s needs re-authorization (%s), banning automatically: %v", projectID, reason, err)
+	if banErr := banlist.GetBanList().Ban(projectID, banlist.BanOptions{
+		Reason: reason,
+		Source: banlist.SourceAutomatic,
+	}); banErr != nil {
+		log.Printf("[ERROR] Failed to save automatic ban for %s: %v", projectID, banErr)
+	}
+	return true
+}
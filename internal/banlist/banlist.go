@@ -1,160 +1,393 @@
-package banlist
-
-import (
-	"encoding/json"
-	"log"
-	"os"
-	"path/filepath"
-	"sync"
-
-	"gcli2apigo/internal/config"
-)
-
-// BanList manages banned credentials
-type BanList struct {
-	bannedProjects map[string]bool
-	mu             sync.RWMutex
-	storePath      string
-}
-
-var (
-	globalBanList *BanList
-	banListOnce   sync.Once
-)
-
-// GetBanList returns the global ban list instance
-func GetBanList() *BanList {
-	banListOnce.Do(func() {
-		globalBanList = NewBanList()
-		globalBanList.Load()
-	})
-	return globalBanList
-}
-
-// NewBanList creates a new ban list
-func NewBanList() *BanList {
-	storePath := filepath.Join(config.OAuthCredsFolder, "banlist.json")
-	return &BanList{
-		bannedProjects: make(map[string]bool),
-		storePath:      storePath,
-	}
-}
-
-// IsBanned checks if a project is banned
-func (bl *BanList) IsBanned(projectID string) bool {
-	bl.mu.RLock()
-	defer bl.mu.RUnlock()
-	return bl.bannedProjects[projectID]
-}
-
-// Ban adds a project to the ban list
-func (bl *BanList) Ban(projectID string) error {
-	bl.mu.Lock()
-	bl.bannedProjects[projectID] = true
-	bl.mu.Unlock()
-
-	log.Printf("[INFO] Banned credential: %s", projectID)
-	return bl.Save()
-}
-
-// Unban removes a project from the ban list
-func (bl *BanList) Unban(projectID string) error {
-	bl.mu.Lock()
-	delete(bl.bannedProjects, projectID)
-	bl.mu.Unlock()
-
-	log.Printf("[INFO] Unbanned credential: %s", projectID)
-	return bl.Save()
-}
-
-// BanMultiple bans multiple projects
-func (bl *BanList) BanMultiple(projectIDs []string) error {
-	bl.mu.Lock()
-	for _, projectID := range projectIDs {
-		bl.bannedProjects[projectID] = true
-	}
-	bl.mu.Unlock()
-
-	log.Printf("[INFO] Banned %d credentials", len(projectIDs))
-	return bl.Save()
-}
-
-// UnbanMultiple unbans multiple projects
-func (bl *BanList) UnbanMultiple(projectIDs []string) error {
-	bl.mu.Lock()
-	for _, projectID := range projectIDs {
-		delete(bl.bannedProjects, projectID)
-	}
-	bl.mu.Unlock()
-
-	log.Printf("[INFO] Unbanned %d credentials", len(projectIDs))
-	return bl.Save()
-}
-
-// GetBannedProjects returns all banned project IDs
-func (bl *BanList) GetBannedProjects() []string {
-	bl.mu.RLock()
-	defer bl.mu.RUnlock()
-
-	projects := make([]string, 0, len(bl.bannedProjects))
-	for projectID := range bl.bannedProjects {
-		projects = append(projects, projectID)
-	}
-	return projects
-}
-
-// Save persists the ban list to disk
-func (bl *BanList) Save() error {
-	bl.mu.RLock()
-	defer bl.mu.RUnlock()
-
-	// Ensure directory exists
-	dir := filepath.Dir(bl.storePath)
-	if err := os.MkdirAll(dir, 0700); err != nil {
-		log.Printf("[ERROR] Failed to create ban list directory: %v", err)
-		return err
-	}
-
-	// Marshal to JSON
-	data, err := json.MarshalIndent(bl.bannedProjects, "", "  ")
-	if err != nil {
-		log.Printf("[ERROR] Failed to marshal ban list: %v", err)
-		return err
-	}
-
-	// Write to file
-	if err := os.WriteFile(bl.storePath, data, 0600); err != nil {
-		log.Printf("[ERROR] Failed to write ban list: %v", err)
-		return err
-	}
-
-	return nil
-}
-
-// Load reads the ban list from disk
-func (bl *BanList) Load() error {
-	bl.mu.Lock()
-	defer bl.mu.Unlock()
-
-	// Check if file exists
-	if _, err := os.Stat(bl.storePath); os.IsNotExist(err) {
-		log.Printf("[INFO] Ban list file does not exist, starting fresh")
-		return nil
-	}
-
-	// Read file
-	data, err := os.ReadFile(bl.storePath)
-	if err != nil {
-		log.Printf("[ERROR] Failed to read ban list: %v", err)
-		return err
-	}
-
-	// Unmarshal JSON
-	if err := json.Unmarshal(data, &bl.bannedProjects); err != nil {
-		log.Printf("[ERROR] Failed to unmarshal ban list: %v", err)
-		return err
-	}
-
-	log.Printf("[INFO] Loaded ban list with %d banned credentials", len(bl.bannedProjects))
-	return nil
-}
+package banlist
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"gcli2apigo/internal/storage"
+)
+
+// Ban sources
+const (
+	SourceDashboard = "dashboard" // Banned manually from the dashboard
+	SourceAutomatic = "automatic" // Banned by the proxy itself
+)
+
+// Machine-readable reasons for automatic bans that can only be resolved by re-authenticating
+const (
+	ReasonInvalidGrant     = "invalid_grant"     // The refresh token was revoked or has expired
+	ReasonPermissionDenied = "permission_denied" // The project was suspended or lost access to Code Assist
+)
+
+// Reasons a ban was lifted, recorded in the ban history
+const (
+	LiftedByDashboard = "dashboard"
+	LiftedByExpiry    = "expired"
+	LiftedByReauth    = "reauthorized"
+)
+
+// maxHistory is the number of lifted bans kept in the history
+const maxHistory = 500
+
+// expiryCheckInterval is how often expired bans are lifted in the background
+const expiryCheckInterval = 30 * time.Second
+
+// BanEntry describes an active ban
+type BanEntry struct {
+	ProjectID string     `json:"project_id"`
+	Reason    string     `json:"reason,omitempty"`
+	Source    string     `json:"source"`
+	BannedAt  time.Time  `json:"banned_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // nil means the ban is permanent
+}
+
+// IsExpired reports whether a timed ban has run out
+func (e *BanEntry) IsExpired(now time.Time) bool {
+	return e.ExpiresAt != nil && !now.Before(*e.ExpiresAt)
+}
+
+// NeedsReauth reports whether the ban was set automatically because the credential must be re-authorized
+func (e *BanEntry) NeedsReauth() bool {
+	return e.Source == SourceAutomatic && (e.Reason == ReasonInvalidGrant || e.Reason == ReasonPermissionDenied)
+}
+
+// BanRecord is a lifted ban kept in the history
+type BanRecord struct {
+	BanEntry
+	LiftedAt time.Time `json:"lifted_at"`
+	LiftedBy string    `json:"lifted_by"`
+}
+
+// BanOptions describes a new ban
+type BanOptions struct {
+	Reason   string
+	Source   string        // SourceDashboard or SourceAutomatic; defaults to SourceDashboard
+	Duration time.Duration // 0 means permanent
+}
+
+// banListFile is the stored format of the ban list
+type banListFile struct {
+	Bans    map[string]*BanEntry `json:"bans"`
+	History []BanRecord          `json:"history"`
+}
+
+// BanList manages banned credentials
+type BanList struct {
+	bans    map[string]*BanEntry
+	history []BanRecord
+	mu      sync.RWMutex
+	saveMu  sync.Mutex // Held from snapshot to write so an older snapshot never overwrites a newer one
+	store   storage.Store
+}
+
+var (
+	globalBanList *BanList
+	banListOnce   sync.Once
+)
+
+// GetBanList returns the global ban list instance
+func GetBanList() *BanList {
+	banListOnce.Do(func() {
+		globalBanList = NewBanList()
+		globalBanList.Load()
+		go globalBanList.expiryLoop()
+	})
+	return globalBanList
+}
+
+// NewBanList creates a new ban list
+func NewBanList() *BanList {
+	return &BanList{
+		bans:    make(map[string]*BanEntry),
+		history: make([]BanRecord, 0),
+		store:   storage.GetStore(),
+	}
+}
+
+// IsBanned checks if a project is banned. Expired bans no longer count even before they are lifted.
+func (bl *BanList) IsBanned(projectID string) bool {
+	bl.mu.RLock()
+	defer bl.mu.RUnlock()
+
+	entry, exists := bl.bans[projectID]
+	return exists && !entry.IsExpired(time.Now())
+}
+
+// GetBan returns a copy of the active ban of a project
+func (bl *BanList) GetBan(projectID string) (*BanEntry, bool) {
+	bl.mu.RLock()
+	defer bl.mu.RUnlock()
+
+	entry, exists := bl.bans[projectID]
+	if !exists || entry.IsExpired(time.Now()) {
+		return nil, false
+	}
+	copied := *entry
+	return &copied, true
+}
+
+// newEntry builds a ban entry from options
+func newEntry(projectID string, opts BanOptions, now time.Time) *BanEntry {
+	entry := &BanEntry{
+		ProjectID: projectID,
+		Reason:    opts.Reason,
+		Source:    opts.Source,
+		BannedAt:  now,
+	}
+	if opts.Duration > 0 {
+		expiresAt := now.Add(opts.Duration)
+		entry.ExpiresAt = &expiresAt
+	}
+	return entry
+}
+
+// Ban adds a project to the ban list, replacing any existing ban
+func (bl *BanList) Ban(projectID string, opts BanOptions) error {
+	return bl.BanMultiple([]string{projectID}, opts)
+}
+
+// Unban removes a project from the ban list
+func (bl *BanList) Unban(projectID string) error {
+	return bl.UnbanMultiple([]string{projectID})
+}
+
+// BanMultiple bans multiple projects with the same options
+func (bl *BanList) BanMultiple(projectIDs []string, opts BanOptions) error {
+	if opts.Source == "" {
+		opts.Source = SourceDashboard
+	}
+
+	now := time.Now()
+	bl.mu.Lock()
+	for _, projectID := range projectIDs {
+		bl.bans[projectID] = newEntry(projectID, opts, now)
+	}
+	bl.mu.Unlock()
+
+	if opts.Duration > 0 {
+		log.Printf("[INFO] Banned %d credential(s) for %v (source: %s, reason: %q)", len(projectIDs), opts.Duration, opts.Source, opts.Reason)
+	} else {
+		log.Printf("[INFO] Banned %d credential(s) (source: %s, reason: %q)", len(projectIDs), opts.Source, opts.Reason)
+	}
+	return bl.Save()
+}
+
+// UnbanMultiple unbans multiple projects
+func (bl *BanList) UnbanMultiple(projectIDs []string) error {
+	now := time.Now()
+	bl.mu.Lock()
+	for _, projectID := range projectIDs {
+		bl.liftLocked(projectID, LiftedByDashboard, now)
+	}
+	bl.mu.Unlock()
+
+	log.Printf("[INFO] Unbanned %d credentials", len(projectIDs))
+	return bl.Save()
+}
+
+// LiftReauthBan lifts the ban of a project if it was set because the credential needed
+// re-authorization; it is called when a fresh credential is saved for the project
+func (bl *BanList) LiftReauthBan(projectID string) error {
+	bl.mu.Lock()
+	entry, exists := bl.bans[projectID]
+	if !exists || !entry.NeedsReauth() {
+		bl.mu.Unlock()
+		return nil
+	}
+	bl.liftLocked(projectID, LiftedByReauth, time.Now())
+	bl.mu.Unlock()
+
+	log.Printf("[INFO] Lifted %s ban for credential %s after re-authorization", entry.Reason, projectID)
+	return bl.Save()
+}
+
+// liftLocked removes a ban and records it in the history.
+// The caller must hold bl.mu for writing.
+func (bl *BanList) liftLocked(projectID string, liftedBy string, now time.Time) {
+	entry, exists := bl.bans[projectID]
+	if !exists {
+		return
+	}
+	delete(bl.bans, projectID)
+
+	bl.history = append(bl.history, BanRecord{
+		BanEntry: *entry,
+		LiftedAt: now,
+		LiftedBy: liftedBy,
+	})
+	if len(bl.history) > maxHistory {
+		bl.history = bl.history[len(bl.history)-maxHistory:]
+	}
+}
+
+// LiftExpired lifts all bans whose expiry has passed and returns how many were lifted
+func (bl *BanList) LiftExpired() int {
+	now := time.Now()
+	bl.mu.Lock()
+	lifted := 0
+	for projectID, entry := range bl.bans {
+		if entry.IsExpired(now) {
+			bl.liftLocked(projectID, LiftedByExpiry, now)
+			lifted++
+		}
+	}
+	bl.mu.Unlock()
+
+	if lifted > 0 {
+		log.Printf("[INFO] Lifted %d expired ban(s)", lifted)
+		bl.Save()
+	}
+	return lifted
+}
+
+// expiryLoop lifts expired bans in the background
+func (bl *BanList) expiryLoop() {
+	ticker := time.NewTicker(expiryCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		bl.LiftExpired()
+	}
+}
+
+// GetBannedProjects returns all banned project IDs
+func (bl *BanList) GetBannedProjects() []string {
+	bl.mu.RLock()
+	defer bl.mu.RUnlock()
+
+	now := time.Now()
+	projects := make([]string, 0, len(bl.bans))
+	for projectID, entry := range bl.bans {
+		if !entry.IsExpired(now) {
+			projects = append(projects, projectID)
+		}
+	}
+	return projects
+}
+
+// GetBans returns copies of all active bans, most recent first
+func (bl *BanList) GetBans() []BanEntry {
+	bl.mu.RLock()
+	defer bl.mu.RUnlock()
+
+	now := time.Now()
+	bans := make([]BanEntry, 0, len(bl.bans))
+	for _, entry := range bl.bans {
+		if !entry.IsExpired(now) {
+			bans = append(bans, *entry)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].BannedAt.After(bans[j].BannedAt)
+	})
+	return bans
+}
+
+// GetHistory returns lifted bans, most recent first
+func (bl *BanList) GetHistory() []BanRecord {
+	bl.mu.RLock()
+	defer bl.mu.RUnlock()
+
+	history := make([]BanRecord, len(bl.history))
+	copy(history, bl.history)
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].LiftedAt.After(history[j].LiftedAt)
+	})
+	return history
+}
+
+// Save persists the ban list to the store
+func (bl *BanList) Save() error {
+	bl.saveMu.Lock()
+	defer bl.saveMu.Unlock()
+
+	// Marshal to JSON
+	bl.mu.RLock()
+	data, err := json.MarshalIndent(banListFile{Bans: bl.bans, History: bl.history}, "", "  ")
+	bl.mu.RUnlock()
+	if err != nil {
+		log.Printf("[ERROR] Failed to marshal ban list: %v", err)
+		return err
+	}
+
+	if err := bl.store.SaveState(map[string][]byte{storage.StateBanList: data}); err != nil {
+		log.Printf("[ERROR] Failed to write ban list: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// Load reads the ban list from the store, migrating the legacy map[string]bool format
+func (bl *BanList) Load() error {
+	data, savedAt, err := bl.store.LoadState(storage.StateBanList)
+	if errors.Is(err, storage.ErrNotFound) {
+		log.Printf("[INFO] Ban list file does not exist, starting fresh")
+		return nil
+	}
+	if err != nil {
+		log.Printf("[ERROR] Failed to read ban list: %v", err)
+		return err
+	}
+
+	bl.mu.Lock()
+
+	// Unmarshal JSON
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		bl.mu.Unlock()
+		log.Printf("[ERROR] Failed to unmarshal ban list: %v", err)
+		return err
+	}
+
+	migrated := false
+	if _, isCurrent := raw["bans"]; isCurrent {
+		var file banListFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			bl.mu.Unlock()
+			log.Printf("[ERROR] Failed to unmarshal ban list: %v", err)
+			return err
+		}
+		if file.Bans != nil {
+			bl.bans = file.Bans
+		}
+		if file.History != nil {
+			bl.history = file.History
+		}
+	} else {
+		// Legacy format: {"project-id": true}. Bans could only be set from the dashboard.
+		var legacy map[string]bool
+		if err := json.Unmarshal(data, &legacy); err != nil {
+			bl.mu.Unlock()
+			log.Printf("[ERROR] Failed to unmarshal legacy ban list: %v", err)
+			return err
+		}
+		for projectID, banned := range legacy {
+			if banned {
+				bl.bans[projectID] = &BanEntry{
+					ProjectID: projectID,
+					Reason:    "migrated from legacy ban list",
+					Source:    SourceDashboard,
+					BannedAt:  savedAt,
+				}
+			}
+		}
+		migrated = true
+	}
+	count := len(bl.bans)
+	bl.mu.Unlock()
+
+	if migrated {
+		log.Printf("[INFO] Migrated legacy ban list with %d banned credentials", count)
+		if err := bl.Save(); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("[INFO] Loaded ban list with %d banned credentials", count)
+	return nil
+}
@@ -0,0 +1,238 @@
+package breaker
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"gcli2apigo/internal/config"
+)
+
+// State is the circuit state of a credential
+type State string
+
+const (
+	StateClosed   State = "closed"    // Credential is in normal rotation
+	StateOpen     State = "open"      // Credential is cooling down after repeated failures
+	StateHalfOpen State = "half-open" // Cooldown elapsed; a single probe request decides the next state
+)
+
+// FailureClass groups upstream failures that count towards opening a circuit
+type FailureClass string
+
+const (
+	FailureRateLimit FailureClass = "rate_limit" // 429 responses
+	FailureAuth      FailureClass = "auth"       // 401 and 403 responses
+	FailureServer    FailureClass = "server"     // 5xx responses
+	FailureRefresh   FailureClass = "refresh"    // OAuth token refresh failures
+)
+
+// probeTimeout is how long a half-open probe may run before another request may probe instead
+const probeTimeout = 2 * time.Minute
+
+// ClassifyStatus returns the failure class of an upstream HTTP status code.
+// ok is false for statuses that do not indicate a problem with the credential itself.
+func ClassifyStatus(status int) (FailureClass, bool) {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return FailureRateLimit, true
+	case status == http.StatusUnauthorized, status == http.StatusForbidden:
+		return FailureAuth, true
+	case status >= 500:
+		return FailureServer, true
+	default:
+		return "", false
+	}
+}
+
+// Status is a snapshot of a credential's circuit
+type Status struct {
+	State               State        `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	LastFailure         FailureClass `json:"last_failure,omitempty"`
+	OpenUntil           *time.Time   `json:"open_until,omitempty"`
+}
+
+// circuit tracks the failures of a single credential
+type circuit struct {
+	state          State
+	failures       map[FailureClass]int // Consecutive failures per class since the last success
+	trips          int                  // Consecutive openings without a successful probe; drives the backoff
+	lastFailure    FailureClass
+	openUntil      time.Time
+	probeStartedAt time.Time
+}
+
+// CircuitBreaker takes credentials out of rotation automatically after repeated failures.
+// It is kept in memory and is independent of the manual ban list.
+type CircuitBreaker struct {
+	circuits map[string]*circuit // Keyed by project ID
+	mu       sync.Mutex
+}
+
+var (
+	globalBreaker *CircuitBreaker
+	breakerOnce   sync.Once
+)
+
+// GetCircuitBreaker returns the global circuit breaker instance
+func GetCircuitBreaker() *CircuitBreaker {
+	breakerOnce.Do(func() {
+		globalBreaker = NewCircuitBreaker()
+	})
+	return globalBreaker
+}
+
+// NewCircuitBreaker creates a new circuit breaker with all circuits closed
+func NewCircuitBreaker() *CircuitBreaker {
+	return &CircuitBreaker{
+		circuits: make(map[string]*circuit),
+	}
+}
+
+// cooldown returns the open duration for the given number of consecutive trips,
+// doubling from the base cooldown up to the configured maximum
+func cooldown(trips int) time.Duration {
+	base := config.GetCircuitBreakerCooldown()
+	maxCooldown := config.GetCircuitBreakerMaxCooldown()
+	d := base
+	for i := 1; i < trips && d < maxCooldown; i++ {
+		d *= 2
+	}
+	return min(d, maxCooldown)
+}
+
+// canProbe reports whether a request may be sent with an open or half-open credential now.
+// The caller must hold cb.mu.
+func (c *circuit) canProbe(now time.Time) bool {
+	if now.Before(c.openUntil) {
+		return false
+	}
+	return c.probeStartedAt.IsZero() || now.Sub(c.probeStartedAt) >= probeTimeout
+}
+
+// Allow reports whether a credential may currently be selected
+func (cb *CircuitBreaker) Allow(projectID string) bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	c, exists := cb.circuits[projectID]
+	if !exists || c.state == StateClosed {
+		return true
+	}
+	return c.canProbe(time.Now())
+}
+
+// Acquire is called when a credential has been selected for a request.
+// For a credential whose cooldown has elapsed it moves the circuit to half-open and
+// claims the single probe request; it returns false if the request may not proceed.
+func (cb *CircuitBreaker) Acquire(projectID string) bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	c, exists := cb.circuits[projectID]
+	if !exists || c.state == StateClosed {
+		return true
+	}
+
+	now := time.Now()
+	if !c.canProbe(now) {
+		return false
+	}
+	if c.state == StateOpen {
+		log.Printf("[INFO] Circuit half-open for credential %s, sending probe request", projectID)
+	}
+	c.state = StateHalfOpen
+	c.probeStartedAt = now
+	return true
+}
+
+// RecordSuccess closes the circuit of a credential and clears its failure counts
+func (cb *CircuitBreaker) RecordSuccess(projectID string) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	c, exists := cb.circuits[projectID]
+	if !exists {
+		return
+	}
+	if c.state != StateClosed {
+		log.Printf("[INFO] Circuit closed for credential %s after successful probe", projectID)
+	}
+	delete(cb.circuits, projectID)
+}
+
+// RecordFailure counts a failure for a credential. The circuit opens once the consecutive
+// failures of a class reach its configured threshold, or immediately if a probe fails.
+func (cb *CircuitBreaker) RecordFailure(projectID string, class FailureClass) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	c, exists := cb.circuits[projectID]
+	if !exists {
+		c = &circuit{
+			state:    StateClosed,
+			failures: make(map[FailureClass]int),
+		}
+		cb.circuits[projectID] = c
+	}
+	c.failures[class]++
+	c.lastFailure = class
+
+	switch c.state {
+	case StateHalfOpen:
+		cb.open(projectID, c)
+	case StateClosed:
+		threshold := config.GetCircuitBreakerThreshold(string(class))
+		if threshold > 0 && c.failures[class] >= threshold {
+			cb.open(projectID, c)
+		}
+	}
+}
+
+// RecordResult records the outcome of an upstream response by its HTTP status code
+func (cb *CircuitBreaker) RecordResult(projectID string, status int) {
+	if class, ok := ClassifyStatus(status); ok {
+		cb.RecordFailure(projectID, class)
+		return
+	}
+	cb.RecordSuccess(projectID)
+}
+
+// open moves a circuit to the open state with exponential backoff.
+// The caller must hold cb.mu.
+func (cb *CircuitBreaker) open(projectID string, c *circuit) {
+	c.trips++
+	d := cooldown(c.trips)
+	c.state = StateOpen
+	c.openUntil = time.Now().Add(d)
+	c.probeStartedAt = time.Time{}
+
+	log.Printf("[WARN] Circuit opened for credential %s after %s failure (trip %d), cooling down for %v",
+		projectID, c.lastFailure, c.trips, d)
+}
+
+// GetStatus returns a snapshot of a credential's circuit
+func (cb *CircuitBreaker) GetStatus(projectID string) Status {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	c, exists := cb.circuits[projectID]
+	if !exists {
+		return Status{State: StateClosed}
+	}
+
+	status := Status{
+		State:       c.state,
+		LastFailure: c.lastFailure,
+	}
+	for _, count := range c.failures {
+		status.ConsecutiveFailures += count
+	}
+	if c.state != StateClosed {
+		openUntil := c.openUntil
+		status.OpenUntil = &openUntil
+	}
+	return status
+}
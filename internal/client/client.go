@@ -1,618 +1,962 @@
-package client
-
-import (
-	"bufio"
-	"bytes"
-	"context"
-	"encoding/json"
-	"fmt"
-	"io"
-	"log"
-	"net/http"
-	"strings"
-	"sync"
-	"time"
-
-	"gcli2apigo/internal/auth"
-	"gcli2apigo/internal/config"
-	"gcli2apigo/internal/httputil"
-	"gcli2apigo/internal/usage"
-
-	"golang.org/x/oauth2"
-)
-
-// TokenRefreshManager manages token refresh operations with per-credential locking
-// to ensure only one refresh happens per credential even with concurrent requests
-type TokenRefreshManager struct {
-	// refreshMutexes stores a mutex for each credential file path
-	// Using sync.Map for thread-safe concurrent access without global lock
-	refreshMutexes sync.Map // map[string]*sync.Mutex
-}
-
-// NewTokenRefreshManager creates a new TokenRefreshManager instance
-func NewTokenRefreshManager() *TokenRefreshManager {
-	return &TokenRefreshManager{}
-}
-
-// RefreshToken refreshes the OAuth token for a credential with per-credential locking
-// This ensures only one refresh operation happens per credential even with concurrent requests
-func (trm *TokenRefreshManager) RefreshToken(credEntry *auth.CredentialEntry) error {
-	if credEntry == nil || credEntry.FilePath == "" {
-		return fmt.Errorf("invalid credential entry")
-	}
-
-	// Get or create a mutex for this specific credential
-	mutexInterface, _ := trm.refreshMutexes.LoadOrStore(credEntry.FilePath, &sync.Mutex{})
-	mutex := mutexInterface.(*sync.Mutex)
-
-	// Acquire the credential-specific lock
-	mutex.Lock()
-	defer mutex.Unlock()
-
-	// Check if token still needs refresh (another goroutine may have already refreshed it)
-	if !credEntry.Token.Expiry.Before(time.Now()) && credEntry.Token.AccessToken != "" {
-		if config.IsDebugEnabled() {
-			log.Printf("[DEBUG] Token already refreshed by another request for credential: %s", credEntry.FilePath)
-		}
-		return nil
-	}
-
-	// Perform the actual token refresh
-	if config.IsDebugEnabled() {
-		log.Printf("[DEBUG] Refreshing token for credential: %s (expiry: %s)", credEntry.FilePath, credEntry.Token.Expiry.Format(time.RFC3339))
-	}
-
-	// Extract client credentials from token extra data or use defaults
-	clientID := config.ClientID
-	clientSecret := config.ClientSecret
-	if extra := credEntry.Token.Extra("client_id"); extra != nil {
-		if id, ok := extra.(string); ok && id != "" {
-			clientID = id
-		}
-	}
-	if extra := credEntry.Token.Extra("client_secret"); extra != nil {
-		if secret, ok := extra.(string); ok && secret != "" {
-			clientSecret = secret
-		}
-	}
-
-	oauthConfig := &oauth2.Config{
-		ClientID:     clientID,
-		ClientSecret: clientSecret,
-		Endpoint: oauth2.Endpoint{
-			TokenURL: config.GetOAuth2Endpoint() + "/token",
-		},
-	}
-
-	tokenSource := oauthConfig.TokenSource(context.Background(), credEntry.Token)
-	newToken, err := tokenSource.Token()
-	if err != nil {
-		log.Printf("[WARN] Token refresh failed for credential %s: %v", credEntry.FilePath, err)
-		return fmt.Errorf("token refresh failed: %v", err)
-	}
-
-	if config.IsDebugEnabled() {
-		log.Printf("[DEBUG] Token refreshed successfully for credential: %s (new expiry: %s)", credEntry.FilePath, newToken.Expiry.Format(time.RFC3339))
-	}
-
-	// Update the credential entry with the new token
-	credEntry.Token = newToken
-
-	// Save the refreshed token asynchronously to avoid blocking
-	auth.SaveRefreshedTokenAsync(credEntry)
-
-	return nil
-}
-
-var (
-	// globalTokenRefreshManager is the global instance used across the application
-	globalTokenRefreshManager = NewTokenRefreshManager()
-)
-
-// SendGeminiRequest sends a request to Google's Gemini API
-// Process: 1. Randomly obtain OAuth credential, 2. Refresh token if needed, 3. Make API request, 4. Return
-// If a 429 error occurs, automatically retry with different OAuth credentials until success or all credentials exhausted
-func SendGeminiRequest(payload map[string]any, isStreaming bool) (any, error) {
-	// Track which credentials have been tried to avoid retrying the same one
-	triedCredentials := make(map[string]bool)
-
-	// Extract model name for usage tracking
-	modelName := ""
-	if model, ok := payload["model"].(string); ok {
-		modelName = model
-	}
-
-	// Retry loop: try different credentials on 429 errors
-	// Limited by MAX_RETRY_ATTEMPTS (default: 5)
-	// This is read dynamically to allow runtime updates without restart
-	maxRetries := config.GetMaxRetryAttempts()
-	if maxRetries <= 0 {
-		maxRetries = 5 // Ensure at least 1 attempt
-	}
-
-	// Track if we've already tried reloading credentials
-	hasReloadedCredentials := false
-
-	for {
-		// Step 1: Randomly obtain an OAuth credential from the oauth_creds folder
-		credEntry, err := auth.GetCredentialForRequest()
-		if err != nil {
-			// Check if error is due to no credentials available
-			if strings.Contains(err.Error(), "no credentials available") || strings.Contains(err.Error(), "credential pool not initialized") {
-				// Try reloading credentials once if we haven't already
-				if !hasReloadedCredentials {
-					log.Printf("[WARN] No credentials available, attempting to reload credential pool...")
-					if reloadErr := auth.ReloadCredentialPool(); reloadErr != nil {
-						log.Printf("[ERROR] Failed to reload credential pool: %v", reloadErr)
-						log.Printf("[ERROR] Credential selection failed: %v", err)
-						return nil, fmt.Errorf("credential selection failed: %v", err)
-					}
-
-					hasReloadedCredentials = true
-					log.Printf("[INFO] Credential pool reloaded, retrying credential selection...")
-
-					// Retry getting credentials after reload
-					credEntry, err = auth.GetCredentialForRequest()
-					if err != nil {
-						log.Printf("[ERROR] Still no credentials available after reload: %v", err)
-						return nil, fmt.Errorf("credential selection failed: %v", err)
-					}
-
-					// Successfully got credentials after reload, continue with request
-					log.Printf("[INFO] Successfully selected credential after reload: %s", credEntry.ProjectID)
-				} else {
-					// Already tried reloading, return error
-					log.Printf("[ERROR] Credential selection failed: %v", err)
-					return nil, fmt.Errorf("credential selection failed: %v", err)
-				}
-			} else {
-				// Different error, return immediately
-				log.Printf("[ERROR] Credential selection failed: %v", err)
-				return nil, fmt.Errorf("credential selection failed: %v", err)
-			}
-		}
-
-		// Check if we've already tried this credential
-		if triedCredentials[credEntry.ProjectID] {
-			// Check if we've reached max retry attempts or tried all available credentials
-			poolSize := auth.GetCredentialPoolSize()
-			if len(triedCredentials) >= maxRetries || len(triedCredentials) >= poolSize {
-				log.Printf("[ERROR] Retry limit reached: tried %d credentials (max: %d, pool size: %d)",
-					len(triedCredentials), maxRetries, poolSize)
-				return nil, fmt.Errorf("rate limit exceeded: retry limit reached after %d attempts", len(triedCredentials))
-			}
-			// Skip this credential and try to get another one
-			continue
-		}
-
-		// Mark this credential as tried
-		triedCredentials[credEntry.ProjectID] = true
-
-		creds := credEntry.Token
-		projID := credEntry.ProjectID
-		if config.IsDebugEnabled() {
-			log.Printf("[DEBUG] Selected credential from: %s (project: %s) [attempt %d/%d]",
-				credEntry.FilePath, projID, len(triedCredentials), auth.GetCredentialPoolSize())
-		}
-
-		// Step 2: Refresh the token if needed (expired OR no access token)
-		needsRefresh := creds.Expiry.Before(time.Now()) || creds.AccessToken == ""
-
-		if needsRefresh && creds.RefreshToken != "" {
-			if config.IsDebugEnabled() {
-				if creds.AccessToken == "" {
-					log.Printf("[DEBUG] No access token, refreshing for credential: %s", credEntry.FilePath)
-				} else {
-					log.Printf("[DEBUG] Token expired (expiry: %s), refreshing for credential: %s", creds.Expiry.Format(time.RFC3339), credEntry.FilePath)
-				}
-			}
-
-			// Use TokenRefreshManager to handle refresh with per-credential locking
-			err := globalTokenRefreshManager.RefreshToken(credEntry)
-			if err != nil {
-				log.Printf("Warning: Token refresh failed for credential %s: %v", credEntry.FilePath, err)
-				if creds.AccessToken == "" {
-					// Try next credential
-					continue
-				}
-				// Continue with existing token as per requirement 2.4
-			} else {
-				// Token was refreshed successfully, update local reference
-				creds = credEntry.Token
-			}
-		} else if creds.AccessToken == "" {
-			log.Printf("[WARN] No access token available for credential %s, trying next credential", credEntry.FilePath)
-			continue
-		} else {
-			if config.IsDebugEnabled() {
-				log.Printf("[DEBUG] Token is still valid (expiry: %s)", creds.Expiry.Format(time.RFC3339))
-			}
-		}
-
-		// Step 3: Make API request (onboarding and actual request)
-
-		// Onboard user with selected credential
-		err = auth.OnboardUser(creds, projID)
-		if err != nil {
-			// Check if it's a 401 error and try refreshing the token
-			if strings.Contains(err.Error(), "401") && creds.RefreshToken != "" {
-				if config.IsDebugEnabled() {
-					log.Printf("[DEBUG] Got 401 during onboarding, forcing token refresh...")
-				}
-
-				// Reset onboarding state since credentials are invalid
-				auth.ResetOnboardingState()
-
-				// Use TokenRefreshManager to handle refresh with per-credential locking
-				refreshErr := globalTokenRefreshManager.RefreshToken(credEntry)
-				if refreshErr != nil {
-					log.Printf("Warning: Failed to refresh token after 401: %v", refreshErr)
-					// Try next credential
-					continue
-				}
-
-				if config.IsDebugEnabled() {
-					log.Printf("[DEBUG] Token refreshed after 401, retrying onboarding...")
-				}
-				// Update local reference to refreshed token
-				creds = credEntry.Token
-
-				// Retry onboarding with refreshed token
-				if retryErr := auth.OnboardUser(creds, projID); retryErr != nil {
-					log.Printf("[WARN] Failed to onboard user after token refresh: %v, trying next credential", retryErr)
-					continue
-				}
-				if config.IsDebugEnabled() {
-					log.Printf("[DEBUG] Onboarding successful after token refresh")
-				}
-			} else {
-				log.Printf("[WARN] Failed to onboard user: %v, trying next credential", err)
-				continue
-			}
-		}
-
-		// Build the final payload with project info
-		requestData, _ := payload["request"].(map[string]any)
-		if requestData == nil {
-			requestData = make(map[string]any)
-		}
-
-		finalPayload := map[string]any{
-			"model":   payload["model"],
-			"project": projID,
-			"request": requestData,
-		}
-
-		// Determine the action and URL using strings.Builder to avoid allocations
-		action := "generateContent"
-		if isStreaming {
-			action = "streamGenerateContent"
-		}
-
-		var urlBuilder strings.Builder
-		endpoint := config.GetCodeAssistEndpoint()
-		urlBuilder.WriteString(endpoint)
-		urlBuilder.WriteString("/v1internal:")
-		urlBuilder.WriteString(action)
-		if isStreaming {
-			urlBuilder.WriteString("?alt=sse")
-		}
-		targetURL := urlBuilder.String()
-
-		log.Printf("[DEBUG] Gemini API request - Endpoint: %s, Action: %s, Full URL: %s", endpoint, action, targetURL)
-
-		// Build request
-		jsonData, err := json.Marshal(finalPayload)
-		if err != nil {
-			return nil, err
-		}
-
-		req, err := http.NewRequest("POST", targetURL, bytes.NewBuffer(jsonData))
-		if err != nil {
-			return nil, err
-		}
-
-		// Use strings.Builder to avoid string allocation in hot path
-		var authHeader strings.Builder
-		authHeader.WriteString("Bearer ")
-		authHeader.WriteString(creds.AccessToken)
-		req.Header.Set("Authorization", authHeader.String())
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("User-Agent", config.GetUserAgent())
-
-		// Send the request using the shared HTTP client
-		resp, err := httputil.SharedHTTPClient.Do(req)
-		if err != nil {
-			return nil, fmt.Errorf("request failed: %v", err)
-		}
-
-		// Check for 429 error and retry with different credential
-		if resp.StatusCode == http.StatusTooManyRequests {
-			resp.Body.Close()
-			log.Printf("[WARN] Received 429 (Too Many Requests) for project %s, retrying with different credential... (attempt %d/%d)",
-				projID, len(triedCredentials), maxRetries)
-
-			// Track error code for this project
-			usage.GetTracker().SetErrorCode(projID, resp.StatusCode)
-
-			// Check if we've reached max retry attempts or tried all credentials
-			poolSize := auth.GetCredentialPoolSize()
-			if len(triedCredentials) >= maxRetries || len(triedCredentials) >= poolSize {
-				log.Printf("[ERROR] Retry limit reached: tried %d credentials (max: %d, pool size: %d)",
-					len(triedCredentials), maxRetries, poolSize)
-				return nil, fmt.Errorf("rate limit exceeded: retry limit reached after %d attempts", len(triedCredentials))
-			}
-
-			// Continue to next iteration to try another credential
-			continue
-		}
-
-		// Step 4: Return response
-		var result any
-		var responseErr error
-
-		if isStreaming {
-			result, responseErr = handleStreamingResponse(resp)
-		} else {
-			result, responseErr = handleNonStreamingResponse(resp)
-		}
-
-		// Track usage and error status
-		if responseErr == nil && resp.StatusCode == http.StatusOK {
-			isProModel := usage.IsProModel(modelName)
-			usage.GetTracker().IncrementUsage(projID, isProModel)
-			if config.IsDebugEnabled() {
-				log.Printf("[DEBUG] Usage tracked for project %s (model: %s, isPro: %v)", projID, modelName, isProModel)
-			}
-		} else if resp.StatusCode != http.StatusOK {
-			// Track error code for this project
-			usage.GetTracker().SetErrorCode(projID, resp.StatusCode)
-			if config.IsDebugEnabled() {
-				log.Printf("[DEBUG] Error code %d tracked for project %s", resp.StatusCode, projID)
-			}
-		}
-
-		return result, responseErr
-	}
-}
-
-func handleStreamingResponse(resp *http.Response) (chan string, error) {
-	if resp.StatusCode != http.StatusOK {
-		defer resp.Body.Close()
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("Google API returned status %d: %s", resp.StatusCode, string(body))
-		return nil, fmt.Errorf("API error: %d", resp.StatusCode)
-	}
-
-	streamChan := make(chan string, 100)
-
-	go func() {
-		defer close(streamChan)
-		defer resp.Body.Close()
-
-		// Use larger buffer for scanner to handle large chunks
-		scanner := bufio.NewScanner(resp.Body)
-		buf := make([]byte, 64*1024)
-		scanner.Buffer(buf, 256*1024)
-
-		for scanner.Scan() {
-			line := scanner.Text()
-			// Use CutPrefix to avoid double prefix check and allocation
-			if chunk, found := strings.CutPrefix(line, "data: "); found {
-
-				var obj map[string]any
-				if err := json.Unmarshal([]byte(chunk), &obj); err != nil {
-					continue
-				}
-
-				if response, ok := obj["response"].(map[string]any); ok {
-					responseJSON, _ := json.Marshal(response)
-					streamChan <- string(responseJSON)
-				} else {
-					streamChan <- chunk
-				}
-			}
-		}
-	}()
-
-	return streamChan, nil
-}
-
-func handleNonStreamingResponse(resp *http.Response) (map[string]any, error) {
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Google API returned status %d: %s", resp.StatusCode, string(body))
-
-		var errorData map[string]any
-		if err := json.Unmarshal(body, &errorData); err == nil {
-			if errObj, ok := errorData["error"].(map[string]any); ok {
-				return map[string]any{
-					"error": errObj,
-				}, nil
-			}
-		}
-
-		return map[string]any{
-			"error": map[string]any{
-				"message": fmt.Sprintf("API error: %d", resp.StatusCode),
-				"code":    resp.StatusCode,
-			},
-		}, nil
-	}
-
-	// Parse response - use CutPrefix to avoid allocation and double check
-	responseText, _ := strings.CutPrefix(string(body), "data: ")
-
-	var googleAPIResponse map[string]any
-	if err := json.Unmarshal([]byte(responseText), &googleAPIResponse); err != nil {
-		return nil, err
-	}
-
-	if standardGeminiResponse, ok := googleAPIResponse["response"].(map[string]any); ok {
-		return standardGeminiResponse, nil
-	}
-
-	return googleAPIResponse, nil
-}
-
-// BuildGeminiPayloadFromOpenAI builds a Gemini API payload from an OpenAI-transformed request
-func BuildGeminiPayloadFromOpenAI(openaiPayload map[string]any) map[string]any {
-	model, _ := openaiPayload["model"].(string)
-
-	safetySettings := config.DefaultSafetySettings
-	if ss, ok := openaiPayload["safetySettings"]; ok && ss != nil {
-		if ssSlice, ok := ss.([]config.SafetySetting); ok {
-			safetySettings = ssSlice
-		}
-	}
-
-	requestData := map[string]any{
-		"contents":         openaiPayload["contents"],
-		"safetySettings":   safetySettings,
-		"generationConfig": openaiPayload["generationConfig"],
-	}
-
-	if systemInstruction, ok := openaiPayload["systemInstruction"]; ok && systemInstruction != nil {
-		requestData["systemInstruction"] = systemInstruction
-	}
-	if cachedContent, ok := openaiPayload["cachedContent"]; ok && cachedContent != nil {
-		requestData["cachedContent"] = cachedContent
-	}
-	if tools, ok := openaiPayload["tools"]; ok && tools != nil {
-		requestData["tools"] = tools
-	}
-	if toolConfig, ok := openaiPayload["toolConfig"]; ok && toolConfig != nil {
-		requestData["toolConfig"] = toolConfig
-	}
-
-	return map[string]any{
-		"model":   model,
-		"request": requestData,
-	}
-}
-
-// BuildGeminiPayloadFromNative builds a Gemini API payload from a native Gemini request
-func BuildGeminiPayloadFromNative(nativeRequest map[string]any, modelFromPath string) map[string]any {
-	nativeRequest["safetySettings"] = config.DefaultSafetySettings
-
-	// Ensure generationConfig exists
-	var genConfig map[string]any
-	if existingConfig, ok := nativeRequest["generationConfig"].(map[string]any); ok {
-		genConfig = existingConfig
-	} else {
-		genConfig = make(map[string]any)
-		nativeRequest["generationConfig"] = genConfig
-	}
-
-	// Set minimum thinking budget if not already specified
-	if _, hasThinkingConfig := genConfig["thinkingConfig"]; !hasThinkingConfig {
-		genConfig["thinkingConfig"] = map[string]any{
-			"thinkingBudget": config.GetThinkingBudget(modelFromPath),
-		}
-	}
-
-	return map[string]any{
-		"model":   modelFromPath,
-		"request": nativeRequest,
-	}
-}
-
-// GeminiRequestResult holds the result of a parallel Gemini request
-type GeminiRequestResult struct {
-	Index    int   // Original index in the batch
-	Response any   // Response data (chan string for streaming, map for non-streaming)
-	Error    error // Error if request failed
-}
-
-// SendGeminiRequestsParallel sends multiple Gemini requests in parallel using goroutines
-// Returns a slice of results in the same order as the input payloads
-// This is useful for batch processing multiple independent requests
-func SendGeminiRequestsParallel(payloads []map[string]any, isStreaming bool) []GeminiRequestResult {
-	results := make([]GeminiRequestResult, len(payloads))
-	var wg sync.WaitGroup
-
-	// Use buffered channel to collect results
-	resultChan := make(chan GeminiRequestResult, len(payloads))
-
-	// Launch goroutines for each request
-	for i, payload := range payloads {
-		wg.Add(1)
-		go func(index int, p map[string]any) {
-			defer wg.Done()
-
-			response, err := SendGeminiRequest(p, isStreaming)
-			resultChan <- GeminiRequestResult{
-				Index:    index,
-				Response: response,
-				Error:    err,
-			}
-		}(i, payload)
-	}
-
-	// Close channel when all goroutines complete
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
-
-	// Collect results and place them in correct order
-	for result := range resultChan {
-		results[result.Index] = result
-	}
-
-	return results
-}
-
-// SendGeminiRequestsParallelWithLimit sends multiple Gemini requests in parallel with concurrency limit
-// maxConcurrent controls how many requests can run simultaneously
-// This prevents overwhelming the system or hitting rate limits too quickly
-func SendGeminiRequestsParallelWithLimit(payloads []map[string]any, isStreaming bool, maxConcurrent int) []GeminiRequestResult {
-	if maxConcurrent <= 0 {
-		maxConcurrent = 10 // Default to 10 concurrent requests
-	}
-
-	results := make([]GeminiRequestResult, len(payloads))
-	var wg sync.WaitGroup
-
-	// Semaphore to limit concurrent requests
-	semaphore := make(chan struct{}, maxConcurrent)
-	resultChan := make(chan GeminiRequestResult, len(payloads))
-
-	// Launch goroutines for each request
-	for i, payload := range payloads {
-		wg.Add(1)
-		go func(index int, p map[string]any) {
-			defer wg.Done()
-
-			// Acquire semaphore
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }() // Release semaphore
-
-			response, err := SendGeminiRequest(p, isStreaming)
-			resultChan <- GeminiRequestResult{
-				Index:    index,
-				Response: response,
-				Error:    err,
-			}
-		}(i, payload)
-	}
-
-	// Close channel when all goroutines complete
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
-
-	// Collect results and place them in correct order
-	for result := range resultChan {
-		results[result.Index] = result
-	}
-
-	return results
-}
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gcli2apigo/internal/auth"
+	"gcli2apigo/internal/breaker"
+	"gcli2apigo/internal/config"
+	"gcli2apigo/internal/httputil"
+	"gcli2apigo/internal/metrics"
+	"gcli2apigo/internal/usage"
+
+	"golang.org/x/oauth2"
+)
+
+// tokenRefreshTimeout bounds a single OAuth token refresh request
+const tokenRefreshTimeout = 30 * time.Second
+
+// TokenRefreshManager manages token refresh operations with per-credential locking
+// to ensure only one refresh happens per credential even with concurrent requests
+type TokenRefreshManager struct {
+	// refreshMutexes stores a mutex for each credential file path
+	// Using sync.Map for thread-safe concurrent access without global lock
+	refreshMutexes sync.Map // map[string]*sync.Mutex
+}
+
+// NewTokenRefreshManager creates a new TokenRefreshManager instance
+func NewTokenRefreshManager() *TokenRefreshManager {
+	return &TokenRefreshManager{}
+}
+
+// RefreshToken refreshes the OAuth token for a credential with per-credential locking
+// This ensures only one refresh operation happens per credential even with concurrent requests
+func (trm *TokenRefreshManager) RefreshToken(credEntry *auth.CredentialEntry) error {
+	refreshed, err := trm.refreshBefore(credEntry, time.Now())
+	if refreshed {
+		// Save the refreshed token asynchronously to avoid blocking
+		auth.SaveRefreshedTokenAsync(credEntry)
+	}
+	return err
+}
+
+// refreshBefore refreshes the token of a credential unless it is still valid at deadline.
+// refreshed reports whether a new token was obtained; the caller persists it.
+func (trm *TokenRefreshManager) refreshBefore(credEntry *auth.CredentialEntry, deadline time.Time) (refreshed bool, err error) {
+	if credEntry == nil || credEntry.FilePath == "" {
+		return false, fmt.Errorf("invalid credential entry")
+	}
+
+	// Get or create a mutex for this specific credential
+	mutexInterface, _ := trm.refreshMutexes.LoadOrStore(credEntry.FilePath, &sync.Mutex{})
+	mutex := mutexInterface.(*sync.Mutex)
+
+	// Acquire the credential-specific lock
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	// Check if token still needs refresh (another goroutine may have already refreshed it)
+	token := credEntry.Token()
+	if !token.Expiry.Before(deadline) && token.AccessToken != "" {
+		if config.IsDebugEnabled() {
+			log.Printf("[DEBUG] Token already refreshed by another request for credential: %s", credEntry.FilePath)
+		}
+		return false, nil
+	}
+
+	// Perform the actual token refresh
+	if config.IsDebugEnabled() {
+		log.Printf("[DEBUG] Refreshing token for credential: %s (expiry: %s)", credEntry.FilePath, token.Expiry.Format(time.RFC3339))
+	}
+
+	// Extract client credentials from token extra data or use defaults
+	clientID := config.ClientID
+	clientSecret := config.ClientSecret
+	if extra := token.Extra("client_id"); extra != nil {
+		if id, ok := extra.(string); ok && id != "" {
+			clientID = id
+		}
+	}
+	if extra := token.Extra("client_secret"); extra != nil {
+		if secret, ok := extra.(string); ok && secret != "" {
+			clientSecret = secret
+		}
+	}
+
+	oauthConfig := &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Endpoint: oauth2.Endpoint{
+			TokenURL: config.GetOAuth2Endpoint() + "/token",
+		},
+	}
+
+	// Bound the call: the credential's lock is held until it returns. Use the shared client so
+	// the configured proxy applies to token requests too.
+	ctx, cancel := context.WithTimeout(context.Background(), tokenRefreshTimeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, httputil.SharedHTTPClient)
+
+	// Pass only the refresh token: the token source would return a token that is still valid as is
+	tokenSource := oauthConfig.TokenSource(ctx, &oauth2.Token{RefreshToken: token.RefreshToken})
+	newToken, err := tokenSource.Token()
+	auth.RecordRefreshResult(credEntry.ProjectID, err)
+	if err != nil {
+		log.Printf("[WARN] Token refresh failed for credential %s: %v", credEntry.FilePath, err)
+		metrics.RecordTokenRefresh(false)
+		return false, fmt.Errorf("token refresh failed: %w", err)
+	}
+	metrics.RecordTokenRefresh(true)
+
+	if config.IsDebugEnabled() {
+		log.Printf("[DEBUG] Token refreshed successfully for credential: %s (new expiry: %s)", credEntry.FilePath, newToken.Expiry.Format(time.RFC3339))
+	}
+
+	// Keep the client credentials with the new token so later refreshes use them too
+	newToken = newToken.WithExtra(map[string]interface{}{
+		"client_id":     token.Extra("client_id"),
+		"client_secret": token.Extra("client_secret"),
+		"token_uri":     token.Extra("token_uri"),
+	})
+
+	// Update the credential entry with the new token
+	credEntry.SetToken(newToken)
+	return true, nil
+}
+
+var (
+	// globalTokenRefreshManager is the global instance used across the application
+	globalTokenRefreshManager = NewTokenRefreshManager()
+)
+
+// ErrRateLimited is returned when every credential tried for a request was rate limited
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// APIKeyIDField is the payload field holding the named API key the request is attributed to.
+// It is read by SendGeminiRequest for per-key usage tracking and never sent upstream.
+const APIKeyIDField = "api_key_id"
+
+// SendGeminiRequest sends a request to Google's Gemini API
+// Process: 1. Randomly obtain OAuth credential, 2. Refresh token if needed, 3. Make API request, 4. Return
+// If a 429 error occurs, automatically retry with different OAuth credentials until success or all credentials exhausted
+// Cancelling ctx aborts the upstream request, stops the retry loop and ends a streaming response
+func SendGeminiRequest(ctx context.Context, payload map[string]any, isStreaming bool) (any, error) {
+	action := "generateContent"
+	if isStreaming {
+		action = "streamGenerateContent"
+	}
+	return sendGeminiAction(ctx, payload, action, isStreaming)
+}
+
+// SendGeminiEmbedRequest sends a non-streaming embedContent or batchEmbedContents request
+// through the credential pool, with the same rotation as SendGeminiRequest. Embeddings do not
+// use the credential's generation quota, so only the named API key's request count is recorded.
+func SendGeminiEmbedRequest(ctx context.Context, payload map[string]any, action string) (map[string]any, error) {
+	if action != "embedContent" && action != "batchEmbedContents" {
+		return nil, fmt.Errorf("unsupported embedding action: %s", action)
+	}
+
+	resp, projID, err := openGeminiAction(ctx, payload, action, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := handleNonStreamingResponse(resp)
+	breaker.GetCircuitBreaker().RecordResult(projID, resp.StatusCode)
+	if resp.StatusCode != http.StatusOK {
+		usage.GetTracker().SetErrorCode(projID, resp.StatusCode)
+	} else if apiKeyID, _ := payload[APIKeyIDField].(string); err == nil && apiKeyID != "" {
+		usage.GetTracker().IncrementKeyUsage(apiKeyID)
+	}
+	return response, err
+}
+
+// SendGeminiCountTokensRequest sends a countTokens request through the credential pool.
+// The payload request must already be in Code Assist countTokens form (see
+// transformers.GeminiCountTokensRequest). Counting tokens does not generate content, so it is
+// not recorded as usage against the credential or the named API key.
+func SendGeminiCountTokensRequest(ctx context.Context, payload map[string]any) (map[string]any, error) {
+	resp, projID, err := openGeminiAction(ctx, payload, "countTokens", false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := handleNonStreamingResponse(resp)
+	breaker.GetCircuitBreaker().RecordResult(projID, resp.StatusCode)
+	if resp.StatusCode != http.StatusOK {
+		usage.GetTracker().SetErrorCode(projID, resp.StatusCode)
+	}
+	return response, err
+}
+
+// openGeminiAction performs the credential selection and retry loop for a single Code Assist action
+// and returns the final upstream response with the project ID of the credential that produced it.
+// Credentials in exclude (keyed by project ID) are not tried.
+func openGeminiAction(ctx context.Context, payload map[string]any, action string, isStreaming bool, exclude map[string]bool) (*http.Response, string, error) {
+	// Track which credentials have been tried to avoid retrying the same one
+	triedCredentials := make(map[string]bool, len(exclude))
+	for projectID := range exclude {
+		triedCredentials[projectID] = true
+	}
+
+	// Extract model name for usage tracking
+	modelName := ""
+	if model, ok := payload["model"].(string); ok {
+		modelName = model
+	}
+
+	// Retry loop: try different credentials on 429 errors, and back off before retrying
+	// 5xx responses and transport errors
+	// Limited by MAX_RETRY_ATTEMPTS (default: 5) upstream attempts and RETRY_BUDGET_SECONDS
+	// These are read dynamically to allow runtime updates without restart
+	maxRetries := config.GetMaxRetryAttempts()
+	if maxRetries <= 0 {
+		maxRetries = 5 // Ensure at least 1 attempt
+	}
+	budget := config.GetRetryBudget()
+	deadline := time.Now().Add(budget)
+	stats := retryStatsFromContext(ctx)
+	attempts := 0
+
+	// Every upstream attempt beyond the first counts as a retry
+	defer func() {
+		if attempts > 0 {
+			metrics.ObserveRetries(action, attempts-1)
+		}
+	}()
+
+	// retry waits delay before another attempt after a failed one. It returns false if the attempt
+	// limit or the time budget does not allow another attempt, and an error if ctx was cancelled.
+	retry := func(reason string, delay time.Duration) (bool, error) {
+		if attempts >= maxRetries {
+			log.Printf("[ERROR] Retry limit reached after %d attempts (max: %d, last: %s)", attempts, maxRetries, reason)
+			return false, nil
+		}
+		if time.Now().Add(delay).After(deadline) {
+			log.Printf("[ERROR] Retry budget of %v exhausted after %d attempts (last: %s, next delay: %v)", budget, attempts, reason, delay)
+			return false, nil
+		}
+
+		stats.Add(reason)
+		metrics.RecordRetry(action, reason)
+		if delay > 0 {
+			log.Printf("[WARN] Retrying %s after %s in %v (attempt %d/%d)", action, reason, delay, attempts+1, maxRetries)
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return false, fmt.Errorf("request cancelled: %w", err)
+		}
+
+		// Once every credential has been tried, allow them to be tried again
+		if len(triedCredentials) >= auth.GetCredentialPoolSize() {
+			clear(triedCredentials)
+		}
+		return true, nil
+	}
+
+	// Track if we've already tried reloading credentials
+	hasReloadedCredentials := false
+
+	for {
+		// Stop retrying once the client has disconnected or the request timed out
+		if err := ctx.Err(); err != nil {
+			log.Printf("[INFO] Request cancelled after trying %d credential(s): %v", len(triedCredentials), err)
+			return nil, "", fmt.Errorf("request cancelled: %w", err)
+		}
+
+		// Step 1: Select an untried OAuth credential using the configured selection strategy
+		credEntry, err := auth.GetCredentialForModel(modelName, triedCredentials)
+		if err != nil {
+			// Check if error is due to no credentials available
+			if strings.Contains(err.Error(), "no credentials available") || strings.Contains(err.Error(), "credential pool not initialized") {
+				// Try reloading credentials once if we haven't already
+				if !hasReloadedCredentials {
+					log.Printf("[WARN] No credentials available, attempting to reload credential pool...")
+					if reloadErr := auth.ReloadCredentialPool(); reloadErr != nil {
+						log.Printf("[ERROR] Failed to reload credential pool: %v", reloadErr)
+						log.Printf("[ERROR] Credential selection failed: %v", err)
+						return nil, "", fmt.Errorf("credential selection failed: %v", err)
+					}
+
+					hasReloadedCredentials = true
+					log.Printf("[INFO] Credential pool reloaded, retrying credential selection...")
+
+					// Retry getting credentials after reload
+					credEntry, err = auth.GetCredentialForModel(modelName, triedCredentials)
+					if err != nil {
+						log.Printf("[ERROR] Still no credentials available after reload: %v", err)
+						return nil, "", fmt.Errorf("credential selection failed: %v", err)
+					}
+
+					// Successfully got credentials after reload, continue with request
+					log.Printf("[INFO] Successfully selected credential after reload: %s", credEntry.ProjectID)
+				} else {
+					// Already tried reloading, return error
+					log.Printf("[ERROR] Credential selection failed: %v", err)
+					return nil, "", fmt.Errorf("credential selection failed: %v", err)
+				}
+			} else {
+				// Different error, return immediately
+				log.Printf("[ERROR] Credential selection failed: %v", err)
+				return nil, "", fmt.Errorf("credential selection failed: %v", err)
+			}
+		}
+
+		// Check if we've already tried this credential
+		if triedCredentials[credEntry.ProjectID] {
+			// Check if we've reached max retry attempts or tried all available credentials
+			poolSize := auth.GetCredentialPoolSize()
+			if len(triedCredentials) >= maxRetries || len(triedCredentials) >= poolSize {
+				log.Printf("[ERROR] Retry limit reached: tried %d credentials (max: %d, pool size: %d)",
+					len(triedCredentials), maxRetries, poolSize)
+				return nil, "", fmt.Errorf("%w: retry limit reached after %d attempts", ErrRateLimited, len(triedCredentials))
+			}
+			// Skip this credential and try to get another one
+			continue
+		}
+
+		// Mark this credential as tried
+		triedCredentials[credEntry.ProjectID] = true
+
+		creds := credEntry.Token()
+		projID := credEntry.ProjectID
+		if config.IsDebugEnabled() {
+			log.Printf("[DEBUG] Selected credential from: %s (project: %s) [attempt %d/%d]",
+				credEntry.FilePath, projID, len(triedCredentials), auth.GetCredentialPoolSize())
+		}
+
+		// Step 2: Refresh the token if needed (expired OR no access token)
+		needsRefresh := creds.Expiry.Before(time.Now()) || creds.AccessToken == ""
+
+		if needsRefresh && creds.RefreshToken != "" {
+			if config.IsDebugEnabled() {
+				if creds.AccessToken == "" {
+					log.Printf("[DEBUG] No access token, refreshing for credential: %s", credEntry.FilePath)
+				} else {
+					log.Printf("[DEBUG] Token expired (expiry: %s), refreshing for credential: %s", creds.Expiry.Format(time.RFC3339), credEntry.FilePath)
+				}
+			}
+
+			// Use TokenRefreshManager to handle refresh with per-credential locking
+			err := globalTokenRefreshManager.RefreshToken(credEntry)
+			if err != nil {
+				log.Printf("Warning: Token refresh failed for credential %s: %v", credEntry.FilePath, err)
+				breaker.GetCircuitBreaker().RecordFailure(projID, breaker.FailureRefresh)
+				if auth.BanIfTerminal(projID, err) || creds.AccessToken == "" {
+					// Try next credential
+					continue
+				}
+				// Continue with existing token as per requirement 2.4
+			} else {
+				// Token was refreshed successfully, update local reference
+				creds = credEntry.Token()
+			}
+		} else if creds.AccessToken == "" {
+			log.Printf("[WARN] No access token available for credential %s, trying next credential", credEntry.FilePath)
+			continue
+		} else {
+			if config.IsDebugEnabled() {
+				log.Printf("[DEBUG] Token is still valid (expiry: %s)", creds.Expiry.Format(time.RFC3339))
+			}
+		}
+
+		// Step 3: Make API request (onboarding and actual request)
+
+		// Onboard user with selected credential
+		err = auth.OnboardUser(creds, projID)
+		if err != nil {
+			// Check if it's a 401 error and try refreshing the token
+			if strings.Contains(err.Error(), "401") && creds.RefreshToken != "" {
+				if config.IsDebugEnabled() {
+					log.Printf("[DEBUG] Got 401 during onboarding, forcing token refresh...")
+				}
+
+				// Reset onboarding state since credentials are invalid
+				auth.ResetOnboardingState()
+
+				// Use TokenRefreshManager to handle refresh with per-credential locking
+				refreshErr := globalTokenRefreshManager.RefreshToken(credEntry)
+				if refreshErr != nil {
+					log.Printf("Warning: Failed to refresh token after 401: %v", refreshErr)
+					breaker.GetCircuitBreaker().RecordFailure(projID, breaker.FailureRefresh)
+					auth.BanIfTerminal(projID, refreshErr)
+					// Try next credential
+					continue
+				}
+
+				if config.IsDebugEnabled() {
+					log.Printf("[DEBUG] Token refreshed after 401, retrying onboarding...")
+				}
+				// Update local reference to refreshed token
+				creds = credEntry.Token()
+
+				// Retry onboarding with refreshed token
+				if retryErr := auth.OnboardUser(creds, projID); retryErr != nil {
+					log.Printf("[WARN] Failed to onboard user after token refresh: %v, trying next credential", retryErr)
+					auth.BanIfTerminal(projID, retryErr)
+					continue
+				}
+				if config.IsDebugEnabled() {
+					log.Printf("[DEBUG] Onboarding successful after token refresh")
+				}
+			} else {
+				log.Printf("[WARN] Failed to onboard user: %v, trying next credential", err)
+				auth.BanIfTerminal(projID, err)
+				continue
+			}
+		}
+
+		// Build the final payload with project info
+		requestData, _ := payload["request"].(map[string]any)
+		if requestData == nil {
+			requestData = make(map[string]any)
+		}
+
+		// countTokens takes the model inside the request and rejects the other fields
+		finalPayload := map[string]any{
+			"request": requestData,
+		}
+		if action != "countTokens" {
+			finalPayload["model"] = payload["model"]
+			finalPayload["project"] = projID
+		}
+
+		// Build the URL using strings.Builder to avoid allocations
+		var urlBuilder strings.Builder
+		endpoint := config.GetCodeAssistEndpoint()
+		urlBuilder.WriteString(endpoint)
+		urlBuilder.WriteString("/v1internal:")
+		urlBuilder.WriteString(action)
+		if isStreaming {
+			urlBuilder.WriteString("?alt=sse")
+		}
+		targetURL := urlBuilder.String()
+
+		log.Printf("[DEBUG] Gemini API request - Endpoint: %s, Action: %s, Full URL: %s", endpoint, action, targetURL)
+
+		// Build request
+		jsonData, err := json.Marshal(finalPayload)
+		if err != nil {
+			return nil, "", err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "POST", targetURL, bytes.NewBuffer(jsonData))
+		if err != nil {
+			return nil, "", err
+		}
+
+		// Use strings.Builder to avoid string allocation in hot path
+		var authHeader strings.Builder
+		authHeader.WriteString("Bearer ")
+		authHeader.WriteString(creds.AccessToken)
+		req.Header.Set("Authorization", authHeader.String())
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", config.GetUserAgent())
+
+		// Send the request using the shared HTTP client
+		attempts++
+		requestStart := time.Now()
+		resp, err := httputil.SharedHTTPClient.Do(req)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				log.Printf("[INFO] Upstream request for project %s aborted: %v", projID, ctxErr)
+				return nil, "", fmt.Errorf("request cancelled: %w", ctxErr)
+			}
+
+			// Connection resets and timeouts are retried with backoff
+			if reason, retryable := retryableErrorReason(err); retryable {
+				log.Printf("[WARN] Upstream request for project %s failed (%s): %v", projID, reason, err)
+				ok, retryErr := retry(reason, backoffDelay(stats.Count()+1))
+				if retryErr != nil {
+					return nil, "", retryErr
+				}
+				if ok {
+					continue
+				}
+			}
+			return nil, "", fmt.Errorf("request failed: %w", err)
+		}
+		metrics.ObserveUpstreamTTFB(action, modelName, time.Since(requestStart))
+
+		// Check for 429 error and retry with different credential
+		if resp.StatusCode == http.StatusTooManyRequests {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			log.Printf("[WARN] Received 429 (Too Many Requests) for project %s, retrying with different credential... (attempt %d/%d)",
+				projID, attempts, maxRetries)
+
+			// Track error code for this project
+			usage.GetTracker().SetErrorCode(projID, resp.StatusCode)
+			breaker.GetCircuitBreaker().RecordResult(projID, resp.StatusCode)
+			metrics.RecordRateLimited(projID)
+
+			// Rotate immediately while untried credentials remain; once all have been tried,
+			// wait for the delay the upstream asked for before trying them again
+			var delay time.Duration
+			if len(triedCredentials) >= auth.GetCredentialPoolSize() {
+				if hinted, ok := serverRetryDelay(resp.Header, body); ok {
+					delay = hinted
+				} else {
+					delay = backoffDelay(stats.Count() + 1)
+				}
+			}
+
+			ok, retryErr := retry(retryStatusReason(resp.StatusCode), delay)
+			if retryErr != nil {
+				return nil, "", retryErr
+			}
+			if !ok {
+				return nil, "", fmt.Errorf("%w: retry limit reached after %d attempts", ErrRateLimited, attempts)
+			}
+
+			// Continue to next iteration to try another credential
+			continue
+		}
+
+		// Retry transient server errors with backoff, honouring Retry-After and RetryInfo.
+		// When no retry is left the response is passed through to the caller.
+		if isRetryableStatus(resp.StatusCode) {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+			log.Printf("[WARN] Received %d for project %s (attempt %d/%d)", resp.StatusCode, projID, attempts, maxRetries)
+
+			delay, hinted := serverRetryDelay(resp.Header, body)
+			if !hinted {
+				delay = backoffDelay(stats.Count() + 1)
+			}
+
+			ok, retryErr := retry(retryStatusReason(resp.StatusCode), delay)
+			if retryErr != nil {
+				return nil, "", retryErr
+			}
+			if ok {
+				usage.GetTracker().SetErrorCode(projID, resp.StatusCode)
+				breaker.GetCircuitBreaker().RecordResult(projID, resp.StatusCode)
+				continue
+			}
+		}
+
+		return resp, projID, nil
+	}
+}
+
+// sendGeminiAction sends a single Code Assist action and handles its response and usage tracking
+func sendGeminiAction(ctx context.Context, payload map[string]any, action string, isStreaming bool) (any, error) {
+	resp, projID, err := openGeminiAction(ctx, payload, action, isStreaming, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Extract model name for usage tracking
+	modelName, _ := payload["model"].(string)
+
+	// Named API key to attribute usage to, if any
+	apiKeyID, _ := payload[APIKeyIDField].(string)
+
+	var result any
+	var responseErr error
+
+	if isStreaming {
+		var failover *streamFailover
+		if config.IsStreamFailoverEnabled() {
+			failover = newStreamFailover(payload, action, projID)
+		}
+		result, responseErr = handleStreamingResponse(ctx, resp, apiKeyID, failover)
+	} else {
+		result, responseErr = handleNonStreamingResponse(resp)
+	}
+
+	recordResponseUsage(projID, modelName, apiKeyID, resp.StatusCode, result, responseErr)
+	return result, responseErr
+}
+
+// recordResponseUsage tracks usage and error status of a final upstream response
+func recordResponseUsage(projID, modelName, apiKeyID string, status int, result any, responseErr error) {
+	breaker.GetCircuitBreaker().RecordResult(projID, status)
+	if responseErr == nil && status == http.StatusOK {
+		isProModel := usage.IsProModel(modelName)
+		usage.GetTracker().IncrementUsage(projID, isProModel)
+		if config.IsDebugEnabled() {
+			log.Printf("[DEBUG] Usage tracked for project %s (model: %s, isPro: %v)", projID, modelName, isProModel)
+		}
+
+		if apiKeyID != "" {
+			usage.GetTracker().IncrementKeyUsage(apiKeyID)
+			// Streaming token usage is recorded when the stream ends
+			if response, ok := result.(map[string]any); ok {
+				usage.GetTracker().AddKeyTokens(apiKeyID, totalTokenCount(response))
+			}
+		}
+	} else if status != http.StatusOK {
+		// Track error code for this project
+		usage.GetTracker().SetErrorCode(projID, status)
+		if config.IsDebugEnabled() {
+			log.Printf("[DEBUG] Error code %d tracked for project %s", status, projID)
+		}
+	}
+}
+
+// totalTokenCount returns usageMetadata.totalTokenCount from a Gemini response, or 0
+func totalTokenCount(response map[string]any) int {
+	usageMetadata, _ := response["usageMetadata"].(map[string]any)
+	total, _ := usageMetadata["totalTokenCount"].(float64)
+	return int(total)
+}
+
+// handleStreamingResponse relays SSE chunks from resp on the returned channel, which is closed when the
+// upstream stream ends or ctx is cancelled. The response body is bound to ctx, so cancelling it also
+// unblocks a pending read. With a non-nil failover, a stream that ends without a finish reason is
+// resumed on another credential.
+func handleStreamingResponse(ctx context.Context, resp *http.Response, apiKeyID string, failover *streamFailover) (chan string, error) {
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		log.Printf("Google API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("API error: %d", resp.StatusCode)
+	}
+
+	streamChan := make(chan string, 100)
+
+	metrics.StreamStarted()
+	go func() {
+		defer metrics.StreamEnded()
+		defer close(streamChan)
+
+		progress := &streamProgress{}
+
+		// Record the total tokens of all upstream streams for the API key
+		if apiKeyID != "" {
+			defer func() {
+				usage.GetTracker().AddKeyTokens(apiKeyID, progress.totalTokens())
+			}()
+		}
+
+		// send delivers a chunk unless the consumer has gone away
+		send := func(chunk string) bool {
+			select {
+			case streamChan <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		err := relayStream(ctx, resp, send, progress, failover != nil)
+		if failover != nil {
+			failover.resume(ctx, err, progress, send)
+		}
+	}()
+
+	return streamChan, nil
+}
+
+// streamProgress tracks what has been relayed to the client across upstream streams
+type streamProgress struct {
+	text          strings.Builder // Non-thought model text relayed so far
+	chunks        int             // Chunks relayed so far
+	calledTools   bool            // A function call was relayed
+	finished      bool            // A candidate reported a finish reason
+	tokens        int             // Tokens of completed upstream streams
+	segmentTokens int             // usageMetadata is cumulative, so this is the latest total of the current stream
+}
+
+// observe records a relayed Gemini response chunk
+func (sp *streamProgress) observe(response map[string]any) {
+	sp.chunks++
+	if total := totalTokenCount(response); total > 0 {
+		sp.segmentTokens = total
+	}
+
+	candidates, _ := response["candidates"].([]any)
+	for _, candidate := range candidates {
+		candMap, _ := candidate.(map[string]any)
+		if finishReason, _ := candMap["finishReason"].(string); finishReason != "" {
+			sp.finished = true
+		}
+		content, _ := candMap["content"].(map[string]any)
+		parts, _ := content["parts"].([]any)
+		for _, part := range parts {
+			partMap, _ := part.(map[string]any)
+			if _, ok := partMap["functionCall"]; ok {
+				sp.calledTools = true
+			}
+			if thought, _ := partMap["thought"].(bool); thought {
+				continue
+			}
+			if text, ok := partMap["text"].(string); ok {
+				sp.text.WriteString(text)
+			}
+		}
+	}
+}
+
+// endSegment is called when an upstream stream ends
+func (sp *streamProgress) endSegment() {
+	sp.tokens += sp.segmentTokens
+	sp.segmentTokens = 0
+}
+
+// totalTokens returns the tokens of all upstream streams so far
+func (sp *streamProgress) totalTokens() int {
+	return sp.tokens + sp.segmentTokens
+}
+
+// relayStream forwards the SSE chunks of resp through send and closes its body.
+// When stopOnError is set, an error event ends the relay and is returned instead of being forwarded.
+// It returns the read error, if any, that ended the stream.
+func relayStream(ctx context.Context, resp *http.Response, send func(string) bool, progress *streamProgress, stopOnError bool) error {
+	defer resp.Body.Close()
+	defer progress.endSegment()
+
+	// Use larger buffer for scanner to handle large chunks
+	scanner := bufio.NewScanner(resp.Body)
+	buf := make([]byte, 64*1024)
+	scanner.Buffer(buf, 256*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Use CutPrefix to avoid double prefix check and allocation
+		if chunk, found := strings.CutPrefix(line, "data: "); found {
+
+			var obj map[string]any
+			if err := json.Unmarshal([]byte(chunk), &obj); err != nil {
+				continue
+			}
+
+			if errObj, isError := obj["error"]; isError && stopOnError {
+				errJSON, _ := json.Marshal(errObj)
+				return fmt.Errorf("upstream error event: %s", errJSON)
+			}
+
+			if response, ok := obj["response"].(map[string]any); ok {
+				progress.observe(response)
+				responseJSON, _ := json.Marshal(response)
+				if !send(string(responseJSON)) {
+					break
+				}
+			} else {
+				progress.observe(obj)
+				if !send(chunk) {
+					break
+				}
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		if config.IsDebugEnabled() {
+			log.Printf("[DEBUG] Stream relay stopped: %v", err)
+		}
+		return err
+	}
+	return scanner.Err()
+}
+
+func handleNonStreamingResponse(resp *http.Response) (map[string]any, error) {
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Google API returned status %d: %s", resp.StatusCode, string(body))
+
+		var errorData map[string]any
+		if err := json.Unmarshal(body, &errorData); err == nil {
+			if errObj, ok := errorData["error"].(map[string]any); ok {
+				return map[string]any{
+					"error": errObj,
+				}, nil
+			}
+		}
+
+		return map[string]any{
+			"error": map[string]any{
+				"message": fmt.Sprintf("API error: %d", resp.StatusCode),
+				"code":    resp.StatusCode,
+			},
+		}, nil
+	}
+
+	// Parse response - use CutPrefix to avoid allocation and double check
+	responseText, _ := strings.CutPrefix(string(body), "data: ")
+
+	var googleAPIResponse map[string]any
+	if err := json.Unmarshal([]byte(responseText), &googleAPIResponse); err != nil {
+		return nil, err
+	}
+
+	if standardGeminiResponse, ok := googleAPIResponse["response"].(map[string]any); ok {
+		return standardGeminiResponse, nil
+	}
+
+	return googleAPIResponse, nil
+}
+
+// BuildGeminiPayloadFromOpenAI builds a Gemini API payload from an OpenAI-transformed request
+func BuildGeminiPayloadFromOpenAI(openaiPayload map[string]any) map[string]any {
+	model, _ := openaiPayload["model"].(string)
+
+	safetySettings := config.DefaultSafetySettings
+	if ss, ok := openaiPayload["safetySettings"]; ok && ss != nil {
+		if ssSlice, ok := ss.([]config.SafetySetting); ok {
+			safetySettings = ssSlice
+		}
+	}
+
+	requestData := map[string]any{
+		"contents":         openaiPayload["contents"],
+		"safetySettings":   safetySettings,
+		"generationConfig": openaiPayload["generationConfig"],
+	}
+
+	if systemInstruction, ok := openaiPayload["systemInstruction"]; ok && systemInstruction != nil {
+		requestData["systemInstruction"] = systemInstruction
+	}
+	if cachedContent, ok := openaiPayload["cachedContent"]; ok && cachedContent != nil {
+		requestData["cachedContent"] = cachedContent
+	}
+	if tools, ok := openaiPayload["tools"]; ok && tools != nil {
+		requestData["tools"] = tools
+	}
+	if toolConfig, ok := openaiPayload["toolConfig"]; ok && toolConfig != nil {
+		requestData["toolConfig"] = toolConfig
+	}
+
+	return map[string]any{
+		"model":   model,
+		"request": requestData,
+	}
+}
+
+// BuildGeminiPayloadFromNative builds a Gemini API payload from a native Gemini request
+func BuildGeminiPayloadFromNative(nativeRequest map[string]any, modelFromPath string) map[string]any {
+	nativeRequest["safetySettings"] = config.DefaultSafetySettings
+
+	// Ensure generationConfig exists
+	var genConfig map[string]any
+	if existingConfig, ok := nativeRequest["generationConfig"].(map[string]any); ok {
+		genConfig = existingConfig
+	} else {
+		genConfig = make(map[string]any)
+		nativeRequest["generationConfig"] = genConfig
+	}
+
+	// Set minimum thinking budget if not already specified
+	if _, hasThinkingConfig := genConfig["thinkingConfig"]; !hasThinkingConfig {
+		genConfig["thinkingConfig"] = map[string]any{
+			"thinkingBudget": config.GetThinkingBudget(modelFromPath),
+		}
+	}
+
+	return map[string]any{
+		"model":   modelFromPath,
+		"request": nativeRequest,
+	}
+}
+
+// GeminiRequestResult holds the result of a parallel Gemini request
+type GeminiRequestResult struct {
+	Index    int   // Original index in the batch
+	Response any   // Response data (chan string for streaming, map for non-streaming)
+	Error    error // Error if request failed
+}
+
+// SendGeminiRequestsParallel sends multiple Gemini requests in parallel using goroutines
+// Returns a slice of results in the same order as the input payloads
+// This is useful for batch processing multiple independent requests
+func SendGeminiRequestsParallel(ctx context.Context, payloads []map[string]any, isStreaming bool) []GeminiRequestResult {
+	results := make([]GeminiRequestResult, len(payloads))
+	var wg sync.WaitGroup
+
+	// Use buffered channel to collect results
+	resultChan := make(chan GeminiRequestResult, len(payloads))
+
+	// Launch goroutines for each request
+	for i, payload := range payloads {
+		wg.Add(1)
+		go func(index int, p map[string]any) {
+			defer wg.Done()
+
+			response, err := SendGeminiRequest(ctx, p, isStreaming)
+			resultChan <- GeminiRequestResult{
+				Index:    index,
+				Response: response,
+				Error:    err,
+			}
+		}(i, payload)
+	}
+
+	// Close channel when all goroutines complete
+	go func() {
+		wg.Wait()
+		close(resultChan)
+	}()
+
+	// Collect results and place them in correct order
+	for result := range resultChan {
+		results[result.Index] = result
+	}
+
+	return results
+}
+
+// SendGeminiRequestsParallelWithLimit sends multiple Gemini requests in parallel with concurrency limit
+// maxConcurrent controls how many requests can run simultaneously
+// This prevents overwhelming the system or hitting rate limits too quickly
+func SendGeminiRequestsParallelWithLimit(ctx context.Context, payloads []map[string]any, isStreaming bool, maxConcurrent int) []GeminiRequestResult {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 10 // Default to 10 concurrent requests
+	}
+
+	results := make([]GeminiRequestResult, len(payloads))
+	var wg sync.WaitGroup
+
+	// Semaphore to limit concurrent requests
+	semaphore := make(chan struct{}, maxConcurrent)
+	resultChan := make(chan GeminiRequestResult, len(payloads))
+
+	// Launch goroutines for each request
+	for i, payload := range payloads {
+		wg.Add(1)
+		go func(index int, p map[string]any) {
+			defer wg.Done()
+
+			// Acquire semaphore
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }() // Release semaphore
+
+			response, err := SendGeminiRequest(ctx, p, isStreaming)
+			resultChan <- GeminiRequestResult{
+				Index:    index,
+				Response: response,
+				Error:    err,
+			}
+		}(i, payload)
+	}
+
+	// Close channel when all goroutines complete
+	go func() {
+		wg.Wait()
+		close(resultChan)
+	}()
+
+	// Collect results and place them in correct order
+	for result := range resultChan {
+		results[result.Index] = result
+	}
+
+	return results
+}
@@ -0,0 +1,192 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"gcli2apigo/internal/config"
+)
+
+// Retry reasons reported in the X-Gcli-Retry-Reasons header and the retry metrics
+const (
+	RetryReasonConnectionReset   = "connection_reset"
+	RetryReasonConnectionRefused = "connection_refused"
+	RetryReasonTimeout           = "timeout"
+	RetryReasonEOF               = "eof"
+)
+
+// retryStatusReason returns the retry reason of a retryable upstream status
+func retryStatusReason(status int) string {
+	return "status_" + strconv.Itoa(status)
+}
+
+// isRetryableStatus reports whether a 5xx response is worth retrying
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryableErrorReason classifies a transport error; ok is false for errors that should not be retried.
+// Callers check their own context first, so a timeout here is the HTTP client's, not the caller's.
+func retryableErrorReason(err error) (string, bool) {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return RetryReasonConnectionReset, true
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return RetryReasonConnectionRefused, true
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return RetryReasonTimeout, true
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return RetryReasonEOF, true
+	case strings.Contains(err.Error(), "connection reset"):
+		return RetryReasonConnectionReset, true
+	default:
+		return "", false
+	}
+}
+
+// RetryStats records the retries made while serving a request
+type RetryStats struct {
+	mu      sync.Mutex
+	reasons []string
+}
+
+// Add records one retry and its reason
+func (rs *RetryStats) Add(reason string) {
+	rs.mu.Lock()
+	rs.reasons = append(rs.reasons, reason)
+	rs.mu.Unlock()
+}
+
+// Count returns the number of retries made
+func (rs *RetryStats) Count() int {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return len(rs.reasons)
+}
+
+// Reasons returns the retry reasons in the order they occurred
+func (rs *RetryStats) Reasons() []string {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return append([]string(nil), rs.reasons...)
+}
+
+// retryStatsKey is the context key for the RetryStats of a request
+type retryStatsKey struct{}
+
+// WithRetryStats returns a context in which SendGeminiRequest records its retries
+func WithRetryStats(ctx context.Context) (context.Context, *RetryStats) {
+	stats := &RetryStats{}
+	return context.WithValue(ctx, retryStatsKey{}, stats), stats
+}
+
+// retryStatsFromContext returns the RetryStats attached to ctx, or a throwaway one
+func retryStatsFromContext(ctx context.Context) *RetryStats {
+	if stats, ok := ctx.Value(retryStatsKey{}).(*RetryStats); ok {
+		return stats
+	}
+	return &RetryStats{}
+}
+
+// backoffDelay returns the jittered exponential delay before the given retry (1-based):
+// a random duration between half and all of base*2^(retry-1), capped at the configured maximum
+func backoffDelay(retry int) time.Duration {
+	base := config.GetRetryBaseDelay()
+	maxDelay := config.GetRetryMaxDelay()
+	d := base
+	for i := 1; i < retry && d < maxDelay; i++ {
+		d *= 2
+	}
+	d = min(d, maxDelay)
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// parseRetryAfter reads the Retry-After header, given either in seconds or as an HTTP date
+func parseRetryAfter(header http.Header) (time.Duration, bool) {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+// parseRetryInfo reads the retryDelay of a google.rpc.RetryInfo detail from a Google error body.
+// Code Assist sometimes wraps the error object in a single-element array.
+func parseRetryInfo(body []byte) (time.Duration, bool) {
+	type googleError struct {
+		Error struct {
+			Details []struct {
+				Type       string `json:"@type"`
+				RetryDelay string `json:"retryDelay"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+
+	var errs []googleError
+	var single googleError
+	if err := json.Unmarshal(body, &single); err == nil {
+		errs = append(errs, single)
+	} else if err := json.Unmarshal(body, &errs); err != nil {
+		return 0, false
+	}
+
+	for _, e := range errs {
+		for _, detail := range e.Error.Details {
+			if !strings.HasSuffix(detail.Type, "google.rpc.RetryInfo") || detail.RetryDelay == "" {
+				continue
+			}
+			if d, err := time.ParseDuration(detail.RetryDelay); err == nil && d >= 0 {
+				return d, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// serverRetryDelay returns the delay the upstream asked for, preferring Retry-After over RetryInfo
+func serverRetryDelay(header http.Header, body []byte) (time.Duration, bool) {
+	if d, ok := parseRetryAfter(header); ok {
+		return d, true
+	}
+	return parseRetryInfo(body)
+}
+
+// sleepContext waits for d or until ctx is done, returning ctx.Err() in the latter case
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,150 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+
+	"gcli2apigo/internal/auth"
+	"gcli2apigo/internal/config"
+	"gcli2apigo/internal/metrics"
+)
+
+// errStreamTruncated is the cause reported when an upstream stream ends cleanly but without a finish reason
+var errStreamTruncated = errors.New("stream ended without a finish reason")
+
+// streamFailover resumes an interrupted streaming request on another credential (STREAM_FAILOVER=true)
+type streamFailover struct {
+	payload         map[string]any
+	action          string
+	usedCredentials map[string]bool // Project IDs that already served part of this stream
+}
+
+// newStreamFailover prepares failover for a stream served by the given credential
+func newStreamFailover(payload map[string]any, action string, projectID string) *streamFailover {
+	return &streamFailover{
+		payload:         payload,
+		action:          action,
+		usedCredentials: map[string]bool{projectID: true},
+	}
+}
+
+// resume is called when an upstream stream has ended. If it ended without a finish reason, the request
+// is resent with the text relayed so far as a model-turn prefix and the continuation is relayed through
+// send, up to STREAM_FAILOVER_MAX_ATTEMPTS times. If the stream cannot be resumed, an error chunk is sent
+// so the client does not mistake the truncated answer for a complete one.
+func (sf *streamFailover) resume(ctx context.Context, cause error, progress *streamProgress, send func(string) bool) {
+	maxAttempts := config.GetStreamFailoverMaxAttempts()
+	modelName, _ := sf.payload["model"].(string)
+
+	for attempt := 1; !progress.finished; attempt++ {
+		// The client went away; there is nobody to resume for
+		if ctx.Err() != nil {
+			return
+		}
+		if cause == nil {
+			cause = errStreamTruncated
+		}
+
+		if progress.calledTools || attempt > maxAttempts {
+			reason := fmt.Sprintf("gave up after %d attempt(s)", attempt-1)
+			if progress.calledTools {
+				reason = "a function call was already sent"
+			}
+			log.Printf("[ERROR] Stream interrupted after %d chunk(s) and cannot be resumed (%s): %v", progress.chunks, reason, cause)
+			metrics.RecordStreamFailover("failed")
+			send(streamErrorChunk(cause, progress))
+			return
+		}
+
+		log.Printf("[WARN] Stream interrupted after %d chunk(s): %v; resuming on another credential (attempt %d/%d)",
+			progress.chunks, cause, attempt, maxAttempts)
+
+		continuation, err := sf.continuationPayload(progress.text.String())
+		if err != nil {
+			cause = err
+			attempt = maxAttempts // A payload that cannot be rebuilt will not get better
+			continue
+		}
+
+		// Prefer credentials that have not served this stream yet
+		var exclude map[string]bool
+		if len(sf.usedCredentials) < auth.GetCredentialPoolSize() {
+			exclude = sf.usedCredentials
+		}
+
+		resp, projID, err := openGeminiAction(ctx, continuation, sf.action, true, exclude)
+		if err != nil {
+			cause = err
+			continue
+		}
+		sf.usedCredentials[projID] = true
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			log.Printf("[WARN] Stream continuation returned status %d: %s", resp.StatusCode, string(body))
+			recordResponseUsage(projID, modelName, "", resp.StatusCode, nil, nil)
+			cause = fmt.Errorf("API error: %d", resp.StatusCode)
+			continue
+		}
+
+		// The continuation is billed against the credential but not counted as another API key request
+		recordResponseUsage(projID, modelName, "", resp.StatusCode, nil, nil)
+		cause = relayStream(ctx, resp, send, progress, true)
+		if progress.finished {
+			log.Printf("[INFO] Stream resumed on credential %s after %d attempt(s)", projID, attempt)
+			metrics.RecordStreamFailover("recovered")
+		}
+	}
+}
+
+// continuationPayload returns a copy of the original payload whose contents end with a model turn
+// holding the text already relayed, so the model continues where the interrupted stream stopped
+func (sf *streamFailover) continuationPayload(prefix string) (map[string]any, error) {
+	request, _ := sf.payload["request"].(map[string]any)
+	if request == nil {
+		return nil, errors.New("stream failover: payload has no request")
+	}
+
+	continuationRequest := maps.Clone(request)
+	if prefix != "" {
+		// Contents are built as []map[string]any by the transformers and []any for native requests;
+		// a JSON round trip normalises both
+		var contents []any
+		contentsJSON, err := json.Marshal(request["contents"])
+		if err != nil {
+			return nil, fmt.Errorf("stream failover: %w", err)
+		}
+		if err := json.Unmarshal(contentsJSON, &contents); err != nil {
+			return nil, fmt.Errorf("stream failover: %w", err)
+		}
+
+		continuationRequest["contents"] = append(contents, map[string]any{
+			"role":  "model",
+			"parts": []any{map[string]any{"text": prefix}},
+		})
+	}
+
+	continuation := maps.Clone(sf.payload)
+	continuation["request"] = continuationRequest
+	return continuation, nil
+}
+
+// streamErrorChunk returns a Gemini-style error chunk reporting an interrupted stream
+func streamErrorChunk(cause error, progress *streamProgress) string {
+	errorChunk := map[string]any{
+		"error": map[string]any{
+			"code":    http.StatusBadGateway,
+			"status":  "UNAVAILABLE",
+			"message": fmt.Sprintf("Upstream stream was interrupted after %d chunk(s) and could not be resumed: %v", progress.chunks, cause),
+		},
+	}
+	data, _ := json.Marshal(errorChunk)
+	return string(data)
+}
@@ -0,0 +1,147 @@
+package client
+
+import (
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"gcli2apigo/internal/auth"
+	"gcli2apigo/internal/banlist"
+	"gcli2apigo/internal/config"
+)
+
+// tokenRefreshCheckInterval is how often the background refresher looks for tokens close to expiry
+const tokenRefreshCheckInterval = 30 * time.Second
+
+// tokenRefreshMaxBackoff caps the wait before retrying a credential whose refreshes keep failing
+const tokenRefreshMaxBackoff = 30 * time.Minute
+
+// TokenRefresher renews access tokens in the background before they expire, so requests
+// do not wait for an OAuth round-trip. Refreshes are spread out by a random delay and at
+// most a configured number run at once.
+type TokenRefresher struct {
+	margin    time.Duration
+	semaphore chan struct{}
+	pending   map[string]bool // File paths of credentials with a scheduled refresh
+	mu        sync.Mutex
+	rng       *rand.Rand // Protected by mu
+}
+
+var tokenRefresherOnce sync.Once
+
+// StartTokenRefresher starts the background token refresher.
+// It is disabled when TOKEN_REFRESH_MARGIN_SECONDS is 0.
+func StartTokenRefresher() {
+	margin := config.GetTokenRefreshMargin()
+	if margin <= 0 {
+		log.Printf("[INFO] Background token refresh disabled")
+		return
+	}
+
+	tokenRefresherOnce.Do(func() {
+		concurrency := config.GetTokenRefreshConcurrency()
+		tr := &TokenRefresher{
+			margin:    margin,
+			semaphore: make(chan struct{}, concurrency),
+			pending:   make(map[string]bool),
+			rng:       rand.New(rand.NewSource(time.Now().UnixNano())),
+		}
+		log.Printf("[INFO] Background token refresh enabled (%v before expiry, %d at a time)", margin, concurrency)
+		go tr.run()
+	})
+}
+
+// run checks the pool on every tick, starting with one right away
+func (tr *TokenRefresher) run() {
+	ticker := time.NewTicker(tokenRefreshCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		tr.scheduleDue()
+		<-ticker.C
+	}
+}
+
+// scheduleDue schedules a refresh for every credential whose token expires within the margin.
+// Banned credentials are skipped; they are not selected for requests. Credentials whose last
+// refreshes failed are retried after an exponential backoff.
+func (tr *TokenRefresher) scheduleDue() {
+	now := time.Now()
+	deadline := now.Add(tr.margin)
+	bl := banlist.GetBanList()
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	for _, credEntry := range auth.GetCredentials() {
+		token := credEntry.Token()
+		if token.RefreshToken == "" || tr.pending[credEntry.FilePath] || bl.IsBanned(credEntry.ProjectID) {
+			continue
+		}
+		if token.AccessToken != "" && !token.Expiry.Before(deadline) {
+			continue
+		}
+		if retryAt, failing := refreshRetryTime(auth.GetRefreshHealth(credEntry.ProjectID)); failing && now.Before(retryAt) {
+			continue
+		}
+
+		// Jitter within a quarter of the margin, but never past half the remaining lifetime
+		window := tr.margin / 4
+		if remaining := token.Expiry.Sub(now) / 2; remaining < window {
+			window = remaining
+		}
+		var delay time.Duration
+		if window > 0 {
+			delay = time.Duration(tr.rng.Int63n(int64(window)))
+		}
+
+		tr.pending[credEntry.FilePath] = true
+		go tr.refresh(credEntry, delay)
+	}
+}
+
+// refresh waits for the jitter delay and a free slot, then refreshes and persists the token
+func (tr *TokenRefresher) refresh(credEntry *auth.CredentialEntry, delay time.Duration) {
+	defer func() {
+		tr.mu.Lock()
+		delete(tr.pending, credEntry.FilePath)
+		tr.mu.Unlock()
+	}()
+
+	time.Sleep(delay)
+	tr.semaphore <- struct{}{}
+	defer func() { <-tr.semaphore }()
+
+	refreshed, err := globalTokenRefreshManager.refreshBefore(credEntry, time.Now().Add(tr.margin))
+	if err != nil {
+		log.Printf("[WARN] Background token refresh failed for credential %s: %v", credEntry.ProjectID, err)
+		auth.BanIfTerminal(credEntry.ProjectID, err)
+		return
+	}
+	if !refreshed {
+		return
+	}
+
+	if err := auth.SaveRefreshedToken(credEntry); err != nil {
+		log.Printf("[WARN] Failed to save refreshed token for credential %s: %v", credEntry.ProjectID, err)
+		return
+	}
+	if config.IsDebugEnabled() {
+		log.Printf("[DEBUG] Background token refresh succeeded for credential %s (new expiry: %s)",
+			credEntry.ProjectID, credEntry.Token().Expiry.Format(time.RFC3339))
+	}
+}
+
+// refreshRetryTime returns when a credential whose refreshes are failing may be retried: the
+// check interval after the first failure, doubling with each further failure up to the cap
+func refreshRetryTime(health auth.RefreshHealth) (time.Time, bool) {
+	if health.ConsecutiveFailures == 0 || health.LastAttempt == nil {
+		return time.Time{}, false
+	}
+	backoff := tokenRefreshMaxBackoff
+	if shift := health.ConsecutiveFailures - 1; shift < 16 {
+		backoff = min(tokenRefreshCheckInterval<<shift, tokenRefreshMaxBackoff)
+	}
+	return health.LastAttempt.Add(backoff), true
+}
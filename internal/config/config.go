@@ -1,350 +1,422 @@
-package config
-
-import (
-	"log"
-	"os"
-	"path/filepath"
-	"runtime"
-	"sort"
-	"strconv"
-)
-
-// API Endpoints - configurable via environment variables
-// These are now read dynamically to support runtime configuration changes
-var (
-	// CodeAssistEndpoint is deprecated, use GetCodeAssistEndpoint() instead
-	CodeAssistEndpoint = "https://cloudcode-pa.googleapis.com"
-
-	// CloudResourceManagerEndpoint is deprecated, use GetCloudResourceManagerEndpoint() instead
-	CloudResourceManagerEndpoint = "https://cloudresourcemanager.googleapis.com"
-
-	// ServiceUsageEndpoint is deprecated, use GetServiceUsageEndpoint() instead
-	ServiceUsageEndpoint = "https://serviceusage.googleapis.com"
-
-	// OAuth2Endpoint is deprecated, use GetOAuth2Endpoint() instead
-	OAuth2Endpoint = "https://oauth2.googleapis.com"
-
-	// GoogleAPIsEndpoint is deprecated, use GetGoogleAPIsEndpoint() instead
-	GoogleAPIsEndpoint = "https://www.googleapis.com"
-)
-
-// GetCodeAssistEndpoint returns the current Gemini Cloud Assist API endpoint
-func GetCodeAssistEndpoint() string {
-	return getEnvOrDefault("GEMINI_API_ENDPOINT", "https://cloudcode-pa.googleapis.com")
-}
-
-// GetCloudResourceManagerEndpoint returns the current GCP Resource Manager API endpoint
-func GetCloudResourceManagerEndpoint() string {
-	return getEnvOrDefault("GCP_RESOURCE_MANAGER_ENDPOINT", "https://cloudresourcemanager.googleapis.com")
-}
-
-// GetServiceUsageEndpoint returns the current GCP Service Usage API endpoint
-func GetServiceUsageEndpoint() string {
-	return getEnvOrDefault("GCP_SERVICE_USAGE_ENDPOINT", "https://serviceusage.googleapis.com")
-}
-
-// GetOAuth2Endpoint returns the current OAuth2 token endpoint
-func GetOAuth2Endpoint() string {
-	return getEnvOrDefault("OAUTH2_ENDPOINT", "https://oauth2.googleapis.com")
-}
-
-// GetGoogleAPIsEndpoint returns the current Google APIs base endpoint for proxy
-func GetGoogleAPIsEndpoint() string {
-	endpoint := getEnvOrDefault("GOOGLE_APIS_ENDPOINT", "https://www.googleapis.com")
-	log.Printf("[DEBUG] GetGoogleAPIsEndpoint() called, returning: %s (from env: %s)", endpoint, os.Getenv("GOOGLE_APIS_ENDPOINT"))
-	return endpoint
-}
-
-// Client Configuration
-const CLIVersion = "0.1.5" // Match current gemini-cli version
-
-// OAuth Configuration
-const (
-	ClientID     = "681255809395-oo8ft2oprdrnp9e3aqf6av3hmdib135j.apps.googleusercontent.com"
-	ClientSecret = "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"
-)
-
-var Scopes = []string{
-	"https://www.googleapis.com/auth/cloud-platform",
-	"https://www.googleapis.com/auth/userinfo.email",
-	"https://www.googleapis.com/auth/userinfo.profile",
-}
-
-// File Paths
-var (
-	ScriptDir        string
-	CredentialFile   string
-	OAuthCredsFolder string
-)
-
-func init() {
-	// Get the directory of the executable
-	ex, err := os.Executable()
-	if err != nil {
-		ScriptDir = "."
-	} else {
-		ScriptDir = filepath.Dir(ex)
-	}
-
-	// Set credential file path
-	googleAppCreds := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
-	if googleAppCreds == "" {
-		googleAppCreds = "oauth_creds.json"
-	}
-	CredentialFile = filepath.Join(ScriptDir, googleAppCreds)
-
-	// Set credentials folder path
-	OAuthCredsFolder = os.Getenv("OAUTH_CREDS_FOLDER")
-	if OAuthCredsFolder == "" {
-		OAuthCredsFolder = filepath.Join(ScriptDir, "oauth_creds")
-	}
-	// Support both absolute and relative paths
-	// If the path is not absolute, make it relative to ScriptDir
-	if !filepath.IsAbs(OAuthCredsFolder) {
-		OAuthCredsFolder = filepath.Join(ScriptDir, OAuthCredsFolder)
-	}
-}
-
-// Authentication
-var (
-	GeminiAuthPassword = getEnvOrDefault("GEMINI_AUTH_PASSWORD", "") // Dashboard only
-	GeminiAPIKey       = getEnvOrDefault("GEMINI_API_KEY", "")       // API requests only
-	Password           = os.Getenv("PASSWORD")                       // Both dashboard and API
-)
-
-// Debug Logging
-var DebugLoggingEnabled = os.Getenv("DEBUG_LOGGING") == "true"
-
-// Default Language
-var DefaultLanguage = getEnvOrDefault("DEFAULT_LANGUAGE", "zh")
-
-// ReloadConfig reloads configuration from environment variables
-// Call this after loading .env file to pick up new values
-func ReloadConfig() {
-	GeminiAuthPassword = os.Getenv("GEMINI_AUTH_PASSWORD")
-	GeminiAPIKey = os.Getenv("GEMINI_API_KEY")
-	Password = os.Getenv("PASSWORD")
-	DebugLoggingEnabled = os.Getenv("DEBUG_LOGGING") == "true"
-	DefaultLanguage = getEnvOrDefault("DEFAULT_LANGUAGE", "zh")
-
-	// Apply default PASSWORD if all auth variables are empty
-	if Password == "" && GeminiAuthPassword == "" && GeminiAPIKey == "" {
-		Password = "123456"
-		log.Printf("[WARN] No authentication credentials found in environment variables")
-		log.Printf("[INFO] Setting default PASSWORD=123456 for first-time setup")
-	}
-
-	// Validate: if PASSWORD is empty, GEMINI_AUTH_PASSWORD and GEMINI_API_KEY must have values
-	if Password == "" {
-		if GeminiAuthPassword == "" {
-			log.Printf("[ERROR] PASSWORD is empty, but GEMINI_AUTH_PASSWORD is also empty")
-			log.Printf("[ERROR] Please set either PASSWORD or both GEMINI_AUTH_PASSWORD and GEMINI_API_KEY")
-			os.Exit(1)
-		}
-		if GeminiAPIKey == "" {
-			log.Printf("[ERROR] PASSWORD is empty, but GEMINI_API_KEY is also empty")
-			log.Printf("[ERROR] Please set either PASSWORD or both GEMINI_AUTH_PASSWORD and GEMINI_API_KEY")
-			os.Exit(1)
-		}
-	}
-
-	log.Printf("[INFO] Configuration reloaded: AuthPassword set=%v, APIKey set=%v, Password set=%v, Debug=%v, Language=%s",
-		GeminiAuthPassword != "", GeminiAPIKey != "", Password != "", DebugLoggingEnabled, DefaultLanguage)
-}
-
-func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
-
-func getEnvOrDefaultInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
-		}
-	}
-	return defaultValue
-}
-
-// GetMaxRetryAttempts returns the current max retry attempts setting
-// This reads from environment variable each time to allow dynamic updates
-func GetMaxRetryAttempts() int {
-	return getEnvOrDefaultInt("MAX_RETRY_ATTEMPTS", 5)
-}
-
-// GetCredentialRateLimitRPS returns the max RPS per credential
-// Lower values = more conservative, higher values = more aggressive
-// Default: 8 RPS per credential (conservative for shared IP scenarios)
-func GetCredentialRateLimitRPS() int {
-	return getEnvOrDefaultInt("CREDENTIAL_RATE_LIMIT_RPS", 8)
-}
-
-// IsRateLimitingEnabled returns whether credential rate limiting is enabled
-func IsRateLimitingEnabled() bool {
-	return os.Getenv("DISABLE_RATE_LIMITING") != "true"
-}
-
-// IsDebugEnabled returns true if debug logging is enabled
-func IsDebugEnabled() bool {
-	return DebugLoggingEnabled
-}
-
-// GetDefaultLanguage returns the default language setting
-func GetDefaultLanguage() string {
-	return DefaultLanguage
-}
-
-// GetFakeModelName returns the fake streaming model name based on language setting
-// For English (en): returns "modelID-fake" (e.g., "gemini-2.5-pro-fake")
-// For Chinese (zh): returns "假流式/modelID" (e.g., "假流式/gemini-2.5-pro")
-func GetFakeModelName(modelID string) string {
-	lang := GetDefaultLanguage()
-	if lang == "en" {
-		return modelID + "-fake"
-	}
-	// Default to Chinese format
-	return "假流式/" + modelID
-}
-
-// SafetySetting represents a safety setting for the Gemini API
-type SafetySetting struct {
-	Category  string `json:"category"`
-	Threshold string `json:"threshold"`
-}
-
-// DefaultSafetySettings for Google API
-var DefaultSafetySettings = []SafetySetting{
-	{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_NONE"},
-	{Category: "HARM_CATEGORY_HATE_SPEECH", Threshold: "BLOCK_NONE"},
-	{Category: "HARM_CATEGORY_SEXUALLY_EXPLICIT", Threshold: "BLOCK_NONE"},
-	{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Threshold: "BLOCK_NONE"},
-	{Category: "HARM_CATEGORY_CIVIC_INTEGRITY", Threshold: "BLOCK_NONE"},
-	{Category: "HARM_CATEGORY_IMAGE_DANGEROUS_CONTENT", Threshold: "BLOCK_NONE"},
-	{Category: "HARM_CATEGORY_IMAGE_HARASSMENT", Threshold: "BLOCK_NONE"},
-	{Category: "HARM_CATEGORY_IMAGE_HATE", Threshold: "BLOCK_NONE"},
-	{Category: "HARM_CATEGORY_IMAGE_SEXUALLY_EXPLICIT", Threshold: "BLOCK_NONE"},
-	{Category: "HARM_CATEGORY_UNSPECIFIED", Threshold: "BLOCK_NONE"},
-}
-
-// Model represents a Gemini model
-type Model struct {
-	Name                       string   `json:"name"`
-	Version                    string   `json:"version"`
-	DisplayName                string   `json:"displayName"`
-	Description                string   `json:"description"`
-	InputTokenLimit            int      `json:"inputTokenLimit"`
-	OutputTokenLimit           int      `json:"outputTokenLimit"`
-	SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
-	Temperature                float64  `json:"temperature"`
-	MaxTemperature             float64  `json:"maxTemperature"`
-	TopP                       float64  `json:"topP"`
-	TopK                       int      `json:"topK"`
-}
-
-// BaseModels (without search variants) - Updated with latest models as of October 2025
-var BaseModels = []Model{
-	{
-		Name:                       "models/gemini-2.5-pro-preview-06-05",
-		Version:                    "002",
-		DisplayName:                "Gemini 2.5 Pro Preview 0605",
-		Description:                "Gemini 2.5 Pro Preview 0605",
-		InputTokenLimit:            1048576,
-		OutputTokenLimit:           65535,
-		SupportedGenerationMethods: []string{"generateContent", "streamGenerateContent"},
-		Temperature:                1.0,
-		MaxTemperature:             2.0,
-		TopP:                       0.95,
-		TopK:                       64,
-	},
-	{
-		Name:                       "models/gemini-2.5-pro",
-		Version:                    "002",
-		DisplayName:                "Gemini 2.5 Pro",
-		Description:                "Gemini 2.5 Pro",
-		InputTokenLimit:            1048576,
-		OutputTokenLimit:           65535,
-		SupportedGenerationMethods: []string{"generateContent", "streamGenerateContent"},
-		Temperature:                1.0,
-		MaxTemperature:             2.0,
-		TopP:                       0.95,
-		TopK:                       64,
-	},
-	{
-		Name:                       "models/gemini-2.5-flash",
-		Version:                    "002",
-		DisplayName:                "Gemini 2.5 Flash",
-		Description:                "Gemini 2.5 Flash",
-		InputTokenLimit:            1048576,
-		OutputTokenLimit:           65535,
-		SupportedGenerationMethods: []string{"generateContent", "streamGenerateContent"},
-		Temperature:                1.0,
-		MaxTemperature:             2.0,
-		TopP:                       0.95,
-		TopK:                       64,
-	},
-}
-
-// SupportedModels includes only base models
-var SupportedModels []Model
-
-func init() {
-	// Use only base models
-	allModels := make([]Model, 0)
-	allModels = append(allModels, BaseModels...)
-
-	// Sort by name
-	sort.Slice(allModels, func(i, j int) bool {
-		return allModels[i].Name < allModels[j].Name
-	})
-
-	SupportedModels = allModels
-}
-
-// GetThinkingBudget gets the default thinking budget for a model
-// Returns 1024 (minimum) to reduce thinking token usage and improve response speed
-func GetThinkingBudget(modelName string) int {
-	return -1
-}
-
-// GetUserAgent generates User-Agent string matching gemini-cli format
-func GetUserAgent() string {
-	system := runtime.GOOS
-	arch := runtime.GOARCH
-	return "GeminiCLI/" + CLIVersion + " (" + system + "; " + arch + ")"
-}
-
-// GetPlatformString generates platform string matching gemini-cli format
-func GetPlatformString() string {
-	system := runtime.GOOS
-	arch := runtime.GOARCH
-
-	switch system {
-	case "darwin":
-		if arch == "arm64" {
-			return "DARWIN_ARM64"
-		}
-		return "DARWIN_AMD64"
-	case "linux":
-		if arch == "arm64" {
-			return "LINUX_ARM64"
-		}
-		return "LINUX_AMD64"
-	case "windows":
-		return "WINDOWS_AMD64"
-	default:
-		return "PLATFORM_UNSPECIFIED"
-	}
-}
-
-// GetClientMetadata returns client metadata for API requests
-func GetClientMetadata(projectID string) map[string]any {
-	return map[string]any{
-		"ideType":     "IDE_UNSPECIFIED",
-		"platform":    GetPlatformString(),
-		"pluginType":  "GEMINI",
-		"duetProject": projectID,
-	}
-}
-
+package config
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// API Endpoints - configurable via environment variables
+// These are now read dynamically to support runtime configuration changes
+var (
+	// CodeAssistEndpoint is deprecated, use GetCodeAssistEndpoint() instead
+	CodeAssistEndpoint = "https://cloudcode-pa.googleapis.com"
+
+	// CloudResourceManagerEndpoint is deprecated, use GetCloudResourceManagerEndpoint() instead
+	CloudResourceManagerEndpoint = "https://cloudresourcemanager.googleapis.com"
+
+	// ServiceUsageEndpoint is deprecated, use GetServiceUsageEndpoint() instead
+	ServiceUsageEndpoint = "https://serviceusage.googleapis.com"
+
+	// OAuth2Endpoint is deprecated, use GetOAuth2Endpoint() instead
+	OAuth2Endpoint = "https://oauth2.googleapis.com"
+
+	// GoogleAPIsEndpoint is deprecated, use GetGoogleAPIsEndpoint() instead
+	GoogleAPIsEndpoint = "https://www.googleapis.com"
+)
+
+// GetCodeAssistEndpoint returns the current Gemini Cloud Assist API endpoint
+func GetCodeAssistEndpoint() string {
+	return getEnvOrDefault("GEMINI_API_ENDPOINT", "https://cloudcode-pa.googleapis.com")
+}
+
+// GetCloudResourceManagerEndpoint returns the current GCP Resource Manager API endpoint
+func GetCloudResourceManagerEndpoint() string {
+	return getEnvOrDefault("GCP_RESOURCE_MANAGER_ENDPOINT", "https://cloudresourcemanager.googleapis.com")
+}
+
+// GetServiceUsageEndpoint returns the current GCP Service Usage API endpoint
+func GetServiceUsageEndpoint() string {
+	return getEnvOrDefault("GCP_SERVICE_USAGE_ENDPOINT", "https://serviceusage.googleapis.com")
+}
+
+// GetOAuth2Endpoint returns the current OAuth2 token endpoint
+func GetOAuth2Endpoint() string {
+	return getEnvOrDefault("OAUTH2_ENDPOINT", "https://oauth2.googleapis.com")
+}
+
+// GetGoogleAPIsEndpoint returns the current Google APIs base endpoint for proxy
+func GetGoogleAPIsEndpoint() string {
+	endpoint := getEnvOrDefault("GOOGLE_APIS_ENDPOINT", "https://www.googleapis.com")
+	log.Printf("[DEBUG] GetGoogleAPIsEndpoint() called, returning: %s (from env: %s)", endpoint, os.Getenv("GOOGLE_APIS_ENDPOINT"))
+	return endpoint
+}
+
+// Client Configuration
+const CLIVersion = "0.1.5" // Match current gemini-cli version
+
+// OAuth Configuration
+const (
+	ClientID     = "681255809395-oo8ft2oprdrnp9e3aqf6av3hmdib135j.apps.googleusercontent.com"
+	ClientSecret = "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"
+)
+
+var Scopes = []string{
+	"https://www.googleapis.com/auth/cloud-platform",
+	"https://www.googleapis.com/auth/userinfo.email",
+	"https://www.googleapis.com/auth/userinfo.profile",
+}
+
+// File Paths
+var (
+	ScriptDir        string
+	CredentialFile   string
+	OAuthCredsFolder string
+)
+
+func init() {
+	// Get the directory of the executable
+	ex, err := os.Executable()
+	if err != nil {
+		ScriptDir = "."
+	} else {
+		ScriptDir = filepath.Dir(ex)
+	}
+
+	// Set credential file path
+	googleAppCreds := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if googleAppCreds == "" {
+		googleAppCreds = "oauth_creds.json"
+	}
+	CredentialFile = filepath.Join(ScriptDir, googleAppCreds)
+
+	// Set credentials folder path
+	OAuthCredsFolder = os.Getenv("OAUTH_CREDS_FOLDER")
+	if OAuthCredsFolder == "" {
+		OAuthCredsFolder = filepath.Join(ScriptDir, "oauth_creds")
+	}
+	// Support both absolute and relative paths
+	// If the path is not absolute, make it relative to ScriptDir
+	if !filepath.IsAbs(OAuthCredsFolder) {
+		OAuthCredsFolder = filepath.Join(ScriptDir, OAuthCredsFolder)
+	}
+}
+
+// Authentication
+var (
+	GeminiAuthPassword = getEnvOrDefault("GEMINI_AUTH_PASSWORD", "") // Dashboard only
+	GeminiAPIKey       = getEnvOrDefault("GEMINI_API_KEY", "")       // API requests only
+	Password           = os.Getenv("PASSWORD")                       // Both dashboard and API
+)
+
+// Debug Logging
+var DebugLoggingEnabled = os.Getenv("DEBUG_LOGGING") == "true"
+
+// Default Language
+var DefaultLanguage = getEnvOrDefault("DEFAULT_LANGUAGE", "zh")
+
+// ReloadConfig reloads configuration from environment variables
+// Call this after loading .env file to pick up new values
+func ReloadConfig() {
+	GeminiAuthPassword = os.Getenv("GEMINI_AUTH_PASSWORD")
+	GeminiAPIKey = os.Getenv("GEMINI_API_KEY")
+	Password = os.Getenv("PASSWORD")
+	DebugLoggingEnabled = os.Getenv("DEBUG_LOGGING") == "true"
+	DefaultLanguage = getEnvOrDefault("DEFAULT_LANGUAGE", "zh")
+
+	// Apply default PASSWORD if all auth variables are empty
+	if Password == "" && GeminiAuthPassword == "" && GeminiAPIKey == "" {
+		Password = "123456"
+		log.Printf("[WARN] No authentication credentials found in environment variables")
+		log.Printf("[INFO] Setting default PASSWORD=123456 for first-time setup")
+	}
+
+	// Validate: if PASSWORD is empty, GEMINI_AUTH_PASSWORD and GEMINI_API_KEY must have values
+	if Password == "" {
+		if GeminiAuthPassword == "" {
+			log.Printf("[ERROR] PASSWORD is empty, but GEMINI_AUTH_PASSWORD is also empty")
+			log.Printf("[ERROR] Please set either PASSWORD or both GEMINI_AUTH_PASSWORD and GEMINI_API_KEY")
+			os.Exit(1)
+		}
+		if GeminiAPIKey == "" {
+			log.Printf("[ERROR] PASSWORD is empty, but GEMINI_API_KEY is also empty")
+			log.Printf("[ERROR] Please set either PASSWORD or both GEMINI_AUTH_PASSWORD and GEMINI_API_KEY")
+			os.Exit(1)
+		}
+	}
+
+	log.Printf("[INFO] Configuration reloaded: AuthPassword set=%v, APIKey set=%v, Password set=%v, Debug=%v, Language=%s",
+		GeminiAuthPassword != "", GeminiAPIKey != "", Password != "", DebugLoggingEnabled, DefaultLanguage)
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvOrDefaultInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.Atoi(value); err == nil {
+			return intValue
+		}
+	}
+	return defaultValue
+}
+
+// GetMaxRetryAttempts returns the current max retry attempts setting
+// This reads from environment variable each time to allow dynamic updates
+func GetMaxRetryAttempts() int {
+	return getEnvOrDefaultInt("MAX_RETRY_ATTEMPTS", 5)
+}
+
+// GetRetryBaseDelay returns the initial backoff before retrying a 5xx response or transport error
+func GetRetryBaseDelay() time.Duration {
+	return time.Duration(getEnvOrDefaultInt("RETRY_BASE_DELAY_MS", 500)) * time.Millisecond
+}
+
+// GetRetryMaxDelay returns the upper bound of the exponential retry backoff
+func GetRetryMaxDelay() time.Duration {
+	return time.Duration(getEnvOrDefaultInt("RETRY_MAX_DELAY_MS", 8000)) * time.Millisecond
+}
+
+// GetRetryBudget returns the total time a request may spend on retries
+// No retry is started (or waited for) that would end after the budget
+func GetRetryBudget() time.Duration {
+	return time.Duration(getEnvOrDefaultInt("RETRY_BUDGET_SECONDS", 60)) * time.Second
+}
+
+// IsStreamFailoverEnabled returns whether interrupted streams are resumed on another credential
+func IsStreamFailoverEnabled() bool {
+	return os.Getenv("STREAM_FAILOVER") == "true"
+}
+
+// GetStreamFailoverMaxAttempts returns how many times an interrupted stream may be resumed
+func GetStreamFailoverMaxAttempts() int {
+	return getEnvOrDefaultInt("STREAM_FAILOVER_MAX_ATTEMPTS", 2)
+}
+
+// GetCredentialRateLimitRPS returns the max RPS per credential
+// Lower values = more conservative, higher values = more aggressive
+// Default: 8 RPS per credential (conservative for shared IP scenarios)
+func GetCredentialRateLimitRPS() int {
+	return getEnvOrDefaultInt("CREDENTIAL_RATE_LIMIT_RPS", 8)
+}
+
+// GetCredentialSelectionStrategy returns how credentials are picked for each request:
+// round-robin, random, least-used or quota-aware (default)
+// This reads from environment variable each time to allow dynamic updates
+func GetCredentialSelectionStrategy() string {
+	return getEnvOrDefault("CREDENTIAL_SELECTION_STRATEGY", "quota-aware")
+}
+
+// GetCircuitBreakerThreshold returns how many consecutive failures of a class
+// (rate_limit, auth, server or refresh) open a credential's circuit; 0 disables the class
+// This reads from environment variable each time to allow dynamic updates
+func GetCircuitBreakerThreshold(class string) int {
+	defaults := map[string]int{
+		"rate_limit": 3,
+		"auth":       2,
+		"server":     5,
+		"refresh":    2,
+	}
+	return getEnvOrDefaultInt("CIRCUIT_BREAKER_THRESHOLD_"+strings.ToUpper(class), defaults[class])
+}
+
+// GetCircuitBreakerCooldown returns how long a circuit stays open after its first trip
+// Each further trip without a successful probe doubles the cooldown
+func GetCircuitBreakerCooldown() time.Duration {
+	return time.Duration(getEnvOrDefaultInt("CIRCUIT_BREAKER_COOLDOWN_SECONDS", 30)) * time.Second
+}
+
+// GetCircuitBreakerMaxCooldown returns the upper bound of the exponential cooldown
+func GetCircuitBreakerMaxCooldown() time.Duration {
+	return time.Duration(getEnvOrDefaultInt("CIRCUIT_BREAKER_MAX_COOLDOWN_SECONDS", 1800)) * time.Second
+}
+
+// GetResponsesStoreMaxEntries returns how many stored /v1/responses results are kept for previous_response_id
+// The oldest entries are evicted first once the limit is reached
+func GetResponsesStoreMaxEntries() int {
+	return getEnvOrDefaultInt("RESPONSES_STORE_MAX_ENTRIES", 1000)
+}
+
+// GetResponsesStoreTTL returns how long a stored /v1/responses result can be continued
+func GetResponsesStoreTTL() time.Duration {
+	return time.Duration(getEnvOrDefaultInt("RESPONSES_STORE_TTL_MINUTES", 60)) * time.Minute
+}
+
+// GetCredentialsWatchInterval returns how often the credentials folder is checked for changed files
+// Zero disables the watcher; the pool is then only reloaded by dashboard actions
+func GetCredentialsWatchInterval() time.Duration {
+	return time.Duration(getEnvOrDefaultInt("CREDENTIALS_WATCH_INTERVAL_SECONDS", 10)) * time.Second
+}
+
+// GetTokenRefreshMargin returns how long before expiry access tokens are refreshed in the background
+// Zero disables background refresh; tokens are then only refreshed by requests once expired
+func GetTokenRefreshMargin() time.Duration {
+	return time.Duration(getEnvOrDefaultInt("TOKEN_REFRESH_MARGIN_SECONDS", 600)) * time.Second
+}
+
+// GetTokenRefreshConcurrency returns how many background token refreshes may run at once
+func GetTokenRefreshConcurrency() int {
+	concurrency := getEnvOrDefaultInt("TOKEN_REFRESH_CONCURRENCY", 4)
+	if concurrency < 1 {
+		return 1
+	}
+	return concurrency
+}
+
+// GetCredentialsEncryptionKey returns the base64-encoded AES-256 key for credential files
+// Credential files are written in plaintext when neither the key nor a key file is set
+func GetCredentialsEncryptionKey() string {
+	return os.Getenv("CREDENTIALS_ENCRYPTION_KEY")
+}
+
+// GetCredentialsEncryptionKeyFile returns the path of a file holding the credential encryption key
+func GetCredentialsEncryptionKeyFile() string {
+	return os.Getenv("CREDENTIALS_ENCRYPTION_KEY_FILE")
+}
+
+// GetStorageBackend returns where credentials, bans and usage are stored: "file" or "sqlite"
+func GetStorageBackend() string {
+	return strings.ToLower(getEnvOrDefault("STORAGE_BACKEND", "file"))
+}
+
+// GetSQLitePath returns the database file used by the SQLite storage backend
+// Relative paths are resolved against the application directory, like OAUTH_CREDS_FOLDER
+func GetSQLitePath() string {
+	path := getEnvOrDefault("STORAGE_SQLITE_PATH", filepath.Join(OAuthCredsFolder, "gcli2apigo.db"))
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(ScriptDir, path)
+	}
+	return path
+}
+
+// IsRateLimitingEnabled returns whether credential rate limiting is enabled
+func IsRateLimitingEnabled() bool {
+	return os.Getenv("DISABLE_RATE_LIMITING") != "true"
+}
+
+// GetMetricsToken returns the bearer token required to scrape /metrics
+// The metrics endpoint is disabled when it is empty
+func GetMetricsToken() string {
+	return os.Getenv("METRICS_TOKEN")
+}
+
+// IsDebugEnabled returns true if debug logging is enabled
+func IsDebugEnabled() bool {
+	return DebugLoggingEnabled
+}
+
+// GetDefaultLanguage returns the default language setting
+func GetDefaultLanguage() string {
+	return DefaultLanguage
+}
+
+// GetFakeModelName returns the fake streaming model name based on language setting
+// For English (en): returns "modelID-fake" (e.g., "gemini-2.5-pro-fake")
+// For Chinese (zh): returns "假流式/modelID" (e.g., "假流式/gemini-2.5-pro")
+func GetFakeModelName(modelID string) string {
+	lang := GetDefaultLanguage()
+	if lang == "en" {
+		return modelID + "-fake"
+	}
+	// Default to Chinese format
+	return "假流式/" + modelID
+}
+
+// SafetySetting represents a safety setting for the Gemini API
+type SafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+// DefaultSafetySettings for Google API
+var DefaultSafetySettings = []SafetySetting{
+	{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_NONE"},
+	{Category: "HARM_CATEGORY_HATE_SPEECH", Threshold: "BLOCK_NONE"},
+	{Category: "HARM_CATEGORY_SEXUALLY_EXPLICIT", Threshold: "BLOCK_NONE"},
+	{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Threshold: "BLOCK_NONE"},
+	{Category: "HARM_CATEGORY_CIVIC_INTEGRITY", Threshold: "BLOCK_NONE"},
+	{Category: "HARM_CATEGORY_IMAGE_DANGEROUS_CONTENT", Threshold: "BLOCK_NONE"},
+	{Category: "HARM_CATEGORY_IMAGE_HARASSMENT", Threshold: "BLOCK_NONE"},
+	{Category: "HARM_CATEGORY_IMAGE_HATE", Threshold: "BLOCK_NONE"},
+	{Category: "HARM_CATEGORY_IMAGE_SEXUALLY_EXPLICIT", Threshold: "BLOCK_NONE"},
+	{Category: "HARM_CATEGORY_UNSPECIFIED", Threshold: "BLOCK_NONE"},
+}
+
+// Model represents a Gemini model
+type Model struct {
+	Name                       string   `json:"name"`
+	Version                    string   `json:"version"`
+	DisplayName                string   `json:"displayName"`
+	Description                string   `json:"description"`
+	InputTokenLimit            int      `json:"inputTokenLimit"`
+	OutputTokenLimit           int      `json:"outputTokenLimit"`
+	SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+	Temperature                float64  `json:"temperature"`
+	MaxTemperature             float64  `json:"maxTemperature"`
+	TopP                       float64  `json:"topP"`
+	TopK                       int      `json:"topK"`
+}
+
+// GetThinkingBudget gets the default thinking budget for a model from the model registry
+// Returns -1 (dynamic thinking) when the model sets no budget or is not in the registry
+func GetThinkingBudget(modelName string) int {
+	if entry, ok := GetModelRegistry().Lookup(modelName); ok && entry.ThinkingBudget != nil {
+		return *entry.ThinkingBudget
+	}
+	return -1
+}
+
+// GetDefaultEmbeddingModel returns the Gemini embedding model used when an
+// OpenAI embeddings request names a non-Gemini model (e.g. text-embedding-3-small)
+func GetDefaultEmbeddingModel() string {
+	return getEnvOrDefault("DEFAULT_EMBEDDING_MODEL", "gemini-embedding-001")
+}
+
+// GetUserAgent generates User-Agent string matching gemini-cli format
+func GetUserAgent() string {
+	system := runtime.GOOS
+	arch := runtime.GOARCH
+	return "GeminiCLI/" + CLIVersion + " (" + system + "; " + arch + ")"
+}
+
+// GetPlatformString generates platform string matching gemini-cli format
+func GetPlatformString() string {
+	system := runtime.GOOS
+	arch := runtime.GOARCH
+
+	switch system {
+	case "darwin":
+		if arch == "arm64" {
+			return "DARWIN_ARM64"
+		}
+		return "DARWIN_AMD64"
+	case "linux":
+		if arch == "arm64" {
+			return "LINUX_ARM64"
+		}
+		return "LINUX_AMD64"
+	case "windows":
+		return "WINDOWS_AMD64"
+	default:
+		return "PLATFORM_UNSPECIFIED"
+	}
+}
+
+// GetClientMetadata returns client metadata for API requests
+func GetClientMetadata(projectID string) map[string]any {
+	return map[string]any{
+		"ideType":     "IDE_UNSPECIFIED",
+		"platform":    GetPlatformString(),
+		"pluginType":  "GEMINI",
+		"duetProject": projectID,
+	}
+}
+
@@ -0,0 +1,475 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ModelRegistryFile is the name of the model registry file in OAuthCredsFolder
+const ModelRegistryFile = "models.json"
+
+// modelRegistryPollInterval is how often the registry file is checked for changes
+const modelRegistryPollInterval = 5 * time.Second
+
+// Model classes; pro models count against the separate pro quota in usage tracking
+const (
+	ModelClassPro   = "pro"
+	ModelClassFlash = "flash"
+)
+
+// Model sources reported by ModelRegistry.Source
+const (
+	ModelSourceDefault = "default"
+	ModelSourceFile    = "file"
+)
+
+// ModelEntry is a model in the registry. The JSON fields follow the Gemini models format,
+// plus the settings the proxy uses for the model.
+type ModelEntry struct {
+	ID                         string   `json:"id"` // Without the "models/" prefix
+	Version                    string   `json:"version,omitempty"`
+	DisplayName                string   `json:"displayName,omitempty"`
+	Description                string   `json:"description,omitempty"`
+	InputTokenLimit            int      `json:"inputTokenLimit"`
+	OutputTokenLimit           int      `json:"outputTokenLimit"`
+	SupportedGenerationMethods []string `json:"supportedGenerationMethods,omitempty"`
+	Temperature                float64  `json:"temperature,omitempty"`
+	MaxTemperature             float64  `json:"maxTemperature,omitempty"`
+	TopP                       float64  `json:"topP,omitempty"`
+	TopK                       int      `json:"topK,omitempty"`
+	ThinkingBudget             *int     `json:"thinkingBudget,omitempty"` // Default thinking budget; nil means dynamic (-1)
+	Class                      string   `json:"class"`                    // "pro" or "flash"
+	FakeStreaming              bool     `json:"fakeStreaming"`            // Whether the fake streaming variant is offered
+}
+
+// GeminiModel returns the entry in the Gemini models format
+func (e ModelEntry) GeminiModel() Model {
+	return Model{
+		Name:                       "models/" + e.ID,
+		Version:                    e.Version,
+		DisplayName:                e.DisplayName,
+		Description:                e.Description,
+		InputTokenLimit:            e.InputTokenLimit,
+		OutputTokenLimit:           e.OutputTokenLimit,
+		SupportedGenerationMethods: e.SupportedGenerationMethods,
+		Temperature:                e.Temperature,
+		MaxTemperature:             e.MaxTemperature,
+		TopP:                       e.TopP,
+		TopK:                       e.TopK,
+	}
+}
+
+// ModelRegistryConfig is the content of the registry file
+type ModelRegistryConfig struct {
+	Models []ModelEntry `json:"models"`
+	// Aliases maps extra model names (e.g. "gpt-4o") to registry models
+	Aliases map[string]string `json:"aliases,omitempty"`
+	// Fallbacks lists, per model, the models tried in order when its quota is exhausted
+	Fallbacks map[string][]string `json:"fallbacks,omitempty"`
+}
+
+// ModelRegistry holds the models served by the proxy. It is loaded from ModelRegistryFile when
+// present, falls back to DefaultModelEntries otherwise, and picks up changes to the file.
+type ModelRegistry struct {
+	entries   []ModelEntry // Sorted by ID
+	byID      map[string]int
+	aliases   map[string]string
+	fallbacks map[string][]string
+	source    string
+	modTime   time.Time
+	mu        sync.RWMutex
+	storePath string
+}
+
+var (
+	globalModelRegistry *ModelRegistry
+	modelRegistryOnce   sync.Once
+)
+
+// GetModelRegistry returns the global model registry instance
+func GetModelRegistry() *ModelRegistry {
+	modelRegistryOnce.Do(func() {
+		globalModelRegistry = NewModelRegistry(filepath.Join(OAuthCredsFolder, ModelRegistryFile))
+		if err := globalModelRegistry.Load(); err != nil {
+			log.Printf("[ERROR] Failed to load model registry, using built-in models: %v", err)
+		}
+		go globalModelRegistry.watchLoop(modelRegistryPollInterval)
+	})
+	return globalModelRegistry
+}
+
+// NewModelRegistry creates a registry backed by the file at storePath, holding the built-in models
+func NewModelRegistry(storePath string) *ModelRegistry {
+	mr := &ModelRegistry{storePath: storePath}
+	cfg, _ := ValidateModelRegistry(ModelRegistryConfig{Models: DefaultModelEntries})
+	mr.apply(cfg, ModelSourceDefault, time.Time{})
+	return mr
+}
+
+// Path returns the path of the registry file
+func (mr *ModelRegistry) Path() string {
+	return mr.storePath
+}
+
+// Load reads the registry file. A missing file restores the built-in models; an invalid file
+// returns an error and keeps the current models.
+func (mr *ModelRegistry) Load() error {
+	info, err := os.Stat(mr.storePath)
+	if os.IsNotExist(err) {
+		cfg, _ := ValidateModelRegistry(ModelRegistryConfig{Models: DefaultModelEntries})
+		mr.apply(cfg, ModelSourceDefault, time.Time{})
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(mr.storePath)
+	if err != nil {
+		return err
+	}
+
+	var cfg ModelRegistryConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		mr.markSeen(info.ModTime())
+		return fmt.Errorf("invalid %s: %v", ModelRegistryFile, err)
+	}
+	cfg, err = ValidateModelRegistry(cfg)
+	if err != nil {
+		mr.markSeen(info.ModTime())
+		return fmt.Errorf("invalid %s: %v", ModelRegistryFile, err)
+	}
+
+	mr.apply(cfg, ModelSourceFile, info.ModTime())
+	log.Printf("[INFO] Loaded %d models and %d aliases from %s", len(cfg.Models), len(cfg.Aliases), mr.storePath)
+	return nil
+}
+
+// Save validates a registry configuration, writes it to the registry file and applies it
+func (mr *ModelRegistry) Save(cfg ModelRegistryConfig) error {
+	validated, err := ValidateModelRegistry(cfg)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(validated, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so the watcher never reads a partial file
+	if err := os.MkdirAll(filepath.Dir(mr.storePath), 0755); err != nil {
+		return err
+	}
+	tmpPath := mr.storePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, mr.storePath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	var modTime time.Time
+	if info, err := os.Stat(mr.storePath); err == nil {
+		modTime = info.ModTime()
+	}
+	mr.apply(validated, ModelSourceFile, modTime)
+	log.Printf("[INFO] Saved %d models and %d aliases to %s", len(validated.Models), len(validated.Aliases), mr.storePath)
+	return nil
+}
+
+// apply replaces the models, aliases and fallbacks held by the registry
+func (mr *ModelRegistry) apply(cfg ModelRegistryConfig, source string, modTime time.Time) {
+	byID := make(map[string]int, len(cfg.Models))
+	for i, entry := range cfg.Models {
+		byID[entry.ID] = i
+	}
+
+	mr.mu.Lock()
+	mr.entries = cfg.Models
+	mr.byID = byID
+	mr.aliases = cfg.Aliases
+	mr.fallbacks = cfg.Fallbacks
+	mr.source = source
+	mr.modTime = modTime
+	mr.mu.Unlock()
+}
+
+// markSeen records the modification time of a file that failed to load, so it is not reported again
+func (mr *ModelRegistry) markSeen(modTime time.Time) {
+	mr.mu.Lock()
+	mr.modTime = modTime
+	mr.mu.Unlock()
+}
+
+// watchLoop reloads the registry whenever the file is created, changed or removed
+func (mr *ModelRegistry) watchLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		mr.mu.RLock()
+		source, modTime := mr.source, mr.modTime
+		mr.mu.RUnlock()
+
+		info, err := os.Stat(mr.storePath)
+		switch {
+		case err == nil && info.ModTime().Equal(modTime):
+			continue
+		case os.IsNotExist(err) && source == ModelSourceDefault:
+			continue
+		}
+
+		log.Printf("[INFO] Model registry file changed, reloading %s", mr.storePath)
+		if err := mr.Load(); err != nil {
+			log.Printf("[ERROR] Failed to reload model registry, keeping current models: %v", err)
+		}
+	}
+}
+
+// Source returns ModelSourceFile when the models were loaded from the registry file,
+// or ModelSourceDefault for the built-in models
+func (mr *ModelRegistry) Source() string {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+	return mr.source
+}
+
+// Entries returns copies of all registry entries ordered by ID
+func (mr *ModelRegistry) Entries() []ModelEntry {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	entries := make([]ModelEntry, len(mr.entries))
+	copy(entries, mr.entries)
+	return entries
+}
+
+// Config returns a copy of the registry configuration: models, aliases and fallbacks
+func (mr *ModelRegistry) Config() ModelRegistryConfig {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	cfg := ModelRegistryConfig{
+		Models:    make([]ModelEntry, len(mr.entries)),
+		Aliases:   make(map[string]string, len(mr.aliases)),
+		Fallbacks: make(map[string][]string, len(mr.fallbacks)),
+	}
+	copy(cfg.Models, mr.entries)
+	for alias, target := range mr.aliases {
+		cfg.Aliases[alias] = target
+	}
+	for model, fallbacks := range mr.fallbacks {
+		cfg.Fallbacks[model] = append([]string(nil), fallbacks...)
+	}
+	return cfg
+}
+
+// ResolveAlias returns the registry model an alias points to, or the name unchanged
+// (without a "models/" prefix) when it is not an alias
+func (mr *ModelRegistry) ResolveAlias(modelName string) (string, bool) {
+	modelName = strings.TrimPrefix(modelName, "models/")
+
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	if target, ok := mr.aliases[modelName]; ok {
+		return target, true
+	}
+	return modelName, false
+}
+
+// Fallbacks returns the models to try, in order, when the quota of a model is exhausted
+func (mr *ModelRegistry) Fallbacks(modelName string) []string {
+	modelName = strings.TrimPrefix(modelName, "models/")
+
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+	return append([]string(nil), mr.fallbacks[modelName]...)
+}
+
+// Models returns all models in the Gemini models format, ordered by name
+func (mr *ModelRegistry) Models() []Model {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	models := make([]Model, 0, len(mr.entries))
+	for _, entry := range mr.entries {
+		models = append(models, entry.GeminiModel())
+	}
+	return models
+}
+
+// Lookup returns the entry for a model name, with or without the "models/" prefix
+func (mr *ModelRegistry) Lookup(modelName string) (ModelEntry, bool) {
+	modelName = strings.TrimPrefix(modelName, "models/")
+
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	index, exists := mr.byID[modelName]
+	if !exists {
+		return ModelEntry{}, false
+	}
+	return mr.entries[index], true
+}
+
+// ValidateModelRegistry checks a registry configuration and returns it normalised.
+// Aliases and fallbacks must refer to registry models, and aliases must not shadow a model.
+func ValidateModelRegistry(cfg ModelRegistryConfig) (ModelRegistryConfig, error) {
+	entries, err := ValidateModelEntries(cfg.Models)
+	if err != nil {
+		return ModelRegistryConfig{}, err
+	}
+	ids := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		ids[entry.ID] = true
+	}
+
+	aliases := make(map[string]string, len(cfg.Aliases))
+	for alias, target := range cfg.Aliases {
+		alias = strings.TrimSpace(alias)
+		target = strings.TrimPrefix(strings.TrimSpace(target), "models/")
+		if alias == "" || strings.ContainsAny(alias, ": ") {
+			return ModelRegistryConfig{}, fmt.Errorf("aliases: invalid alias %q", alias)
+		}
+		if ids[alias] {
+			return ModelRegistryConfig{}, fmt.Errorf("aliases.%s: a model with this ID exists", alias)
+		}
+		if !ids[target] {
+			return ModelRegistryConfig{}, fmt.Errorf("aliases.%s: unknown model %s", alias, target)
+		}
+		aliases[alias] = target
+	}
+
+	fallbacks := make(map[string][]string, len(cfg.Fallbacks))
+	for model, targets := range cfg.Fallbacks {
+		model = strings.TrimPrefix(strings.TrimSpace(model), "models/")
+		if !ids[model] {
+			return ModelRegistryConfig{}, fmt.Errorf("fallbacks.%s: unknown model", model)
+		}
+		normalised := make([]string, 0, len(targets))
+		for i, target := range targets {
+			target = strings.TrimPrefix(strings.TrimSpace(target), "models/")
+			if !ids[target] {
+				return ModelRegistryConfig{}, fmt.Errorf("fallbacks.%s[%d]: unknown model %s", model, i, target)
+			}
+			if target == model {
+				return ModelRegistryConfig{}, fmt.Errorf("fallbacks.%s[%d]: a model cannot fall back to itself", model, i)
+			}
+			normalised = append(normalised, target)
+		}
+		if len(normalised) > 0 {
+			fallbacks[model] = normalised
+		}
+	}
+
+	return ModelRegistryConfig{Models: entries, Aliases: aliases, Fallbacks: fallbacks}, nil
+}
+
+// ValidateModelEntries checks a list of registry entries and returns them normalised and
+// sorted by ID. Missing display names and generation methods are filled with defaults.
+func ValidateModelEntries(entries []ModelEntry) ([]ModelEntry, error) {
+	if len(entries) == 0 {
+		return nil, errors.New("models: at least one model is required")
+	}
+
+	validated := make([]ModelEntry, 0, len(entries))
+	seen := make(map[string]bool, len(entries))
+	for i, entry := range entries {
+		entry.ID = strings.TrimPrefix(strings.TrimSpace(entry.ID), "models/")
+		if entry.ID == "" {
+			return nil, fmt.Errorf("models[%d].id: must not be empty", i)
+		}
+		if strings.ContainsAny(entry.ID, ": ") {
+			return nil, fmt.Errorf("models[%d].id: must not contain ':' or spaces", i)
+		}
+		if seen[entry.ID] {
+			return nil, fmt.Errorf("models[%d].id: duplicate model %s", i, entry.ID)
+		}
+		seen[entry.ID] = true
+
+		entry.Class = strings.ToLower(strings.TrimSpace(entry.Class))
+		if entry.Class != ModelClassPro && entry.Class != ModelClassFlash {
+			return nil, fmt.Errorf("models[%d].class: must be %q or %q", i, ModelClassPro, ModelClassFlash)
+		}
+		if entry.InputTokenLimit < 0 || entry.OutputTokenLimit < 0 {
+			return nil, fmt.Errorf("models[%d]: token limits must not be negative", i)
+		}
+		if entry.ThinkingBudget != nil && *entry.ThinkingBudget < -1 {
+			return nil, fmt.Errorf("models[%d].thinkingBudget: must be -1 (dynamic) or at least 0", i)
+		}
+
+		if entry.DisplayName == "" {
+			entry.DisplayName = entry.ID
+		}
+		if entry.Description == "" {
+			entry.Description = entry.DisplayName
+		}
+		if len(entry.SupportedGenerationMethods) == 0 {
+			entry.SupportedGenerationMethods = []string{"generateContent", "streamGenerateContent"}
+		}
+		validated = append(validated, entry)
+	}
+
+	sort.Slice(validated, func(i, j int) bool {
+		return validated[i].ID < validated[j].ID
+	})
+	return validated, nil
+}
+
+// DefaultModelEntries are the models served when no registry file exists
+var DefaultModelEntries = []ModelEntry{
+	defaultProEntry("gemini-2.5-pro", "Gemini 2.5 Pro"),
+	defaultProEntry("gemini-2.5-pro-preview-03-25", "Gemini 2.5 Pro Preview 0325"),
+	defaultProEntry("gemini-2.5-pro-preview-05-06", "Gemini 2.5 Pro Preview 0506"),
+	defaultProEntry("gemini-2.5-pro-preview-06-05", "Gemini 2.5 Pro Preview 0605"),
+	defaultFlashEntry("gemini-2.5-flash", "Gemini 2.5 Flash"),
+	defaultFlashEntry("gemini-2.5-flash-preview-04-17", "Gemini 2.5 Flash Preview 0417"),
+	defaultFlashEntry("gemini-2.5-flash-preview-05-20", "Gemini 2.5 Flash Preview 0520"),
+	defaultFlashEntry("gemini-flash-latest", "Gemini Flash Latest"),
+	defaultImageEntry("gemini-2.5-flash-image", "Gemini 2.5 Flash Image"),
+	defaultImageEntry("gemini-2.5-flash-image-preview", "Gemini 2.5 Flash Image Preview"),
+}
+
+func defaultProEntry(id, displayName string) ModelEntry {
+	return ModelEntry{
+		ID:                         id,
+		Version:                    "002",
+		DisplayName:                displayName,
+		Description:                displayName,
+		InputTokenLimit:            1048576,
+		OutputTokenLimit:           65535,
+		SupportedGenerationMethods: []string{"generateContent", "streamGenerateContent", "countTokens"},
+		Temperature:                1.0,
+		MaxTemperature:             2.0,
+		TopP:                       0.95,
+		TopK:                       64,
+		Class:                      ModelClassPro,
+		FakeStreaming:              true,
+	}
+}
+
+func defaultFlashEntry(id, displayName string) ModelEntry {
+	entry := defaultProEntry(id, displayName)
+	entry.Class = ModelClassFlash
+	return entry
+}
+
+// Image models do not think and have a smaller context window; they are not offered for fake streaming
+func defaultImageEntry(id, displayName string) ModelEntry {
+	entry := defaultFlashEntry(id, displayName)
+	entry.InputTokenLimit = 32768
+	entry.OutputTokenLimit = 32768
+	entry.FakeStreaming = false
+	return entry
+}
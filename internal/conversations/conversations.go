@@ -0,0 +1,109 @@
+package conversations
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"gcli2apigo/internal/config"
+)
+
+// Conversation is a stored /v1/responses result that later requests can continue with previous_response_id
+type Conversation struct {
+	Response  map[string]interface{}   // Response object as returned to the client
+	Items     []map[string]interface{} // Input and output items of the whole conversation, oldest first
+	Owner     string                   // API key ID that created the response, "" for the shared password
+	CreatedAt time.Time
+}
+
+// Store keeps recent conversations in memory. Entries expire after RESPONSES_STORE_TTL_MINUTES and the
+// oldest are evicted once RESPONSES_STORE_MAX_ENTRIES is reached; nothing is written to disk.
+type Store struct {
+	entries map[string]*Conversation // Keyed by response ID
+	order   []string                 // Response IDs in insertion order; may hold IDs already deleted
+	mu      sync.Mutex
+}
+
+var (
+	globalStore *Store
+	storeOnce   sync.Once
+)
+
+// GetStore returns the global conversation store instance
+func GetStore() *Store {
+	storeOnce.Do(func() {
+		globalStore = NewStore()
+	})
+	return globalStore
+}
+
+// NewStore creates an empty conversation store
+func NewStore() *Store {
+	return &Store{
+		entries: make(map[string]*Conversation),
+	}
+}
+
+// Save stores a conversation under its response ID. The store takes ownership of the items;
+// callers must not modify them afterwards.
+func (s *Store) Save(id string, conversation *Conversation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if conversation.CreatedAt.IsZero() {
+		conversation.CreatedAt = time.Now()
+	}
+	if _, exists := s.entries[id]; !exists {
+		s.order = append(s.order, id)
+	}
+	s.entries[id] = conversation
+	s.prune(time.Now())
+}
+
+// Get returns the conversation stored under a response ID if it exists and has not expired.
+// The returned conversation is shared and must not be modified.
+func (s *Store) Get(id string) (*Conversation, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conversation, exists := s.entries[id]
+	if !exists {
+		return nil, false
+	}
+	if time.Since(conversation.CreatedAt) > config.GetResponsesStoreTTL() {
+		delete(s.entries, id)
+		return nil, false
+	}
+	return conversation, true
+}
+
+// Delete removes a stored conversation, reporting whether it existed
+func (s *Store) Delete(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.entries[id]; !exists {
+		return false
+	}
+	delete(s.entries, id)
+	return true
+}
+
+// prune drops expired conversations and evicts the oldest ones above the size limit.
+// Entries are stored in insertion order, so both checks only need to look at the front.
+// The caller must hold s.mu.
+func (s *Store) prune(now time.Time) {
+	ttl := config.GetResponsesStoreTTL()
+	maxEntries := max(config.GetResponsesStoreMaxEntries(), 1)
+
+	drop := 0
+	for _, id := range s.order {
+		conversation, exists := s.entries[id]
+		if exists && now.Sub(conversation.CreatedAt) <= ttl && len(s.entries) <= maxEntries {
+			break
+		}
+		delete(s.entries, id)
+		drop++
+	}
+	s.order = slices.Delete(s.order, 0, drop)
+}
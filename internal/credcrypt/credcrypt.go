@@ -0,0 +1,318 @@
+// Package credcrypt encrypts OAuth credential files at rest with AES-256-GCM.
+//
+// Encryption is enabled by setting CREDENTIALS_ENCRYPTION_KEY (a base64-encoded 32-byte key)
+// or CREDENTIALS_ENCRYPTION_KEY_FILE. Encrypted files keep their .json name and stay valid
+// JSON, so the credentials folder layout does not change. Plaintext files are still read
+// while a key is configured, which lets a folder be migrated gradually.
+package credcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gcli2apigo/internal/config"
+)
+
+// Algorithm identifies the encryption scheme in encrypted credential files
+const Algorithm = "aes-256-gcm"
+
+// KeySize is the length of an encryption key in bytes
+const KeySize = 32
+
+// ErrNoKey is returned when reading an encrypted file without a configured key
+var ErrNoKey = errors.New("credential file is encrypted but no encryption key is configured (set CREDENTIALS_ENCRYPTION_KEY or CREDENTIALS_ENCRYPTION_KEY_FILE)")
+
+// ErrKeyNotConfigured is returned when migrating without a configured key
+var ErrKeyNotConfigured = errors.New("no encryption key is configured (set CREDENTIALS_ENCRYPTION_KEY or CREDENTIALS_ENCRYPTION_KEY_FILE)")
+
+// envelope is the on-disk format of an encrypted credential file
+type envelope struct {
+	Encrypted  string `json:"encrypted"`  // Always Algorithm
+	KeyID      string `json:"key_id"`     // Identifies the key without revealing it, to report a wrong key
+	Nonce      string `json:"nonce"`      // Base64
+	Ciphertext string `json:"ciphertext"` // Base64, includes the GCM tag
+}
+
+// LoadKey returns the configured encryption key, or nil when encryption is disabled.
+// CREDENTIALS_ENCRYPTION_KEY takes precedence over CREDENTIALS_ENCRYPTION_KEY_FILE.
+// A key file may hold the base64-encoded key or the raw 32 bytes.
+func LoadKey() ([]byte, error) {
+	if encoded := strings.TrimSpace(config.GetCredentialsEncryptionKey()); encoded != "" {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != KeySize {
+			return nil, fmt.Errorf("CREDENTIALS_ENCRYPTION_KEY must be a base64-encoded %d-byte key", KeySize)
+		}
+		return key, nil
+	}
+
+	keyFile := config.GetCredentialsEncryptionKeyFile()
+	if keyFile == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encryption key file: %w", err)
+	}
+	if key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data))); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	if len(data) == KeySize {
+		return data, nil
+	}
+	return nil, fmt.Errorf("encryption key file %s must hold a base64-encoded or raw %d-byte key", keyFile, KeySize)
+}
+
+// GenerateKey returns a new random key, base64-encoded for CREDENTIALS_ENCRYPTION_KEY
+func GenerateKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// keyID returns a short fingerprint of a key
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// IsEncrypted reports whether data is an encrypted credential file
+func IsEncrypted(data []byte) bool {
+	var env envelope
+	return json.Unmarshal(data, &env) == nil && env.Encrypted == Algorithm
+}
+
+// Encrypt seals plaintext with the key and returns the encrypted file content
+func Encrypt(plaintext, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return json.MarshalIndent(envelope{
+		Encrypted:  Algorithm,
+		KeyID:      keyID(key),
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, plaintext, nil)),
+	}, "", "  ")
+}
+
+// Decrypt opens encrypted file content with the key
+func Decrypt(data, key []byte) ([]byte, error) {
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil || env.Encrypted != Algorithm {
+		return nil, errors.New("not an encrypted credential file")
+	}
+	if env.KeyID != keyID(key) {
+		return nil, fmt.Errorf("credential file was encrypted with a different key (key id %s, configured key id %s)", env.KeyID, keyID(key))
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(env.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(env.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, errors.New("invalid nonce length")
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("failed to decrypt credential file: data is corrupted or was tampered with")
+	}
+	return plaintext, nil
+}
+
+// Open returns the plaintext of credential file content, decrypting it if it is encrypted
+func Open(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	key, err := LoadKey()
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrNoKey
+	}
+	return Decrypt(data, key)
+}
+
+// ReadFile reads a credential file and returns its plaintext
+func ReadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Open(data)
+}
+
+// Seal returns the content to store for a credential: encrypted when a key is configured,
+// otherwise the plaintext itself
+func Seal(plaintext []byte) ([]byte, error) {
+	key, err := LoadKey()
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return plaintext, nil
+	}
+	return Encrypt(plaintext, key)
+}
+
+// WriteFile writes a credential file with 0600 permissions, encrypted when a key is configured.
+// The file is written to a temporary file and renamed so readers never see a partial file.
+func WriteFile(path string, plaintext []byte) error {
+	data, err := Seal(plaintext)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it into place
+func writeFileAtomic(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// MigrationResult counts the files handled by MigrateFolder
+type MigrationResult struct {
+	Converted int      // Files encrypted or decrypted
+	Unchanged int      // Credential files already in the requested form
+	Skipped   int      // JSON files that are not credentials (ban list, usage stats, ...)
+	Failed    []string // Files that could not be converted, with the reason
+}
+
+// MigrateFolder encrypts (or decrypts) every credential file in a folder with the configured key.
+// Credential files are recognized by their refresh_token field, so the ban list, usage stats
+// and other JSON files in the folder are left alone.
+func MigrateFolder(folderPath string, encrypt bool) (*MigrationResult, error) {
+	key, err := LoadKey()
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrKeyNotConfigured
+	}
+
+	files, err := os.ReadDir(folderPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials folder: %w", err)
+	}
+
+	result := &MigrationResult{}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(folderPath, file.Name())
+		changed, isCredential, err := migrateFile(path, key, encrypt)
+		switch {
+		case err != nil:
+			result.Failed = append(result.Failed, fmt.Sprintf("%s: %v", file.Name(), err))
+		case !isCredential:
+			result.Skipped++
+		case changed:
+			result.Converted++
+		default:
+			result.Unchanged++
+		}
+	}
+	return result, nil
+}
+
+// MigrateFile encrypts (or decrypts) a single credential file, e.g. the legacy oauth_creds.json.
+// It reports whether the file changed; files that are not credentials are left alone.
+func MigrateFile(path string, encrypt bool) (bool, error) {
+	key, err := LoadKey()
+	if err != nil {
+		return false, err
+	}
+	if key == nil {
+		return false, ErrKeyNotConfigured
+	}
+	changed, _, err := migrateFile(path, key, encrypt)
+	return changed, err
+}
+
+// migrateFile converts one file and reports whether it changed and whether it is a credential file
+func migrateFile(path string, key []byte, encrypt bool) (changed bool, isCredential bool, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, false, err
+	}
+
+	encrypted := IsEncrypted(data)
+	plaintext := data
+	if encrypted {
+		if plaintext, err = Decrypt(data, key); err != nil {
+			return false, true, err
+		}
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(plaintext, &fields); err != nil {
+		if encrypted {
+			return false, true, fmt.Errorf("decrypted content is not valid JSON: %w", err)
+		}
+		return false, false, nil
+	}
+	if _, ok := fields["refresh_token"]; !ok {
+		return false, false, nil
+	}
+
+	if encrypted == encrypt {
+		return false, true, nil
+	}
+	if encrypt {
+		if data, err = Encrypt(plaintext, key); err != nil {
+			return false, true, err
+		}
+	} else {
+		data = plaintext
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return false, true, err
+	}
+	return true, true, nil
+}
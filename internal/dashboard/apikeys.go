@@ -0,0 +1,215 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"gcli2apigo/internal/apikeys"
+	"gcli2apigo/internal/usage"
+)
+
+// APIKeyInfo is the dashboard view of a named API key with today's usage
+type APIKeyInfo struct {
+	ID                string     `json:"id"`
+	Label             string     `json:"label"`
+	KeyPrefix         string     `json:"key_prefix"`
+	DailyRequestLimit int        `json:"daily_request_limit"`
+	DailyTokenLimit   int        `json:"daily_token_limit"`
+	AllowedModels     []string   `json:"allowed_models"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	Revoked           bool       `json:"revoked"`
+	Active            bool       `json:"active"`
+	RequestCount      int        `json:"request_count"`
+	TokenCount        int        `json:"token_count"`
+}
+
+// newAPIKeyInfo builds the dashboard view of a key, leaving out its hash
+func newAPIKeyInfo(key *apikeys.APIKey) APIKeyInfo {
+	keyUsage := usage.GetTracker().GetKeyUsage(key.ID)
+	allowedModels := key.AllowedModels
+	if allowedModels == nil {
+		allowedModels = []string{}
+	}
+
+	return APIKeyInfo{
+		ID:                key.ID,
+		Label:             key.Label,
+		KeyPrefix:         key.KeyPrefix,
+		DailyRequestLimit: key.DailyRequestLimit,
+		DailyTokenLimit:   key.DailyTokenLimit,
+		AllowedModels:     allowedModels,
+		ExpiresAt:         key.ExpiresAt,
+		CreatedAt:         key.CreatedAt,
+		Revoked:           key.Revoked,
+		Active:            key.IsActive(),
+		RequestCount:      keyUsage.RequestCount,
+		TokenCount:        keyUsage.TokenCount,
+	}
+}
+
+// writeJSONError writes a dashboard API error response
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"error":   message,
+	})
+}
+
+// HandleListAPIKeys returns all named API keys with today's usage
+func (dh *DashboardHandlers) HandleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	keys := apikeys.GetKeyStore().List()
+	infos := make([]APIKeyInfo, 0, len(keys))
+	for i := range keys {
+		infos = append(infos, newAPIKeyInfo(&keys[i]))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": infos,
+	})
+}
+
+// HandleCreateAPIKey issues a new named API key; the key is only returned in this response
+func (dh *DashboardHandlers) HandleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req apikeys.KeyOptions
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Label) == "" {
+		writeJSONError(w, http.StatusBadRequest, "Label is required")
+		return
+	}
+
+	plaintext, key, err := apikeys.GetKeyStore().Create(req)
+	if err != nil {
+		log.Printf("[ERROR] Failed to create API key: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to create API key")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"key":     plaintext,
+		"info":    newAPIKeyInfo(key),
+	})
+}
+
+// HandleUpdateAPIKey changes the label, quotas, allowed models or expiry of a key
+func (dh *DashboardHandlers) HandleUpdateAPIKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID string `json:"id"`
+		apikeys.KeyOptions
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.ID == "" {
+		writeJSONError(w, http.StatusBadRequest, "Key ID is required")
+		return
+	}
+
+	key, err := apikeys.GetKeyStore().Update(req.ID, req.KeyOptions)
+	if err != nil {
+		log.Printf("[ERROR] Failed to update API key %s: %v", req.ID, err)
+		if errors.Is(err, apikeys.ErrKeyNotFound) {
+			writeJSONError(w, http.StatusNotFound, "API key not found")
+		} else {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to update API key")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"info":    newAPIKeyInfo(key),
+	})
+}
+
+// HandleRevokeAPIKey revokes a named API key; the record is kept for usage history
+func (dh *DashboardHandlers) HandleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+		writeJSONError(w, http.StatusBadRequest, "Key ID is required")
+		return
+	}
+
+	if err := apikeys.GetKeyStore().Revoke(req.ID); err != nil {
+		log.Printf("[ERROR] Failed to revoke API key %s: %v", req.ID, err)
+		if errors.Is(err, apikeys.ErrKeyNotFound) {
+			writeJSONError(w, http.StatusNotFound, "API key not found")
+		} else {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to revoke API key")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "API key revoked",
+	})
+}
+
+// HandleDeleteAPIKey permanently deletes a named API key
+// Expected path: /dashboard/api/keys/{id}
+func (dh *DashboardHandlers) HandleDeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/dashboard/api/keys/"), "/")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "Key ID is required")
+		return
+	}
+
+	if err := apikeys.GetKeyStore().Delete(id); err != nil {
+		log.Printf("[ERROR] Failed to delete API key %s: %v", id, err)
+		if errors.Is(err, apikeys.ErrKeyNotFound) {
+			writeJSONError(w, http.StatusNotFound, "API key not found")
+		} else {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to delete API key")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "API key deleted",
+	})
+}
@@ -0,0 +1,67 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"gcli2apigo/internal/config"
+)
+
+// HandleGetModels returns the models, aliases and fallbacks of the model registry and where they were loaded from
+func (dh *DashboardHandlers) HandleGetModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	registry := config.GetModelRegistry()
+	cfg := registry.Config()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"models":    cfg.Models,
+		"aliases":   cfg.Aliases,
+		"fallbacks": cfg.Fallbacks,
+		"source":    registry.Source(),
+		"path":      registry.Path(),
+	})
+}
+
+// HandleSaveModels replaces the model registry and writes it to the registry file.
+// The change applies immediately; no restart is needed.
+func (dh *DashboardHandlers) HandleSaveModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req config.ModelRegistryConfig
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if _, err := config.ValidateModelRegistry(req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	registry := config.GetModelRegistry()
+	if err := registry.Save(req); err != nil {
+		log.Printf("[ERROR] Failed to save model registry: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to save model registry: "+err.Error())
+		return
+	}
+
+	cfg := registry.Config()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"models":    cfg.Models,
+		"aliases":   cfg.Aliases,
+		"fallbacks": cfg.Fallbacks,
+		"source":    registry.Source(),
+		"path":      registry.Path(),
+	})
+}
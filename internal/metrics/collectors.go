@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"gcli2apigo/internal/config"
+)
+
+// latencyBuckets are the upper bounds, in seconds, used for request and upstream latency histograms
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+var (
+	requestsTotal = NewCounterVec("gcli2api_requests_total",
+		"API requests by route, model and HTTP status.", "route", "model", "status")
+	requestDuration = NewHistogramVec("gcli2api_request_duration_seconds",
+		"API request latency in seconds by route, model and HTTP status.", latencyBuckets, "route", "model", "status")
+	upstreamRetries = NewHistogramVec("gcli2api_upstream_retries",
+		"Additional upstream attempts per request.", []float64{0, 1, 2, 3, 5, 10}, "action")
+	retriesTotal = NewCounterVec("gcli2api_upstream_retries_total",
+		"Upstream retries by action and reason.", "action", "reason")
+	rateLimitedTotal = NewCounterVec("gcli2api_upstream_rate_limited_total",
+		"429 responses from the upstream API by credential project.", "project_id")
+	tokenRefreshTotal = NewCounterVec("gcli2api_token_refresh_total",
+		"OAuth token refreshes by result (success or failure).", "result")
+	activeStreams = NewGauge("gcli2api_active_streams",
+		"Upstream streaming responses currently being relayed.")
+	streamFailoverTotal = NewCounterVec("gcli2api_stream_failover_total",
+		"Interrupted upstream streams by failover result (recovered or failed).", "result")
+	credentialReloadsTotal = NewCounterVec("gcli2api_credential_reloads_total",
+		"Credential pool reloads from disk by trigger (watcher or reload).", "trigger")
+	credentialChangesTotal = NewCounterVec("gcli2api_credential_changes_total",
+		"Credentials added, updated or removed by credential pool reloads.", "change")
+	upstreamTTFB = NewHistogramVec("gcli2api_upstream_ttfb_seconds",
+		"Time in seconds until the upstream API returned response headers, by action and model.", latencyBuckets, "action", "model")
+)
+
+// otherModelLabel is the model label of requests for models that are not in the registry
+const otherModelLabel = "other"
+
+// normalizeModel maps a client-supplied model name to a bounded label value: the registry
+// model it names or aliases (without a "models/" prefix), the default embedding model, or
+// "other", so callers cannot create a time series per made-up model name
+func normalizeModel(model string) string {
+	if model == "" {
+		return ""
+	}
+	model, _ = config.GetModelRegistry().ResolveAlias(model)
+	if _, ok := config.GetModelRegistry().Lookup(model); ok || model == config.GetDefaultEmbeddingModel() {
+		return model
+	}
+	return otherModelLabel
+}
+
+// ObserveRequest records a finished API request
+func ObserveRequest(route, model string, status int, duration time.Duration) {
+	statusLabel := strconv.Itoa(status)
+	model = normalizeModel(model)
+	requestsTotal.Inc(route, model, statusLabel)
+	requestDuration.Observe(duration.Seconds(), route, model, statusLabel)
+}
+
+// ObserveRetries records how many additional credentials an upstream request needed
+func ObserveRetries(action string, retries int) {
+	upstreamRetries.Observe(float64(retries), action)
+}
+
+// RecordRetry counts a retry of an upstream request and its reason
+func RecordRetry(action, reason string) {
+	retriesTotal.Inc(action, reason)
+}
+
+// RecordRateLimited counts a 429 response for a credential
+func RecordRateLimited(projectID string) {
+	rateLimitedTotal.Inc(projectID)
+}
+
+// RecordTokenRefresh counts an OAuth token refresh attempt
+func RecordTokenRefresh(success bool) {
+	if success {
+		tokenRefreshTotal.Inc("success")
+	} else {
+		tokenRefreshTotal.Inc("failure")
+	}
+}
+
+// StreamStarted marks the start of a relayed upstream stream
+func StreamStarted() {
+	activeStreams.Inc()
+}
+
+// StreamEnded marks the end of a relayed upstream stream
+func StreamEnded() {
+	activeStreams.Dec()
+}
+
+// RecordStreamFailover counts the outcome of resuming an interrupted stream
+func RecordStreamFailover(result string) {
+	streamFailoverTotal.Inc(result)
+}
+
+// ObserveUpstreamTTFB records the time until upstream response headers arrived
+func ObserveUpstreamTTFB(action, model string, duration time.Duration) {
+	upstreamTTFB.Observe(duration.Seconds(), action, normalizeModel(model))
+}
+
+// RecordCredentialReload counts a credential pool reload and the credentials it changed
+func RecordCredentialReload(trigger string, added, updated, removed int) {
+	credentialReloadsTotal.Inc(trigger)
+	credentialChangesTotal.Add(float64(added), "added")
+	credentialChangesTotal.Add(float64(updated), "updated")
+	credentialChangesTotal.Add(float64(removed), "removed")
+}
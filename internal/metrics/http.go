@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"gcli2apigo/internal/config"
+)
+
+// requestLabelsKey is the context key for per-request metric labels
+type requestLabelsKey struct{}
+
+// requestLabels holds labels that handlers fill in while serving a request
+type requestLabels struct {
+	model string
+}
+
+// SetModel sets the model label for the request being served
+func SetModel(r *http.Request, model string) {
+	if labels, ok := r.Context().Value(requestLabelsKey{}).(*requestLabels); ok {
+		labels.model = model
+	}
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	if sr.status == 0 {
+		sr.status = status
+	}
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	return sr.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming responses working through the recorder
+func (sr *statusRecorder) Flush() {
+	if flusher, ok := sr.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+// Instrument wraps an API handler to record request counts and latency under the given route label
+func Instrument(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		labels := &requestLabels{}
+		recorder := &statusRecorder{ResponseWriter: w}
+
+		next(recorder, r.WithContext(context.WithValue(r.Context(), requestLabelsKey{}, labels)))
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		ObserveRequest(route, labels.model, status, time.Since(start))
+	}
+}
+
+// HandleMetrics serves all metrics in the Prometheus text format.
+// The endpoint is disabled unless METRICS_TOKEN is set; scrapers authenticate with
+// "Authorization: Bearer <token>" or the "token" query parameter.
+func HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	token := config.GetMetricsToken()
+	if token == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	provided := r.URL.Query().Get("token")
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		provided = bearer
+	}
+	if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(Render()))
+}
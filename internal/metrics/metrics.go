@@ -0,0 +1,267 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// collector writes one metric family in the Prometheus text exposition format
+type collector interface {
+	write(b *strings.Builder)
+}
+
+var (
+	registry   []collector
+	registryMu sync.Mutex
+)
+
+// register adds a collector to the registry in declaration order
+func register(c collector) {
+	registryMu.Lock()
+	registry = append(registry, c)
+	registryMu.Unlock()
+}
+
+// labelKey joins label values into a map key
+func labelKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// escapeLabelValue escapes a label value for the text format
+func escapeLabelValue(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `"`, `\"`)
+	return strings.ReplaceAll(v, "\n", `\n`)
+}
+
+// formatLabels renders {name="value",...}, with optional extra pairs appended
+func formatLabels(names []string, values []string, extra ...string) string {
+	if len(names) == 0 && len(extra) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, name, escapeLabelValue(values[i]))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, extra[i], escapeLabelValue(extra[i+1]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// formatFloat renders a sample value
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric family
+func writeHeader(b *strings.Builder, name, help, metricType string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// sortedKeys returns map keys in a stable order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+	mu     sync.Mutex
+	values map[string]float64
+	label  map[string][]string
+}
+
+// NewCounterVec creates and registers a counter
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: make(map[string]float64),
+		label:  make(map[string][]string),
+	}
+	register(c)
+	return c
+}
+
+// Add increases the counter for the given label values
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	key := labelKey(labelValues)
+	c.mu.Lock()
+	if _, exists := c.label[key]; !exists {
+		c.label[key] = append([]string(nil), labelValues...)
+	}
+	c.values[key] += delta
+	c.mu.Unlock()
+}
+
+// Inc increases the counter for the given label values by one
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) write(b *strings.Builder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	writeHeader(b, c.name, c.help, "counter")
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(b, "%s%s %s\n", c.name, formatLabels(c.labels, c.label[key]), formatFloat(c.values[key]))
+	}
+}
+
+// histogramValue holds the bucket counts of one label combination
+type histogramValue struct {
+	labels  []string
+	buckets []uint64 // Cumulative counts, one per upper bound
+	count   uint64
+	sum     float64
+}
+
+// HistogramVec is a histogram partitioned by labels
+type HistogramVec struct {
+	name   string
+	help   string
+	labels []string
+	bounds []float64
+	mu     sync.Mutex
+	values map[string]*histogramValue
+}
+
+// NewHistogramVec creates and registers a histogram with the given bucket upper bounds
+func NewHistogramVec(name, help string, bounds []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		bounds: bounds,
+		values: make(map[string]*histogramValue),
+	}
+	register(h)
+	return h
+}
+
+// Observe records a value for the given label values
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := labelKey(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hv, exists := h.values[key]
+	if !exists {
+		hv = &histogramValue{
+			labels:  append([]string(nil), labelValues...),
+			buckets: make([]uint64, len(h.bounds)),
+		}
+		h.values[key] = hv
+	}
+	for i, bound := range h.bounds {
+		if v <= bound {
+			hv.buckets[i]++
+		}
+	}
+	hv.count++
+	hv.sum += v
+}
+
+func (h *HistogramVec) write(b *strings.Builder) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	writeHeader(b, h.name, h.help, "histogram")
+	for _, key := range sortedKeys(h.values) {
+		hv := h.values[key]
+		for i, bound := range h.bounds {
+			fmt.Fprintf(b, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, hv.labels, "le", formatFloat(bound)), hv.buckets[i])
+		}
+		fmt.Fprintf(b, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, hv.labels, "le", "+Inf"), hv.count)
+		fmt.Fprintf(b, "%s_sum%s %s\n", h.name, formatLabels(h.labels, hv.labels), formatFloat(hv.sum))
+		fmt.Fprintf(b, "%s_count%s %d\n", h.name, formatLabels(h.labels, hv.labels), hv.count)
+	}
+}
+
+// Gauge is a value that can go up and down
+type Gauge struct {
+	name  string
+	help  string
+	value atomic.Int64
+}
+
+// NewGauge creates and registers a gauge
+func NewGauge(name, help string) *Gauge {
+	g := &Gauge{name: name, help: help}
+	register(g)
+	return g
+}
+
+// Inc increases the gauge by one
+func (g *Gauge) Inc() {
+	g.value.Add(1)
+}
+
+// Dec decreases the gauge by one
+func (g *Gauge) Dec() {
+	g.value.Add(-1)
+}
+
+func (g *Gauge) write(b *strings.Builder) {
+	writeHeader(b, g.name, g.help, "gauge")
+	fmt.Fprintf(b, "%s %d\n", g.name, g.value.Load())
+}
+
+// GaugeFunc is a gauge whose value is computed at scrape time
+type GaugeFunc struct {
+	name string
+	help string
+	fn   func() float64
+}
+
+// NewGaugeFunc creates and registers a gauge computed by fn on every scrape
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{name: name, help: help, fn: fn}
+	register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(b *strings.Builder) {
+	writeHeader(b, g.name, g.help, "gauge")
+	fmt.Fprintf(b, "%s %s\n", g.name, formatFloat(g.fn()))
+}
+
+// Render returns all registered metrics in the Prometheus text exposition format
+func Render() string {
+	registryMu.Lock()
+	collectors := append([]collector(nil), registry...)
+	registryMu.Unlock()
+
+	var b strings.Builder
+	for _, c := range collectors {
+		c.write(&b)
+	}
+	return b.String()
+}
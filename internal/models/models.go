@@ -1,99 +1,270 @@
-package models
-
-// OpenAI Models
-
-type OpenAIChatMessage struct {
-	Role             string      `json:"role"`
-	Content          interface{} `json:"content"` // Can be string or []ContentPart
-	ReasoningContent string      `json:"reasoning_content,omitempty"`
-}
-
-type ContentPart struct {
-	Type     string    `json:"type"`
-	Text     string    `json:"text,omitempty"`
-	ImageURL *ImageURL `json:"image_url,omitempty"`
-}
-
-type ImageURL struct {
-	URL string `json:"url"`
-}
-
-type OpenAIChatCompletionRequest struct {
-	Model            string                 `json:"model"`
-	Messages         []OpenAIChatMessage    `json:"messages"`
-	Stream           bool                   `json:"stream,omitempty"`
-	Temperature      *float64               `json:"temperature,omitempty"`
-	TopP             *float64               `json:"top_p,omitempty"`
-	MaxTokens        *int                   `json:"max_tokens,omitempty"`
-	Stop             interface{}            `json:"stop,omitempty"` // Can be string or []string
-	FrequencyPenalty *float64               `json:"frequency_penalty,omitempty"`
-	PresencePenalty  *float64               `json:"presence_penalty,omitempty"`
-	N                *int                   `json:"n,omitempty"`
-	Seed             *int                   `json:"seed,omitempty"`
-	ResponseFormat   map[string]interface{} `json:"response_format,omitempty"`
-}
-
-type OpenAIChatCompletionChoice struct {
-	Index        int               `json:"index"`
-	Message      OpenAIChatMessage `json:"message"`
-	FinishReason *string           `json:"finish_reason,omitempty"`
-}
-
-type OpenAIChatCompletionResponse struct {
-	ID      string                       `json:"id"`
-	Object  string                       `json:"object"`
-	Created int64                        `json:"created"`
-	Model   string                       `json:"model"`
-	Choices []OpenAIChatCompletionChoice `json:"choices"`
-}
-
-type OpenAIDelta struct {
-	Content          string `json:"content,omitempty"`
-	ReasoningContent string `json:"reasoning_content,omitempty"`
-}
-
-type OpenAIChatCompletionStreamChoice struct {
-	Index        int         `json:"index"`
-	Delta        OpenAIDelta `json:"delta"`
-	FinishReason *string     `json:"finish_reason,omitempty"`
-}
-
-type OpenAIChatCompletionStreamResponse struct {
-	ID      string                             `json:"id"`
-	Object  string                             `json:"object"`
-	Created int64                              `json:"created"`
-	Model   string                             `json:"model"`
-	Choices []OpenAIChatCompletionStreamChoice `json:"choices"`
-}
-
-// Gemini Models
-
-type GeminiPart struct {
-	Text       string            `json:"text,omitempty"`
-	Thought    bool              `json:"thought,omitempty"`
-	InlineData *GeminiInlineData `json:"inlineData,omitempty"`
-}
-
-type GeminiInlineData struct {
-	MimeType string `json:"mimeType"`
-	Data     string `json:"data"`
-}
-
-type GeminiContent struct {
-	Role  string       `json:"role"`
-	Parts []GeminiPart `json:"parts"`
-}
-
-type GeminiRequest struct {
-	Contents []GeminiContent `json:"contents"`
-}
-
-type GeminiCandidate struct {
-	Content      GeminiContent `json:"content"`
-	FinishReason string        `json:"finishReason,omitempty"`
-	Index        int           `json:"index"`
-}
-
-type GeminiResponse struct {
-	Candidates []GeminiCandidate `json:"candidates"`
-}
+package models
+
+// OpenAI Models
+
+type OpenAIChatMessage struct {
+	Role             string      `json:"role"`
+	Content          interface{} `json:"content"` // Can be string or []ContentPart
+	ReasoningContent string      `json:"reasoning_content,omitempty"`
+	ToolCalls        []ToolCall  `json:"tool_calls,omitempty"`   // Assistant function calls
+	ToolCallID       string      `json:"tool_call_id,omitempty"` // Set on role "tool" messages
+	Name             string      `json:"name,omitempty"`
+}
+
+// ToolCall represents a function call issued by the assistant
+type ToolCall struct {
+	Index        *int                  `json:"index,omitempty"` // Only present in streaming deltas
+	ID           string                `json:"id,omitempty"`
+	Type         string                `json:"type,omitempty"`
+	Function     ToolCallFunction      `json:"function"`
+	ExtraContent *ToolCallExtraContent `json:"extra_content,omitempty"` // Provider-specific data to send back unchanged
+}
+
+// ToolCallExtraContent holds provider-specific tool call data, in the format of Gemini's
+// OpenAI-compatible API: {"google": {"thought_signature": "..."}}
+type ToolCallExtraContent struct {
+	Google *GoogleToolCallExtra `json:"google,omitempty"`
+}
+
+// GoogleToolCallExtra carries the thought signature Gemini attached to a function call.
+// It must be replayed with the call so the model keeps its reasoning context.
+type GoogleToolCallExtra struct {
+	ThoughtSignature string `json:"thought_signature,omitempty"`
+}
+
+// ToolCallFunction holds the function name and JSON-encoded arguments of a tool call
+type ToolCallFunction struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}
+
+// OpenAITool represents a tool definition in a chat completion request
+type OpenAITool struct {
+	Type     string         `json:"type"`
+	Function OpenAIFunction `json:"function"`
+}
+
+// OpenAIFunction describes a function the model may call
+type OpenAIFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+	Strict      *bool                  `json:"strict,omitempty"`
+}
+
+type ContentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+type ImageURL struct {
+	URL string `json:"url"`
+}
+
+type OpenAIChatCompletionRequest struct {
+	Model             string                 `json:"model"`
+	Messages          []OpenAIChatMessage    `json:"messages"`
+	Stream            bool                   `json:"stream,omitempty"`
+	StreamOptions     *StreamOptions         `json:"stream_options,omitempty"`
+	Temperature       *float64               `json:"temperature,omitempty"`
+	TopP              *float64               `json:"top_p,omitempty"`
+	MaxTokens         *int                   `json:"max_tokens,omitempty"`
+	Stop              interface{}            `json:"stop,omitempty"` // Can be string or []string
+	FrequencyPenalty  *float64               `json:"frequency_penalty,omitempty"`
+	PresencePenalty   *float64               `json:"presence_penalty,omitempty"`
+	N                 *int                   `json:"n,omitempty"`
+	Seed              *int                   `json:"seed,omitempty"`
+	ResponseFormat    map[string]interface{} `json:"response_format,omitempty"`
+	Tools             []OpenAITool           `json:"tools,omitempty"`
+	ToolChoice        interface{}            `json:"tool_choice,omitempty"` // Can be string or object
+	ParallelToolCalls *bool                  `json:"parallel_tool_calls,omitempty"`
+}
+
+// StreamOptions holds streaming options; IncludeUsage requests a final usage chunk
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type OpenAIChatCompletionChoice struct {
+	Index        int               `json:"index"`
+	Message      OpenAIChatMessage `json:"message"`
+	FinishReason *string           `json:"finish_reason,omitempty"`
+}
+
+type OpenAIChatCompletionResponse struct {
+	ID      string                       `json:"id"`
+	Object  string                       `json:"object"`
+	Created int64                        `json:"created"`
+	Model   string                       `json:"model"`
+	Choices []OpenAIChatCompletionChoice `json:"choices"`
+}
+
+type OpenAIDelta struct {
+	Content          string     `json:"content,omitempty"`
+	ReasoningContent string     `json:"reasoning_content,omitempty"`
+	ToolCalls        []ToolCall `json:"tool_calls,omitempty"`
+}
+
+type OpenAIChatCompletionStreamChoice struct {
+	Index        int         `json:"index"`
+	Delta        OpenAIDelta `json:"delta"`
+	FinishReason *string     `json:"finish_reason,omitempty"`
+}
+
+type OpenAIChatCompletionStreamResponse struct {
+	ID      string                             `json:"id"`
+	Object  string                             `json:"object"`
+	Created int64                              `json:"created"`
+	Model   string                             `json:"model"`
+	Choices []OpenAIChatCompletionStreamChoice `json:"choices"`
+}
+
+// OpenAICompletionRequest is a request to the legacy text completions endpoint
+type OpenAICompletionRequest struct {
+	Model            string         `json:"model"`
+	Prompt           interface{}    `json:"prompt"` // Can be string or []string
+	Suffix           string         `json:"suffix,omitempty"`
+	Echo             bool           `json:"echo,omitempty"`
+	Stream           bool           `json:"stream,omitempty"`
+	StreamOptions    *StreamOptions `json:"stream_options,omitempty"`
+	Temperature      *float64       `json:"temperature,omitempty"`
+	TopP             *float64       `json:"top_p,omitempty"`
+	MaxTokens        *int           `json:"max_tokens,omitempty"`
+	Stop             interface{}    `json:"stop,omitempty"` // Can be string or []string
+	FrequencyPenalty *float64       `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float64       `json:"presence_penalty,omitempty"`
+	N                *int           `json:"n,omitempty"`
+	Seed             *int           `json:"seed,omitempty"`
+	Logprobs         *int           `json:"logprobs,omitempty"` // Not supported; logprobs are always null
+	User             string         `json:"user,omitempty"`
+}
+
+type OpenAIEmbeddingRequest struct {
+	Model          string      `json:"model"`
+	Input          interface{} `json:"input"` // Can be string or []string
+	EncodingFormat string      `json:"encoding_format,omitempty"`
+	Dimensions     *int        `json:"dimensions,omitempty"`
+	User           string      `json:"user,omitempty"`
+}
+
+// Anthropic Models
+
+type AnthropicMessage struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"` // Can be string or []content block
+}
+
+// AnthropicTool represents a tool definition in a Messages API request
+type AnthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema,omitempty"`
+}
+
+// AnthropicThinking configures extended thinking ("enabled" or "disabled")
+type AnthropicThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens,omitempty"`
+}
+
+type AnthropicMessagesRequest struct {
+	Model         string                 `json:"model"`
+	Messages      []AnthropicMessage     `json:"messages"`
+	System        interface{}            `json:"system,omitempty"` // Can be string or []text block
+	MaxTokens     int                    `json:"max_tokens"`
+	Stream        bool                   `json:"stream,omitempty"`
+	Temperature   *float64               `json:"temperature,omitempty"`
+	TopP          *float64               `json:"top_p,omitempty"`
+	TopK          *int                   `json:"top_k,omitempty"`
+	StopSequences []string               `json:"stop_sequences,omitempty"`
+	Tools         []AnthropicTool        `json:"tools,omitempty"`
+	ToolChoice    map[string]interface{} `json:"tool_choice,omitempty"`
+	Thinking      *AnthropicThinking     `json:"thinking,omitempty"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// OpenAI Responses Models
+
+// ResponsesTool represents a tool definition in a Responses API request; only "function" tools are supported
+type ResponsesTool struct {
+	Type        string                 `json:"type"`
+	Name        string                 `json:"name,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+	Strict      *bool                  `json:"strict,omitempty"`
+}
+
+// ResponsesReasoning configures reasoning effort and whether reasoning summaries are returned
+type ResponsesReasoning struct {
+	Effort  string `json:"effort,omitempty"`  // minimal, low, medium or high
+	Summary string `json:"summary,omitempty"` // auto, concise or detailed
+}
+
+// ResponsesText configures the text output format
+type ResponsesText struct {
+	Format map[string]interface{} `json:"format,omitempty"` // {"type": "text" | "json_object" | "json_schema", ...}
+}
+
+type ResponsesRequest struct {
+	Model              string                 `json:"model"`
+	Input              interface{}            `json:"input"` // Can be string or []input item
+	Instructions       string                 `json:"instructions,omitempty"`
+	Stream             bool                   `json:"stream,omitempty"`
+	Temperature        *float64               `json:"temperature,omitempty"`
+	TopP               *float64               `json:"top_p,omitempty"`
+	MaxOutputTokens    *int                   `json:"max_output_tokens,omitempty"`
+	Tools              []ResponsesTool        `json:"tools,omitempty"`
+	ToolChoice         interface{}            `json:"tool_choice,omitempty"` // Can be string or object
+	ParallelToolCalls  *bool                  `json:"parallel_tool_calls,omitempty"`
+	Reasoning          *ResponsesReasoning    `json:"reasoning,omitempty"`
+	Text               *ResponsesText         `json:"text,omitempty"`
+	PreviousResponseID string                 `json:"previous_response_id,omitempty"`
+	Store              *bool                  `json:"store,omitempty"` // Defaults to true
+	Metadata           map[string]interface{} `json:"metadata,omitempty"`
+	User               string                 `json:"user,omitempty"`
+}
+
+// Gemini Models
+
+type GeminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`
+	InlineData       *GeminiInlineData       `json:"inlineData,omitempty"`
+	FunctionCall     *GeminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *GeminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type GeminiFunctionCall struct {
+	Name string                 `json:"name"`
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+type GeminiFunctionResponse struct {
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
+type GeminiInlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type GeminiContent struct {
+	Role  string       `json:"role"`
+	Parts []GeminiPart `json:"parts"`
+}
+
+type GeminiRequest struct {
+	Contents []GeminiContent `json:"contents"`
+}
+
+type GeminiCandidate struct {
+	Content      GeminiContent `json:"content"`
+	FinishReason string        `json:"finishReason,omitempty"`
+	Index        int           `json:"index"`
+}
+
+type GeminiResponse struct {
+	Candidates []GeminiCandidate `json:"candidates"`
+}
@@ -0,0 +1,360 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"gcli2apigo/internal/apikeys"
+	"gcli2apigo/internal/auth"
+	"gcli2apigo/internal/client"
+	"gcli2apigo/internal/metrics"
+	"gcli2apigo/internal/models"
+	"gcli2apigo/internal/transformers"
+)
+
+// writeAnthropicError writes an error in the Anthropic Messages API error format
+func writeAnthropicError(w http.ResponseWriter, status int, errorType string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"type": "error",
+		"error": map[string]interface{}{
+			"type":    errorType,
+			"message": message,
+		},
+	})
+}
+
+// anthropicErrorType maps an HTTP status code to an Anthropic error type
+func anthropicErrorType(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "invalid_request_error"
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case http.StatusServiceUnavailable:
+		return "overloaded_error"
+	default:
+		return "api_error"
+	}
+}
+
+// HandleMessages handles the Anthropic-compatible Messages endpoint
+func HandleMessages(w http.ResponseWriter, r *http.Request) {
+	// Authenticate user
+	identity, err := auth.AuthenticateUser(r)
+	if err != nil {
+		writeAnthropicError(w, http.StatusUnauthorized, "authentication_error", "Invalid authentication credentials")
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeAnthropicError(w, http.StatusMethodNotAllowed, "invalid_request_error", "Method not allowed")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, "invalid_request_error", "Failed to read request body")
+		return
+	}
+
+	var request models.AnthropicMessagesRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, "invalid_request_error", "Invalid JSON in request body")
+		return
+	}
+
+	if request.Model == "" {
+		writeAnthropicError(w, http.StatusBadRequest, "invalid_request_error", "model: Field required")
+		return
+	}
+	if len(request.Messages) == 0 {
+		writeAnthropicError(w, http.StatusBadRequest, "invalid_request_error", "messages: Field required")
+		return
+	}
+
+	log.Printf("Anthropic messages request: model=%s, stream=%v", request.Model, request.Stream)
+
+	// Resolve aliases and skip to a fallback model when the pool's quota is exhausted
+	route := resolveModelRoute(identity, request.Model, false)
+	request.Model = route.Model
+	metrics.SetModel(r, request.Model)
+
+	// Enforce named API key model restrictions and daily quotas
+	apiKeyID, err := apikeys.GetKeyStore().Authorize(identity, request.Model)
+	if err != nil {
+		status := apikeys.HTTPStatus(err)
+		writeAnthropicError(w, status, anthropicErrorType(status), err.Error())
+		return
+	}
+
+	// Transform Anthropic request to Gemini format
+	if _, err := transformers.AnthropicRequestToGemini(&request); err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	// Build the payload for Google API. A fallback model gets its own payload, since the
+	// thinking budget depends on the model.
+	buildPayload := func(model string) map[string]interface{} {
+		modelRequest := request
+		modelRequest.Model = model
+		geminiRequestData, _ := transformers.AnthropicRequestToGemini(&modelRequest)
+		geminiPayload := client.BuildGeminiPayloadFromOpenAI(geminiRequestData)
+		if apiKeyID != "" {
+			geminiPayload[client.APIKeyIDField] = apiKeyID
+		}
+		return geminiPayload
+	}
+
+	includeThinking := request.Thinking != nil && request.Thinking.Type == "enabled"
+
+	if request.Stream {
+		handleStreamingMessages(w, r, &request, &route, buildPayload, includeThinking)
+	} else {
+		handleNonStreamingMessages(w, r, &request, &route, buildPayload, includeThinking)
+	}
+}
+
+func handleNonStreamingMessages(w http.ResponseWriter, r *http.Request, request *models.AnthropicMessagesRequest, route *modelRoute, buildPayload func(model string) map[string]interface{}, includeThinking bool) {
+	result, err := sendWithFallback(r.Context(), route, false, buildPayload)
+	request.Model = route.Model
+	reportServedModel(w, r, route)
+	if err != nil {
+		writeAnthropicError(w, http.StatusInternalServerError, "api_error", fmt.Sprintf("Request failed: %v", err))
+		return
+	}
+
+	geminiResponse, ok := result.(map[string]interface{})
+	if !ok {
+		writeAnthropicError(w, http.StatusInternalServerError, "api_error", "Invalid response from API")
+		return
+	}
+
+	// Check for error in response
+	if errObj, ok := geminiResponse["error"].(map[string]interface{}); ok {
+		status := http.StatusInternalServerError
+		if code, ok := errObj["code"].(float64); ok {
+			status = int(code)
+		} else if code, ok := errObj["code"].(int); ok {
+			status = code
+		}
+		message, _ := errObj["message"].(string)
+		writeAnthropicError(w, status, anthropicErrorType(status), message)
+		return
+	}
+
+	anthropicResponse := transformers.GeminiResponseToAnthropic(geminiResponse, request.Model, includeThinking)
+
+	log.Printf("Successfully processed non-streaming messages response for model: %s", request.Model)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(anthropicResponse)
+}
+
+// anthropicStreamWriter emits the Anthropic SSE event sequence, opening and closing
+// content blocks as the type of the streamed Gemini parts changes
+type anthropicStreamWriter struct {
+	w          http.ResponseWriter
+	flusher    http.Flusher
+	blockIndex int
+	openType   string // Type of the currently open content block, empty if none
+	hasToolUse bool
+}
+
+func (sw *anthropicStreamWriter) send(event string, data map[string]interface{}) {
+	jsonData, _ := json.Marshal(data)
+	fmt.Fprintf(sw.w, "event: %s\ndata: %s\n\n", event, string(jsonData))
+	sw.flusher.Flush()
+}
+
+// closeBlock sends content_block_stop for the open block, if any
+func (sw *anthropicStreamWriter) closeBlock() {
+	if sw.openType == "" {
+		return
+	}
+	sw.send("content_block_stop", map[string]interface{}{
+		"type":  "content_block_stop",
+		"index": sw.blockIndex,
+	})
+	sw.blockIndex++
+	sw.openType = ""
+}
+
+// writeBlock streams a single content block, continuing the open block when the type matches
+func (sw *anthropicStreamWriter) writeBlock(block map[string]interface{}) {
+	blockType, _ := block["type"].(string)
+
+	if blockType != sw.openType || blockType == "tool_use" {
+		sw.closeBlock()
+
+		var start map[string]interface{}
+		switch blockType {
+		case "thinking":
+			start = map[string]interface{}{"type": "thinking", "thinking": ""}
+		case "tool_use":
+			start = map[string]interface{}{
+				"type":  "tool_use",
+				"id":    block["id"],
+				"name":  block["name"],
+				"input": map[string]interface{}{},
+			}
+			sw.hasToolUse = true
+		default:
+			start = map[string]interface{}{"type": "text", "text": ""}
+		}
+
+		sw.send("content_block_start", map[string]interface{}{
+			"type":          "content_block_start",
+			"index":         sw.blockIndex,
+			"content_block": start,
+		})
+		sw.openType = blockType
+	}
+
+	var delta map[string]interface{}
+	switch blockType {
+	case "thinking":
+		delta = map[string]interface{}{"type": "thinking_delta", "thinking": block["thinking"]}
+	case "tool_use":
+		inputJSON, _ := json.Marshal(block["input"])
+		delta = map[string]interface{}{"type": "input_json_delta", "partial_json": string(inputJSON)}
+	default:
+		delta = map[string]interface{}{"type": "text_delta", "text": block["text"]}
+	}
+
+	sw.send("content_block_delta", map[string]interface{}{
+		"type":  "content_block_delta",
+		"index": sw.blockIndex,
+		"delta": delta,
+	})
+
+	if signature, _ := block["signature"].(string); signature != "" {
+		sw.send("content_block_delta", map[string]interface{}{
+			"type":  "content_block_delta",
+			"index": sw.blockIndex,
+			"delta": map[string]interface{}{"type": "signature_delta", "signature": signature},
+		})
+	}
+
+	// Tool input is sent whole, so the block can be closed right away
+	if blockType == "tool_use" {
+		sw.closeBlock()
+	}
+}
+
+func handleStreamingMessages(w http.ResponseWriter, r *http.Request, request *models.AnthropicMessagesRequest, route *modelRoute, buildPayload func(model string) map[string]interface{}, includeThinking bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAnthropicError(w, http.StatusInternalServerError, "api_error", "Streaming not supported")
+		return
+	}
+
+	// Send request before writing SSE headers so upstream failures keep a proper status code
+	result, err := sendWithFallback(r.Context(), route, true, buildPayload)
+	request.Model = route.Model
+	reportServedModel(w, r, route)
+	if err != nil {
+		writeAnthropicError(w, http.StatusInternalServerError, "api_error", fmt.Sprintf("Request failed: %v", err))
+		return
+	}
+
+	streamChan, ok := result.(chan string)
+	if !ok {
+		writeAnthropicError(w, http.StatusInternalServerError, "api_error", "Streaming request failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	messageID := transformers.NewAnthropicMessageID()
+	log.Printf("Starting streaming messages response: %s", messageID)
+
+	sw := &anthropicStreamWriter{w: w, flusher: flusher}
+
+	sw.send("message_start", map[string]interface{}{
+		"type": "message_start",
+		"message": map[string]interface{}{
+			"id":            messageID,
+			"type":          "message",
+			"role":          "assistant",
+			"model":         request.Model,
+			"content":       []interface{}{},
+			"stop_reason":   nil,
+			"stop_sequence": nil,
+			"usage":         map[string]interface{}{"input_tokens": 0, "output_tokens": 0},
+		},
+	})
+	sw.send("ping", map[string]interface{}{"type": "ping"})
+
+	finishReason := ""
+	var usageMetadata map[string]interface{}
+
+	for chunk := range streamChan {
+		var geminiChunk map[string]interface{}
+		if err := json.Unmarshal([]byte(chunk), &geminiChunk); err != nil {
+			continue
+		}
+
+		// Abort the stream on error chunks
+		if errObj, ok := geminiChunk["error"].(map[string]interface{}); ok {
+			message, _ := errObj["message"].(string)
+			sw.send("error", map[string]interface{}{
+				"type": "error",
+				"error": map[string]interface{}{
+					"type":    "api_error",
+					"message": message,
+				},
+			})
+			return
+		}
+
+		if metadata, ok := geminiChunk["usageMetadata"].(map[string]interface{}); ok {
+			usageMetadata = metadata
+		}
+
+		// Anthropic messages carry a single response; use the first candidate
+		candidates, _ := geminiChunk["candidates"].([]interface{})
+		if len(candidates) == 0 {
+			continue
+		}
+		candMap, _ := candidates[0].(map[string]interface{})
+		content, _ := candMap["content"].(map[string]interface{})
+		parts, _ := content["parts"].([]interface{})
+
+		for _, block := range transformers.GeminiPartsToAnthropicBlocks(parts, includeThinking) {
+			sw.writeBlock(block)
+		}
+
+		if reason, ok := candMap["finishReason"].(string); ok && reason != "" {
+			finishReason = reason
+		}
+	}
+
+	sw.closeBlock()
+
+	sw.send("message_delta", map[string]interface{}{
+		"type": "message_delta",
+		"delta": map[string]interface{}{
+			"stop_reason":   transformers.MapFinishReasonToAnthropic(finishReason, sw.hasToolUse),
+			"stop_sequence": nil,
+		},
+		"usage": transformers.AnthropicUsage(usageMetadata),
+	})
+	sw.send("message_stop", map[string]interface{}{"type": "message_stop"})
+
+	log.Printf("Completed streaming messages response: %s", messageID)
+}
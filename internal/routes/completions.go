@@ -0,0 +1,333 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"gcli2apigo/internal/apikeys"
+	"gcli2apigo/internal/auth"
+	"gcli2apigo/internal/client"
+	"gcli2apigo/internal/metrics"
+	"gcli2apigo/internal/models"
+	"gcli2apigo/internal/transformers"
+
+	"github.com/google/uuid"
+)
+
+// maxParallelPrompts limits how many prompts of one completion request are sent upstream at once
+const maxParallelPrompts = 4
+
+// HandleCompletions handles the legacy OpenAI-compatible text completions endpoint
+func HandleCompletions(w http.ResponseWriter, r *http.Request) {
+	// Authenticate user
+	identity, err := auth.AuthenticateUser(r)
+	if err != nil {
+		writeOpenAIError(w, http.StatusUnauthorized, "invalid_request_error", "Invalid authentication credentials")
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeOpenAIError(w, http.StatusMethodNotAllowed, "invalid_request_error", "Method not allowed")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "Failed to read request body")
+		return
+	}
+
+	var request models.OpenAICompletionRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "Invalid JSON in request body")
+		return
+	}
+
+	if request.Model == "" {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "model: Field required")
+		return
+	}
+	prompts, err := transformers.CompletionPrompts(request.Prompt)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	log.Printf("OpenAI completion request: model=%s, prompts=%d, stream=%v", request.Model, len(prompts), request.Stream)
+
+	// Resolve aliases and skip to a fallback model when the pool's quota is exhausted
+	route := resolveModelRoute(identity, request.Model, false)
+	request.Model = route.Model
+	metrics.SetModel(r, request.Model)
+
+	// Enforce named API key model restrictions and daily quotas
+	apiKeyID, err := apikeys.GetKeyStore().Authorize(identity, request.Model)
+	if err != nil {
+		writeAPIKeyError(w, err)
+		return
+	}
+
+	// Each prompt is a separate upstream request
+	for _, prompt := range prompts {
+		if _, err := transformers.CompletionRequestToGemini(&request, prompt); err != nil {
+			writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+			return
+		}
+	}
+
+	// Build the payload of a prompt for Google API. A fallback model gets its own payload, since
+	// the thinking budget depends on the model.
+	buildPayload := func(model string, prompt string) map[string]interface{} {
+		modelRequest := request
+		modelRequest.Model = model
+		geminiRequestData, _ := transformers.CompletionRequestToGemini(&modelRequest, prompt)
+		geminiPayload := client.BuildGeminiPayloadFromOpenAI(geminiRequestData)
+		if apiKeyID != "" {
+			geminiPayload[client.APIKeyIDField] = apiKeyID
+		}
+		return geminiPayload
+	}
+
+	if request.Stream {
+		handleStreamingCompletion(w, r, &request, &route, prompts, buildPayload)
+	} else {
+		handleNonStreamingCompletion(w, r, &request, &route, prompts, buildPayload)
+	}
+}
+
+// sendPromptsWithFallback sends the prompts in parallel and resends those whose credentials were
+// all rate limited with the route's next fallback model. route.Model is left at the last model tried.
+func sendPromptsWithFallback(ctx context.Context, route *modelRoute, prompts []string, buildPayload func(model string, prompt string) map[string]interface{}) []client.GeminiRequestResult {
+	results := make([]client.GeminiRequestResult, len(prompts))
+	pending := make([]int, len(prompts))
+	for i := range prompts {
+		pending[i] = i
+	}
+
+	for {
+		payloads := make([]map[string]interface{}, 0, len(pending))
+		for _, i := range pending {
+			payloads = append(payloads, buildPayload(route.Model, prompts[i]))
+		}
+
+		rateLimited := make([]int, 0)
+		for j, result := range client.SendGeminiRequestsParallelWithLimit(ctx, payloads, false, maxParallelPrompts) {
+			result.Index = pending[j]
+			results[pending[j]] = result
+			if errors.Is(result.Error, client.ErrRateLimited) {
+				rateLimited = append(rateLimited, pending[j])
+			}
+		}
+		if len(rateLimited) == 0 || len(route.Fallbacks) == 0 {
+			return results
+		}
+
+		log.Printf("[INFO] Model %s is rate limited, falling back to %s", route.Model, route.Fallbacks[0])
+		route.Model, route.Fallbacks = route.Fallbacks[0], route.Fallbacks[1:]
+		pending = rateLimited
+	}
+}
+
+// completionsPerPrompt returns n, the number of choices generated for each prompt
+func completionsPerPrompt(request *models.OpenAICompletionRequest) int {
+	if request.N != nil && *request.N > 1 {
+		return *request.N
+	}
+	return 1
+}
+
+func handleNonStreamingCompletion(w http.ResponseWriter, r *http.Request, request *models.OpenAICompletionRequest, route *modelRoute, prompts []string, buildPayload func(model string, prompt string) map[string]interface{}) {
+	results := sendPromptsWithFallback(r.Context(), route, prompts, buildPayload)
+	request.Model = route.Model
+	reportServedModel(w, r, route)
+
+	n := completionsPerPrompt(request)
+	choices := make([]map[string]interface{}, 0, len(prompts)*n)
+	usageMetadatas := make([]map[string]interface{}, 0, len(prompts))
+
+	for i, result := range results {
+		if result.Error != nil {
+			writeOpenAIError(w, http.StatusInternalServerError, "api_error", fmt.Sprintf("Request failed: %v", result.Error))
+			return
+		}
+
+		geminiResponse, ok := result.Response.(map[string]interface{})
+		if !ok {
+			writeOpenAIError(w, http.StatusInternalServerError, "api_error", "Invalid response from API")
+			return
+		}
+
+		// Check for error in response
+		if errObj, ok := geminiResponse["error"]; ok {
+			status := http.StatusInternalServerError
+			if errMap, ok := errObj.(map[string]interface{}); ok {
+				if code, ok := errMap["code"].(float64); ok {
+					status = int(code)
+				}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": errObj})
+			return
+		}
+
+		echo := ""
+		if request.Echo {
+			echo = prompts[i]
+		}
+		choices = append(choices, transformers.GeminiResponseToCompletionChoices(geminiResponse, i*n, echo)...)
+
+		if usageMetadata, ok := geminiResponse["usageMetadata"].(map[string]interface{}); ok {
+			usageMetadatas = append(usageMetadatas, usageMetadata)
+		}
+	}
+
+	response := transformers.NewCompletionResponse("cmpl-"+uuid.New().String(), request.Model, choices)
+	response["usage"] = transformers.CompletionUsage(usageMetadatas)
+
+	log.Printf("Successfully processed non-streaming completion response for model: %s", request.Model)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleStreamingCompletion streams the prompts one after another; each choice keeps its own index
+func handleStreamingCompletion(w http.ResponseWriter, r *http.Request, request *models.OpenAICompletionRequest, route *modelRoute, prompts []string, buildPayload func(model string, prompt string) map[string]interface{}) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeOpenAIError(w, http.StatusInternalServerError, "api_error", "Streaming not supported")
+		return
+	}
+
+	// Once a prompt falls back, the remaining prompts start at the fallback model
+	sendPrompt := func(prompt string) (interface{}, error) {
+		result, err := sendWithFallback(r.Context(), route, true, func(model string) map[string]interface{} {
+			return buildPayload(model, prompt)
+		})
+		request.Model = route.Model
+		return result, err
+	}
+
+	// Send the first request before writing SSE headers so upstream failures keep a proper status code
+	result, err := sendPrompt(prompts[0])
+	reportServedModel(w, r, route)
+	if err != nil {
+		writeOpenAIError(w, http.StatusInternalServerError, "api_error", fmt.Sprintf("Request failed: %v", err))
+		return
+	}
+	firstStream, ok := result.(chan string)
+	if !ok {
+		writeOpenAIError(w, http.StatusInternalServerError, "api_error", "Streaming request failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	responseID := "cmpl-" + uuid.New().String()
+	log.Printf("Starting streaming completion response: %s", responseID)
+
+	sendData := func(data interface{}) {
+		jsonData, _ := json.Marshal(data)
+		fmt.Fprintf(w, "data: %s\n\n", string(jsonData))
+		flusher.Flush()
+	}
+	sendError := func(message string) {
+		sendData(map[string]interface{}{
+			"error": map[string]interface{}{
+				"message": message,
+				"type":    "api_error",
+				"code":    500,
+			},
+		})
+		fmt.Fprintf(w, "data: [DONE]\n\n")
+		flusher.Flush()
+	}
+
+	n := completionsPerPrompt(request)
+	usageMetadatas := make([]map[string]interface{}, 0, len(prompts))
+
+	for i, prompt := range prompts {
+		streamChan := firstStream
+		if i > 0 {
+			result, err := sendPrompt(prompt)
+			if err != nil {
+				sendError(fmt.Sprintf("Request failed: %v", err))
+				return
+			}
+			if streamChan, ok = result.(chan string); !ok {
+				sendError("Streaming request failed")
+				return
+			}
+		}
+
+		if request.Echo {
+			echoChoices := make([]map[string]interface{}, 0, n)
+			for j := 0; j < n; j++ {
+				echoChoices = append(echoChoices, map[string]interface{}{
+					"text":          prompts[i],
+					"index":         i*n + j,
+					"logprobs":      nil,
+					"finish_reason": nil,
+				})
+			}
+			sendData(transformers.NewCompletionResponse(responseID, request.Model, echoChoices))
+		}
+
+		// Streamed usageMetadata is cumulative, so the last one seen is the total for this prompt
+		var usageMetadata map[string]interface{}
+
+		for chunk := range streamChan {
+			var geminiChunk map[string]interface{}
+			if err := json.Unmarshal([]byte(chunk), &geminiChunk); err != nil {
+				continue
+			}
+
+			// Abort the stream on error chunks
+			if errObj, ok := geminiChunk["error"]; ok {
+				sendData(map[string]interface{}{"error": errObj})
+				fmt.Fprintf(w, "data: [DONE]\n\n")
+				flusher.Flush()
+				return
+			}
+
+			if metadata, ok := geminiChunk["usageMetadata"].(map[string]interface{}); ok {
+				usageMetadata = metadata
+			}
+
+			// Skip chunks that carry neither text nor a finish reason, e.g. thinking-only chunks
+			choices := make([]map[string]interface{}, 0, n)
+			for _, choice := range transformers.GeminiResponseToCompletionChoices(geminiChunk, i*n, "") {
+				if choice["text"] != "" || choice["finish_reason"] != nil {
+					choices = append(choices, choice)
+				}
+			}
+			if len(choices) > 0 {
+				sendData(transformers.NewCompletionResponse(responseID, request.Model, choices))
+			}
+		}
+
+		if usageMetadata != nil {
+			usageMetadatas = append(usageMetadatas, usageMetadata)
+		}
+	}
+
+	// Send the usage chunk when requested via stream_options.include_usage
+	if request.StreamOptions != nil && request.StreamOptions.IncludeUsage {
+		usageChunk := transformers.NewCompletionResponse(responseID, request.Model, []map[string]interface{}{})
+		usageChunk["usage"] = transformers.CompletionUsage(usageMetadatas)
+		sendData(usageChunk)
+	}
+
+	// Send the final [DONE] marker
+	fmt.Fprintf(w, "data: [DONE]\n\n")
+	flusher.Flush()
+	log.Printf("Completed streaming completion response: %s", responseID)
+}
@@ -0,0 +1,110 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"gcli2apigo/internal/apikeys"
+	"gcli2apigo/internal/auth"
+	"gcli2apigo/internal/client"
+	"gcli2apigo/internal/config"
+	"gcli2apigo/internal/metrics"
+	"gcli2apigo/internal/models"
+	"gcli2apigo/internal/transformers"
+)
+
+// HandleChatCompletionsCount counts the prompt tokens of a chat completion request without generating
+// a completion. The body is the same as for /v1/chat/completions; the messages and tools are counted.
+func HandleChatCompletionsCount(w http.ResponseWriter, r *http.Request) {
+	// Authenticate user
+	identity, err := auth.AuthenticateUser(r)
+	if err != nil {
+		writeOpenAIError(w, http.StatusUnauthorized, "invalid_request_error", "Invalid authentication credentials")
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeOpenAIError(w, http.StatusMethodNotAllowed, "invalid_request_error", "Method not allowed")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "Failed to read request body")
+		return
+	}
+
+	var request models.OpenAIChatCompletionRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "Invalid JSON in request body")
+		return
+	}
+
+	if request.Model == "" {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "model: Field required")
+		return
+	}
+
+	// Fake streaming variants count the same as their base model
+	modelName := strings.TrimSuffix(request.Model, "-fake")
+	modelName = strings.TrimPrefix(modelName, "假流式/")
+	modelName, _ = config.GetModelRegistry().ResolveAlias(modelName)
+	request.Model = modelName
+
+	log.Printf("OpenAI token count request: model=%s, messages=%d", modelName, len(request.Messages))
+	metrics.SetModel(r, modelName)
+
+	// Enforce named API key model restrictions; counting is not charged against quotas
+	if _, err := apikeys.GetKeyStore().Authorize(identity, modelName); err != nil {
+		writeAPIKeyError(w, err)
+		return
+	}
+
+	// The output format does not affect the prompt, so it is not validated here
+	request.ResponseFormat = nil
+	geminiRequestData, err := transformers.OpenAIRequestToGemini(&request)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	countRequest, estimated, err := transformers.GeminiCountTokensRequest(geminiRequestData, modelName)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "messages: must not be empty")
+		return
+	}
+
+	geminiResponse, err := client.SendGeminiCountTokensRequest(r.Context(), map[string]interface{}{
+		"model":   modelName,
+		"request": countRequest,
+	})
+	if err != nil {
+		writeOpenAIError(w, http.StatusInternalServerError, "api_error", fmt.Sprintf("Request failed: %v", err))
+		return
+	}
+
+	if errObj, ok := geminiResponse["error"].(map[string]interface{}); ok {
+		status := http.StatusInternalServerError
+		if code, ok := errObj["code"].(float64); ok {
+			status = int(code)
+		}
+		message, _ := errObj["message"].(string)
+		writeOpenAIError(w, status, "api_error", message)
+		return
+	}
+
+	totalTokens, _ := geminiResponse["totalTokens"].(float64)
+
+	// Tools are folded into the first turn, so their share is an estimate
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"object":        "chat.completion.token_count",
+		"model":         modelName,
+		"prompt_tokens": int(totalTokens),
+		"estimated":     estimated,
+	})
+}
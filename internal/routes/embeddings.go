@@ -0,0 +1,135 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"gcli2apigo/internal/apikeys"
+	"gcli2apigo/internal/auth"
+	"gcli2apigo/internal/client"
+	"gcli2apigo/internal/metrics"
+	"gcli2apigo/internal/models"
+	"gcli2apigo/internal/transformers"
+)
+
+// HandleEmbeddings handles OpenAI-compatible embeddings endpoint
+func HandleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	// Authenticate user
+	identity, err := auth.AuthenticateUser(r)
+	if err != nil {
+		writeOpenAIError(w, http.StatusUnauthorized, "invalid_request_error", "Invalid authentication credentials")
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeOpenAIError(w, http.StatusMethodNotAllowed, "invalid_request_error", "Method not allowed")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "Failed to read request body")
+		return
+	}
+
+	var request models.OpenAIEmbeddingRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "Invalid JSON in request body")
+		return
+	}
+
+	texts, err := transformers.OpenAIEmbeddingInputs(request.Input)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	if request.EncodingFormat != "" && request.EncodingFormat != "float" && request.EncodingFormat != "base64" {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("Unsupported encoding_format: %s", request.EncodingFormat))
+		return
+	}
+
+	modelName := transformers.ResolveEmbeddingModel(request.Model)
+	log.Printf("OpenAI embeddings request: model=%s (upstream %s), inputs=%d", request.Model, modelName, len(texts))
+
+	metrics.SetModel(r, modelName)
+
+	// Enforce named API key model restrictions and daily quotas
+	apiKeyID, err := apikeys.GetKeyStore().Authorize(identity, modelName)
+	if err != nil {
+		writeAPIKeyError(w, err)
+		return
+	}
+
+	action, geminiRequest := transformers.OpenAIEmbeddingRequestToGemini(&request, modelName, texts)
+	geminiPayload := map[string]interface{}{
+		"model":   modelName,
+		"request": geminiRequest,
+	}
+	if apiKeyID != "" {
+		geminiPayload[client.APIKeyIDField] = apiKeyID
+	}
+
+	geminiResponse, err := client.SendGeminiEmbedRequest(r.Context(), geminiPayload, action)
+	if err != nil {
+		writeOpenAIError(w, http.StatusInternalServerError, "api_error", fmt.Sprintf("Request failed: %v", err))
+		return
+	}
+
+	// Check for error in response
+	if errObj, ok := geminiResponse["error"]; ok {
+		status := http.StatusInternalServerError
+		if errMap, ok := errObj.(map[string]interface{}); ok {
+			if code, ok := errMap["code"].(float64); ok {
+				status = int(code)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": errObj})
+		return
+	}
+
+	responseModel := request.Model
+	if responseModel == "" {
+		responseModel = modelName
+	}
+	openaiResponse, err := transformers.GeminiEmbeddingsToOpenAI(geminiResponse, responseModel, texts, request.EncodingFormat)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadGateway, "api_error", fmt.Sprintf("Invalid embedding response from API: %v", err))
+		return
+	}
+
+	log.Printf("Successfully processed embeddings request for model: %s", modelName)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(openaiResponse)
+}
+
+// writeAPIKeyError writes an OpenAI-format error for a rejected named API key
+func writeAPIKeyError(w http.ResponseWriter, err error) {
+	status := apikeys.HTTPStatus(err)
+	errorType := "invalid_request_error"
+	switch status {
+	case http.StatusForbidden:
+		errorType = "permission_error"
+	case http.StatusTooManyRequests:
+		errorType = "insufficient_quota"
+	}
+	writeOpenAIError(w, status, errorType, err.Error())
+}
+
+// writeOpenAIError writes an error in the OpenAI error format
+func writeOpenAIError(w http.ResponseWriter, status int, errorType string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"type":    errorType,
+			"code":    status,
+		},
+	})
+}
@@ -1,341 +1,564 @@
-package routes
-
-import (
-	"encoding/json"
-	"fmt"
-	"io"
-	"log"
-	"net/http"
-	"strings"
-	"time"
-
-	"gcli2apigo/internal/auth"
-	"gcli2apigo/internal/client"
-	"gcli2apigo/internal/config"
-)
-
-// HandleGeminiListModels handles native Gemini models endpoint
-func HandleGeminiListModels(w http.ResponseWriter, r *http.Request) {
-	// Authenticate user
-	if _, err := auth.AuthenticateUser(r); err != nil {
-		http.Error(w, `{"error":{"message":"Invalid authentication credentials","code":401}}`, http.StatusUnauthorized)
-		return
-	}
-
-	if r.Method != http.MethodGet {
-		http.Error(w, `{"error":{"message":"Method not allowed","code":405}}`, http.StatusMethodNotAllowed)
-		return
-	}
-
-	log.Println("Gemini models list requested")
-
-	// Build models list including fake streaming variants
-	allModels := make([]config.Model, 0, len(config.SupportedModels)*2)
-
-	// Add base models
-	allModels = append(allModels, config.SupportedModels...)
-
-	// Add fake streaming variants for supported models
-	for _, model := range config.SupportedModels {
-		modelID := strings.TrimPrefix(model.Name, "models/")
-		if isFakeStreamingAllowed(modelID) {
-			fakeModelName := config.GetFakeModelName(modelID)
-			fakeModel := model
-			fakeModel.Name = "models/" + fakeModelName
-			fakeModel.DisplayName = fakeModel.DisplayName + " (Fake Streaming)"
-			fakeModel.Description = fakeModel.Description + " - Fake streaming variant"
-			allModels = append(allModels, fakeModel)
-		}
-	}
-
-	modelsResponse := map[string]interface{}{
-		"models": allModels,
-	}
-
-	log.Printf("Returning %d Gemini models (including fake streaming variants)", len(allModels))
-
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	json.NewEncoder(w).Encode(modelsResponse)
-}
-
-// HandleGeminiListModelsV1 handles alternative models endpoint for v1 API version
-func HandleGeminiListModelsV1(w http.ResponseWriter, r *http.Request) {
-	if r.URL.Path == "/v1/models" {
-		HandleGeminiListModels(w, r)
-	} else {
-		http.NotFound(w, r)
-	}
-}
-
-// HandleGeminiProxy handles native Gemini API proxy endpoint
-func HandleGeminiProxy(w http.ResponseWriter, r *http.Request) {
-	// Skip if this is a known route
-	if r.URL.Path == "/" || r.URL.Path == "/health" ||
-		strings.HasPrefix(r.URL.Path, "/v1/chat/completions") ||
-		(r.URL.Path == "/v1/models" && r.Method == http.MethodGet) ||
-		(r.URL.Path == "/v1beta/models" && r.Method == http.MethodGet) {
-		http.NotFound(w, r)
-		return
-	}
-
-	// Only handle Gemini API paths
-	if !strings.HasPrefix(r.URL.Path, "/v1beta/") && !strings.HasPrefix(r.URL.Path, "/v1/") {
-		http.NotFound(w, r)
-		return
-	}
-
-	// Authenticate user
-	if _, err := auth.AuthenticateUser(r); err != nil {
-		http.Error(w, `{"error":{"message":"Invalid authentication credentials","code":401}}`, http.StatusUnauthorized)
-		return
-	}
-
-	// Get the request body
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, `{"error":{"message":"Failed to read request body","code":400}}`, http.StatusBadRequest)
-		return
-	}
-
-	// Determine if this is a streaming request
-	isStreaming := strings.Contains(strings.ToLower(r.URL.Path), "stream")
-
-	// Extract model name from the path
-	modelName := extractModelFromPath(r.URL.Path)
-
-	log.Printf("Gemini proxy request: path=%s, model=%s, stream=%v", r.URL.Path, modelName, isStreaming)
-
-	if modelName == "" {
-		log.Printf("Could not extract model name from path: %s", r.URL.Path)
-		http.Error(w, fmt.Sprintf(`{"error":{"message":"Could not extract model name from path: %s","code":400}}`, r.URL.Path), http.StatusBadRequest)
-		return
-	}
-
-	// Detect and handle fake stream mode based on language setting
-	isFakeStream := false
-
-	// Check for English format: modelID-fake
-	if strings.HasSuffix(modelName, "-fake") {
-		isFakeStream = true
-		modelName = strings.TrimSuffix(modelName, "-fake")
-	} else if strings.HasPrefix(modelName, "假流式/") {
-		// Check for Chinese format: 假流式/modelID
-		isFakeStream = true
-		modelName = strings.TrimPrefix(modelName, "假流式/")
-	}
-
-	if isFakeStream {
-		log.Printf("Detected fake stream mode in Gemini proxy, stripped model name: %s", modelName)
-
-		// Validate that fake streaming is only allowed for specific models
-		if !isFakeStreamingAllowed(modelName) {
-			errorData := map[string]interface{}{
-				"error": map[string]interface{}{
-					"message": fmt.Sprintf("Fake streaming is not supported for model: %s. Only gemini-2.5-pro (and preview models) and gemini flash models (excluding gemini-flash-image) support fake streaming.", modelName),
-					"code":    400,
-				},
-			}
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(errorData)
-			return
-		}
-
-		// Force streaming mode for fake stream
-		isStreaming = true
-	}
-
-	// Parse the incoming request
-	var incomingRequest map[string]interface{}
-	if len(body) > 0 {
-		if err := json.Unmarshal(body, &incomingRequest); err != nil {
-			log.Printf("Invalid JSON in request body: %v", err)
-			http.Error(w, `{"error":{"message":"Invalid JSON in request body","code":400}}`, http.StatusBadRequest)
-			return
-		}
-	} else {
-		incomingRequest = make(map[string]interface{})
-	}
-
-	// Build the payload for Google API
-	geminiPayload := client.BuildGeminiPayloadFromNative(incomingRequest, modelName)
-
-	// Send the request to Google API
-	result, err := client.SendGeminiRequest(geminiPayload, isStreaming)
-	if err != nil {
-		log.Printf("Gemini proxy error: %v", err)
-		errorData := map[string]interface{}{
-			"error": map[string]interface{}{
-				"message": fmt.Sprintf("Proxy error: %v", err),
-				"code":    500,
-			},
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errorData)
-		return
-	}
-
-	if isStreaming {
-		handleGeminiStreamingResponse(w, result)
-	} else {
-		handleGeminiNonStreamingResponse(w, result, modelName)
-	}
-}
-
-func handleGeminiStreamingResponse(w http.ResponseWriter, result interface{}) {
-	streamChan, ok := result.(chan string)
-	if !ok {
-		errorData := map[string]interface{}{
-			"error": map[string]interface{}{
-				"message": "Streaming request failed",
-				"code":    500,
-			},
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errorData)
-		return
-	}
-
-	// Set headers for SSE
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Content-Disposition", "attachment")
-	w.Header().Set("Vary", "Origin, X-Origin, Referer")
-	w.Header().Set("X-XSS-Protection", "0")
-	w.Header().Set("X-Frame-Options", "SAMEORIGIN")
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.Header().Set("X-Accel-Buffering", "no")
-	w.Header().Set("Server", "ESF")
-
-	flusher, ok := w.(http.Flusher)
-	if !ok {
-		http.Error(w, `{"error":{"message":"Streaming not supported","code":500}}`, http.StatusInternalServerError)
-		return
-	}
-
-	// Smart buffering: accumulate chunks and flush on sentence boundaries or time
-	var chunkAccumulator strings.Builder
-	chunkAccumulator.Grow(8 * 1024) // Pre-allocate 8KB
-
-	lastFlushTime := time.Now()
-	flushInterval := 50 * time.Millisecond
-
-	// Sentence boundary detection
-	isSentenceBoundary := func(text string) bool {
-		if len(text) == 0 {
-			return false
-		}
-		// Check last rune for sentence boundaries (supports Unicode)
-		runes := []rune(text)
-		lastRune := runes[len(runes)-1]
-		return lastRune == '.' || lastRune == '!' || lastRune == '?' ||
-			lastRune == '。' || lastRune == '！' || lastRune == '？' ||
-			lastRune == '\n'
-	}
-
-	sendAccumulatedChunk := func() {
-		if chunkAccumulator.Len() == 0 {
-			return
-		}
-
-		fmt.Fprintf(w, "data: %s\n\n", chunkAccumulator.String())
-		flusher.Flush()
-
-		chunkAccumulator.Reset()
-		lastFlushTime = time.Now()
-	}
-
-	for chunk := range streamChan {
-		chunkAccumulator.WriteString(chunk)
-
-		// Extract text to check for sentence boundaries
-		var geminiChunk map[string]interface{}
-		if err := json.Unmarshal([]byte(chunk), &geminiChunk); err == nil {
-			candidates, _ := geminiChunk["candidates"].([]interface{})
-			for _, candidate := range candidates {
-				candMap, _ := candidate.(map[string]interface{})
-				content, _ := candMap["content"].(map[string]interface{})
-				parts, _ := content["parts"].([]interface{})
-
-				var textContent string
-				for _, part := range parts {
-					partMap, _ := part.(map[string]interface{})
-					if text, ok := partMap["text"].(string); ok {
-						textContent += text
-					}
-				}
-
-				// Flush conditions:
-				// 1. Sentence boundary detected
-				// 2. Time interval exceeded (50ms)
-				// 3. Buffer size exceeded (8KB safety limit)
-				timeSinceFlush := time.Since(lastFlushTime)
-
-				if isSentenceBoundary(textContent) ||
-					timeSinceFlush >= flushInterval ||
-					chunkAccumulator.Len() >= 8*1024 {
-					sendAccumulatedChunk()
-					return
-				}
-			}
-		}
-	}
-
-	sendAccumulatedChunk() // Final flush
-}
-
-func handleGeminiNonStreamingResponse(w http.ResponseWriter, result interface{}, modelName string) {
-	geminiResponse, ok := result.(map[string]interface{})
-	if !ok {
-		errorData := map[string]interface{}{
-			"error": map[string]interface{}{
-				"message": "Invalid response from API",
-				"code":    500,
-			},
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errorData)
-		return
-	}
-
-	// Check for error in response
-	if errObj, ok := geminiResponse["error"]; ok {
-		w.Header().Set("Content-Type", "application/json")
-		if errMap, ok := errObj.(map[string]interface{}); ok {
-			if code, ok := errMap["code"].(float64); ok {
-				w.WriteHeader(int(code))
-			} else {
-				w.WriteHeader(http.StatusInternalServerError)
-			}
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-		json.NewEncoder(w).Encode(map[string]interface{}{"error": errObj})
-		return
-	}
-
-	log.Printf("Successfully processed Gemini request for model: %s", modelName)
-
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	json.NewEncoder(w).Encode(geminiResponse)
-}
-
-func extractModelFromPath(path string) string {
-	parts := strings.Split(path, "/")
-
-	// Look for the pattern: .../models/{model_name}/...
-	for i, part := range parts {
-		if part == "models" && i+1 < len(parts) {
-			modelName := parts[i+1]
-			// Remove any action suffix like ":streamGenerateContent" or ":generateContent"
-			if idx := strings.Index(modelName, ":"); idx != -1 {
-				modelName = modelName[:idx]
-			}
-			return modelName
-		}
-	}
-
-	return ""
-}
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"gcli2apigo/internal/apikeys"
+	"gcli2apigo/internal/auth"
+	"gcli2apigo/internal/client"
+	"gcli2apigo/internal/config"
+	"gcli2apigo/internal/metrics"
+	"gcli2apigo/internal/transformers"
+)
+
+// HandleGeminiListModels handles native Gemini models endpoint
+func HandleGeminiListModels(w http.ResponseWriter, r *http.Request) {
+	// Authenticate user
+	if _, err := auth.AuthenticateUser(r); err != nil {
+		http.Error(w, `{"error":{"message":"Invalid authentication credentials","code":401}}`, http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":{"message":"Method not allowed","code":405}}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	log.Println("Gemini models list requested")
+
+	allModels := geminiModelList()
+	modelsResponse := map[string]interface{}{
+		"models": allModels,
+	}
+
+	log.Printf("Returning %d Gemini models (including fake streaming variants)", len(allModels))
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(modelsResponse)
+}
+
+// geminiModelList returns the supported models followed by their fake streaming variants
+func geminiModelList() []config.Model {
+	models := config.GetModelRegistry().Models()
+	allModels := make([]config.Model, 0, len(models)*2)
+
+	// Add base models
+	allModels = append(allModels, models...)
+
+	// Add fake streaming variants for supported models
+	for _, model := range models {
+		modelID := strings.TrimPrefix(model.Name, "models/")
+		if isFakeStreamingAllowed(modelID) {
+			fakeModelName := config.GetFakeModelName(modelID)
+			fakeModel := model
+			fakeModel.Name = "models/" + fakeModelName
+			fakeModel.DisplayName = fakeModel.DisplayName + " (Fake Streaming)"
+			fakeModel.Description = fakeModel.Description + " - Fake streaming variant"
+			allModels = append(allModels, fakeModel)
+		}
+	}
+
+	return allModels
+}
+
+// HandleGeminiListModelsV1 handles alternative models endpoint for v1 API version
+func HandleGeminiListModelsV1(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/v1/models" {
+		HandleGeminiListModels(w, r)
+	} else {
+		http.NotFound(w, r)
+	}
+}
+
+// Native model actions served by HandleGeminiProxy
+const (
+	actionGenerateContent       = "generateContent"
+	actionStreamGenerateContent = "streamGenerateContent"
+	actionCountTokens           = "countTokens"
+	actionEmbedContent          = "embedContent"
+	actionBatchEmbedContents    = "batchEmbedContents"
+)
+
+// unsupportedGeminiActions are Gemini API model actions that have no Code Assist equivalent.
+// They are rejected with 400 instead of the 404 returned for unknown actions.
+var unsupportedGeminiActions = map[string]bool{
+	"batchGenerateContent":   true,
+	"asyncBatchEmbedContent": true,
+	"bidiGenerateContent":    true,
+	"predict":                true,
+	"predictLongRunning":     true,
+	"generateAnswer":         true,
+	"generateMessage":        true,
+	"generateText":           true,
+	"countMessageTokens":     true,
+	"countTextTokens":        true,
+	"embedText":              true,
+	"batchEmbedText":         true,
+}
+
+// HandleGeminiProxy handles native Gemini API proxy endpoint.
+// Paths have the form /{version}/models/{model}:{action}; GET /{version}/models/{model} returns the model.
+func HandleGeminiProxy(w http.ResponseWriter, r *http.Request) {
+	// Skip if this is a known route
+	if r.URL.Path == "/" || r.URL.Path == "/health" ||
+		strings.HasPrefix(r.URL.Path, "/v1/chat/completions") ||
+		(r.URL.Path == "/v1/models" && r.Method == http.MethodGet) ||
+		(r.URL.Path == "/v1beta/models" && r.Method == http.MethodGet) {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Only handle Gemini API paths
+	if !strings.HasPrefix(r.URL.Path, "/v1beta/") && !strings.HasPrefix(r.URL.Path, "/v1/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Authenticate user
+	identity, err := auth.AuthenticateUser(r)
+	if err != nil {
+		writeGeminiError(w, http.StatusUnauthorized, "Invalid authentication credentials")
+		return
+	}
+
+	modelName, action, ok := parseGeminiModelPath(r.URL.Path)
+	if !ok {
+		writeGeminiError(w, http.StatusNotFound, fmt.Sprintf("Not found: %s", r.URL.Path))
+		return
+	}
+
+	if action == "" {
+		if r.Method != http.MethodGet {
+			writeGeminiError(w, http.StatusNotFound, fmt.Sprintf("Not found: %s %s", r.Method, r.URL.Path))
+			return
+		}
+		handleGeminiGetModel(w, modelName)
+		return
+	}
+
+	switch action {
+	case actionGenerateContent, actionStreamGenerateContent, actionCountTokens, actionEmbedContent, actionBatchEmbedContents:
+	default:
+		if unsupportedGeminiActions[action] {
+			writeGeminiError(w, http.StatusBadRequest, fmt.Sprintf("Action %s is not supported by this proxy", action))
+		} else {
+			writeGeminiError(w, http.StatusNotFound, fmt.Sprintf("Unknown action: %s", action))
+		}
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeGeminiError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Get the request body
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeGeminiError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+
+	isStreaming := action == actionStreamGenerateContent
+
+	log.Printf("Gemini proxy request: path=%s, model=%s, action=%s", r.URL.Path, modelName, action)
+	metrics.SetModel(r, modelName)
+
+	if modelName == "" {
+		writeGeminiError(w, http.StatusBadRequest, fmt.Sprintf("Could not extract model name from path: %s", r.URL.Path))
+		return
+	}
+
+	// Detect and handle fake stream mode based on language setting
+	isFakeStream := false
+
+	// Check for English format: modelID-fake
+	if strings.HasSuffix(modelName, "-fake") {
+		isFakeStream = true
+		modelName = strings.TrimSuffix(modelName, "-fake")
+	} else if strings.HasPrefix(modelName, "假流式/") {
+		// Check for Chinese format: 假流式/modelID
+		isFakeStream = true
+		modelName = strings.TrimPrefix(modelName, "假流式/")
+	}
+
+	isGenerateAction := action == actionGenerateContent || action == actionStreamGenerateContent
+
+	// Resolve aliases; only generated content moves to a fallback model when quota runs out
+	var route modelRoute
+	if isGenerateAction {
+		route = resolveModelRoute(identity, modelName, isFakeStream)
+	} else {
+		route.Model, _ = config.GetModelRegistry().ResolveAlias(modelName)
+	}
+	modelName = route.Model
+	metrics.SetModel(r, modelName)
+
+	// Fake streaming only changes how generated content is delivered
+	if isFakeStream && isGenerateAction {
+		log.Printf("Detected fake stream mode in Gemini proxy, stripped model name: %s", modelName)
+
+		// Validate that fake streaming is only allowed for specific models
+		if !isFakeStreamingAllowed(modelName) {
+			writeGeminiError(w, http.StatusBadRequest, fmt.Sprintf("Fake streaming is not supported for model: %s. Fake streaming must be enabled for the model in the model registry.", modelName))
+			return
+		}
+
+		// Force streaming mode for fake stream
+		isStreaming = true
+	}
+
+	// Parse the incoming request
+	var incomingRequest map[string]interface{}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &incomingRequest); err != nil {
+			log.Printf("Invalid JSON in request body: %v", err)
+			writeGeminiError(w, http.StatusBadRequest, "Invalid JSON in request body")
+			return
+		}
+	} else {
+		incomingRequest = make(map[string]interface{})
+	}
+
+	// Enforce named API key model restrictions and daily quotas
+	apiKeyID, err := apikeys.GetKeyStore().Authorize(identity, modelName)
+	if err != nil {
+		writeGeminiError(w, apikeys.HTTPStatus(err), err.Error())
+		return
+	}
+
+	// Embedding and token counting actions bypass the generateContent payload builder
+	switch action {
+	case actionEmbedContent, actionBatchEmbedContents:
+		handleGeminiEmbedRequest(w, r, incomingRequest, modelName, action, apiKeyID)
+		return
+	case actionCountTokens:
+		handleGeminiCountTokensRequest(w, r, incomingRequest, modelName)
+		return
+	}
+
+	// Build the payload for Google API; each fallback model gets its own thinking budget
+	buildPayload := func(model string) map[string]interface{} {
+		geminiPayload := client.BuildGeminiPayloadFromNative(cloneNativeRequest(incomingRequest), model)
+		if apiKeyID != "" {
+			geminiPayload[client.APIKeyIDField] = apiKeyID
+		}
+		return geminiPayload
+	}
+
+	// Send the request to Google API
+	result, err := sendWithFallback(r.Context(), &route, isStreaming, buildPayload)
+	modelName = route.Model
+	metrics.SetModel(r, modelName)
+	w.Header().Set(ServedModelHeader, modelName)
+	if err != nil {
+		log.Printf("Gemini proxy error: %v", err)
+		writeGeminiError(w, http.StatusInternalServerError, fmt.Sprintf("Proxy error: %v", err))
+		return
+	}
+
+	if isStreaming {
+		// Like the Gemini API, streamGenerateContent returns a JSON array unless alt=sse is set.
+		// Fake streaming always uses SSE.
+		useSSE := isFakeStream || r.URL.Query().Get("alt") == "sse"
+		handleGeminiStreamingResponse(w, result, useSSE)
+	} else {
+		handleGeminiNonStreamingResponse(w, result, modelName)
+	}
+}
+
+// handleGeminiGetModel returns a single model, like GET /v1beta/models/{model} of the Gemini API
+func handleGeminiGetModel(w http.ResponseWriter, modelName string) {
+	modelName, _ = config.GetModelRegistry().ResolveAlias(modelName)
+	for _, model := range geminiModelList() {
+		if model.Name == "models/"+modelName {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			json.NewEncoder(w).Encode(model)
+			return
+		}
+	}
+	writeGeminiError(w, http.StatusNotFound, fmt.Sprintf("models/%s is not found", modelName))
+}
+
+// handleGeminiStreamingResponse relays stream chunks either as SSE events or, without alt=sse,
+// as the elements of a JSON array written while the stream progresses
+func handleGeminiStreamingResponse(w http.ResponseWriter, result interface{}, useSSE bool) {
+	streamChan, ok := result.(chan string)
+	if !ok {
+		writeGeminiError(w, http.StatusInternalServerError, "Streaming request failed")
+		return
+	}
+
+	if useSSE {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Content-Disposition", "attachment")
+	} else {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	}
+	w.Header().Set("Vary", "Origin, X-Origin, Referer")
+	w.Header().Set("X-XSS-Protection", "0")
+	w.Header().Set("X-Frame-Options", "SAMEORIGIN")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.Header().Set("Server", "ESF")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeGeminiError(w, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+
+	// Smart buffering: accumulate chunks and flush on sentence boundaries or time
+	var chunkAccumulator strings.Builder
+	chunkAccumulator.Grow(8 * 1024) // Pre-allocate 8KB
+
+	lastFlushTime := time.Now()
+	flushInterval := 50 * time.Millisecond
+
+	// Sentence boundary detection
+	isSentenceBoundary := func(text string) bool {
+		if len(text) == 0 {
+			return false
+		}
+		// Check last rune for sentence boundaries (supports Unicode)
+		runes := []rune(text)
+		lastRune := runes[len(runes)-1]
+		return lastRune == '.' || lastRune == '!' || lastRune == '?' ||
+			lastRune == '。' || lastRune == '！' || lastRune == '？' ||
+			lastRune == '\n'
+	}
+
+	sendAccumulatedChunks := func() {
+		if chunkAccumulator.Len() == 0 {
+			return
+		}
+
+		io.WriteString(w, chunkAccumulator.String())
+		flusher.Flush()
+
+		chunkAccumulator.Reset()
+		lastFlushTime = time.Now()
+	}
+
+	if !useSSE {
+		chunkAccumulator.WriteString("[")
+	}
+
+	first := true
+	for chunk := range streamChan {
+		// Every chunk is a separate SSE event or array element
+		if useSSE {
+			chunkAccumulator.WriteString("data: ")
+			chunkAccumulator.WriteString(chunk)
+			chunkAccumulator.WriteString("\n\n")
+		} else {
+			if !first {
+				chunkAccumulator.WriteString(",\n")
+			}
+			chunkAccumulator.WriteString(chunk)
+		}
+		first = false
+
+		// Extract text to check for sentence boundaries
+		var textContent string
+		var geminiChunk map[string]interface{}
+		if err := json.Unmarshal([]byte(chunk), &geminiChunk); err == nil {
+			candidates, _ := geminiChunk["candidates"].([]interface{})
+			for _, candidate := range candidates {
+				candMap, _ := candidate.(map[string]interface{})
+				content, _ := candMap["content"].(map[string]interface{})
+				parts, _ := content["parts"].([]interface{})
+
+				for _, part := range parts {
+					partMap, _ := part.(map[string]interface{})
+					if text, ok := partMap["text"].(string); ok {
+						textContent += text
+					}
+				}
+			}
+		}
+
+		// Flush conditions:
+		// 1. Sentence boundary detected
+		// 2. Time interval exceeded (50ms)
+		// 3. Buffer size exceeded (8KB safety limit)
+		if isSentenceBoundary(textContent) ||
+			time.Since(lastFlushTime) >= flushInterval ||
+			chunkAccumulator.Len() >= 8*1024 {
+			sendAccumulatedChunks()
+		}
+	}
+
+	if !useSSE {
+		chunkAccumulator.WriteString("]")
+	}
+	sendAccumulatedChunks() // Final flush
+}
+
+func handleGeminiNonStreamingResponse(w http.ResponseWriter, result interface{}, modelName string) {
+	geminiResponse, ok := result.(map[string]interface{})
+	if !ok {
+		errorData := map[string]interface{}{
+			"error": map[string]interface{}{
+				"message": "Invalid response from API",
+				"code":    500,
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(errorData)
+		return
+	}
+
+	// Check for error in response
+	if errObj, ok := geminiResponse["error"]; ok {
+		w.Header().Set("Content-Type", "application/json")
+		if errMap, ok := errObj.(map[string]interface{}); ok {
+			if code, ok := errMap["code"].(float64); ok {
+				w.WriteHeader(int(code))
+			} else {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": errObj})
+		return
+	}
+
+	log.Printf("Successfully processed Gemini request for model: %s", modelName)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(geminiResponse)
+}
+
+// handleGeminiEmbedRequest forwards a native embedContent or batchEmbedContents request through the credential pool
+func handleGeminiEmbedRequest(w http.ResponseWriter, r *http.Request, incomingRequest map[string]interface{}, modelName string, action string, apiKeyID string) {
+	// batchEmbedContents requires a model on every request; default it to the model in the path
+	if requests, ok := incomingRequest["requests"].([]interface{}); ok {
+		for _, request := range requests {
+			if requestMap, ok := request.(map[string]interface{}); ok {
+				if _, hasModel := requestMap["model"]; !hasModel {
+					requestMap["model"] = "models/" + modelName
+				}
+			}
+		}
+	}
+
+	geminiPayload := map[string]interface{}{
+		"model":   modelName,
+		"request": incomingRequest,
+	}
+	if apiKeyID != "" {
+		geminiPayload[client.APIKeyIDField] = apiKeyID
+	}
+
+	result, err := client.SendGeminiEmbedRequest(r.Context(), geminiPayload, action)
+	if err != nil {
+		log.Printf("Gemini proxy error: %v", err)
+		errorData := map[string]interface{}{
+			"error": map[string]interface{}{
+				"message": fmt.Sprintf("Proxy error: %v", err),
+				"code":    500,
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(errorData)
+		return
+	}
+
+	handleGeminiNonStreamingResponse(w, result, modelName)
+}
+
+// handleGeminiCountTokensRequest forwards a native countTokens request through the credential pool
+func handleGeminiCountTokensRequest(w http.ResponseWriter, r *http.Request, incomingRequest map[string]interface{}, modelName string) {
+	countRequest, estimated, err := transformers.GeminiCountTokensRequest(incomingRequest, modelName)
+	if err != nil {
+		errorData := map[string]interface{}{
+			"error": map[string]interface{}{
+				"message": err.Error(),
+				"code":    400,
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorData)
+		return
+	}
+
+	geminiPayload := map[string]interface{}{
+		"model":   modelName,
+		"request": countRequest,
+	}
+
+	result, err := client.SendGeminiCountTokensRequest(r.Context(), geminiPayload)
+	if err != nil {
+		log.Printf("Gemini proxy error: %v", err)
+		errorData := map[string]interface{}{
+			"error": map[string]interface{}{
+				"message": fmt.Sprintf("Proxy error: %v", err),
+				"code":    500,
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(errorData)
+		return
+	}
+
+	// Flag counts where the systemInstruction or tools were folded into the first turn
+	if _, failed := result["error"]; estimated && !failed {
+		result["estimated"] = true
+	}
+
+	handleGeminiNonStreamingResponse(w, result, modelName)
+}
+
+// parseGeminiModelPath splits a native model path such as /v1beta/models/gemini-2.5-pro:generateContent
+// into the model and the action; action is empty for a plain model path. The model may itself
+// contain slashes (e.g. 假流式/gemini-2.5-pro). ok is false when the path does not address a model.
+func parseGeminiModelPath(path string) (model string, action string, ok bool) {
+	var rest string
+	for _, prefix := range []string{"/v1beta/models/", "/v1/models/"} {
+		if after, found := strings.CutPrefix(path, prefix); found {
+			rest = after
+			ok = true
+			break
+		}
+	}
+	if !ok || rest == "" {
+		return "", "", false
+	}
+
+	if idx := strings.LastIndex(rest, ":"); idx != -1 {
+		return rest[:idx], rest[idx+1:], true
+	}
+
+	// Older clients separate the action with a slash, e.g. /v1beta/models/gemini-2.5-pro/generateContent
+	if idx := strings.LastIndex(rest, "/"); idx != -1 {
+		switch rest[idx+1:] {
+		case actionGenerateContent, actionStreamGenerateContent, actionCountTokens, actionEmbedContent, actionBatchEmbedContents:
+			return rest[:idx], rest[idx+1:], true
+		}
+	}
+	return rest, "", true
+}
+
+// writeGeminiError writes an error in the native Gemini API format
+func writeGeminiError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"code":    status,
+		},
+	})
+}
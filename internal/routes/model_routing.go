@@ -0,0 +1,93 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"gcli2apigo/internal/apikeys"
+	"gcli2apigo/internal/auth"
+	"gcli2apigo/internal/client"
+	"gcli2apigo/internal/config"
+	"gcli2apigo/internal/metrics"
+)
+
+// ServedModelHeader reports the registry model that served a request once aliases and
+// fallbacks have been applied
+const ServedModelHeader = "X-Served-Model"
+
+// modelRoute is the model a request is sent to, followed by the models tried in order
+// when the quota of the previous one is exhausted
+type modelRoute struct {
+	Model     string
+	Fallbacks []string
+}
+
+// resolveModelRoute resolves an alias from the model registry and, when the pool has no daily
+// quota left for the model, starts at the first fallback that still has quota. Fallbacks the
+// API key may not use, or that cannot fake stream when fakeStream is set, are left out.
+func resolveModelRoute(identity string, requested string, fakeStream bool) modelRoute {
+	registry := config.GetModelRegistry()
+	model, isAlias := registry.ResolveAlias(requested)
+	if isAlias && config.IsDebugEnabled() {
+		log.Printf("[DEBUG] Resolved model alias %s to %s", requested, model)
+	}
+
+	fallbacks := make([]string, 0)
+	for _, fallback := range registry.Fallbacks(model) {
+		if _, err := apikeys.GetKeyStore().Authorize(identity, fallback); err != nil {
+			continue
+		}
+		if fakeStream && !isFakeStreamingAllowed(fallback) {
+			continue
+		}
+		fallbacks = append(fallbacks, fallback)
+	}
+
+	route := modelRoute{Model: model, Fallbacks: fallbacks}
+	for !auth.HasQuotaForModel(route.Model) && len(route.Fallbacks) > 0 {
+		log.Printf("[INFO] Daily quota exhausted for model %s, falling back to %s", route.Model, route.Fallbacks[0])
+		route.Model, route.Fallbacks = route.Fallbacks[0], route.Fallbacks[1:]
+	}
+	return route
+}
+
+// sendWithFallback sends the payload built for the route's model and moves on to the next
+// fallback while every credential is rate limited. route.Model is left at the model that
+// served the request (or the last one tried).
+func sendWithFallback(ctx context.Context, route *modelRoute, isStreaming bool, buildPayload func(model string) map[string]interface{}) (interface{}, error) {
+	for {
+		result, err := client.SendGeminiRequest(ctx, buildPayload(route.Model), isStreaming)
+		if err == nil || !errors.Is(err, client.ErrRateLimited) || len(route.Fallbacks) == 0 {
+			return result, err
+		}
+
+		log.Printf("[INFO] Model %s is rate limited, falling back to %s", route.Model, route.Fallbacks[0])
+		route.Model, route.Fallbacks = route.Fallbacks[0], route.Fallbacks[1:]
+	}
+}
+
+// reportServedModel sets the X-Served-Model header and the model metric label to the model
+// that served the request, which differs from the requested one for aliases and fallbacks
+func reportServedModel(w http.ResponseWriter, r *http.Request, route *modelRoute) {
+	metrics.SetModel(r, route.Model)
+	w.Header().Set(ServedModelHeader, route.Model)
+}
+
+// cloneNativeRequest copies a native Gemini request deeply enough for BuildGeminiPayloadFromNative,
+// which sets safety settings and a model specific thinking budget on the request it is given
+func cloneNativeRequest(request map[string]interface{}) map[string]interface{} {
+	clone := make(map[string]interface{}, len(request))
+	for key, value := range request {
+		clone[key] = value
+	}
+	if genConfig, ok := request["generationConfig"].(map[string]interface{}); ok {
+		genConfigClone := make(map[string]interface{}, len(genConfig))
+		for key, value := range genConfig {
+			genConfigClone[key] = value
+		}
+		clone["generationConfig"] = genConfigClone
+	}
+	return clone
+}
@@ -0,0 +1,493 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"gcli2apigo/internal/apikeys"
+	"gcli2apigo/internal/auth"
+	"gcli2apigo/internal/client"
+	"gcli2apigo/internal/conversations"
+	"gcli2apigo/internal/metrics"
+	"gcli2apigo/internal/models"
+	"gcli2apigo/internal/transformers"
+)
+
+// HandleResponses handles the OpenAI-compatible Responses endpoint:
+// POST /v1/responses creates a response, GET and DELETE /v1/responses/{id} read and remove a stored one
+func HandleResponses(w http.ResponseWriter, r *http.Request) {
+	// Authenticate user
+	identity, err := auth.AuthenticateUser(r)
+	if err != nil {
+		writeOpenAIError(w, http.StatusUnauthorized, "invalid_request_error", "Invalid authentication credentials")
+		return
+	}
+
+	if responseID := strings.TrimPrefix(r.URL.Path, "/v1/responses/"); responseID != r.URL.Path {
+		handleStoredResponse(w, r, identity, responseID)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeOpenAIError(w, http.StatusMethodNotAllowed, "invalid_request_error", "Method not allowed")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "Failed to read request body")
+		return
+	}
+
+	var request models.ResponsesRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "Invalid JSON in request body")
+		return
+	}
+
+	if request.Model == "" {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "model: Field required")
+		return
+	}
+	inputItems, err := transformers.ResponsesInputItems(request.Input)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	log.Printf("OpenAI responses request: model=%s, stream=%v, previous_response_id=%s", request.Model, request.Stream, request.PreviousResponseID)
+
+	// Resolve aliases and skip to a fallback model when the pool's quota is exhausted
+	route := resolveModelRoute(identity, request.Model, false)
+	request.Model = route.Model
+	metrics.SetModel(r, request.Model)
+
+	// Enforce named API key model restrictions and daily quotas
+	apiKeyID, err := apikeys.GetKeyStore().Authorize(identity, request.Model)
+	if err != nil {
+		writeAPIKeyError(w, err)
+		return
+	}
+
+	// Continue a stored conversation; responses are only visible to the key that created them
+	conversation := inputItems
+	if request.PreviousResponseID != "" {
+		previous, ok := conversations.GetStore().Get(request.PreviousResponseID)
+		if !ok || previous.Owner != apiKeyID {
+			writeOpenAIError(w, http.StatusNotFound, "invalid_request_error",
+				fmt.Sprintf("Previous response with id '%s' not found.", request.PreviousResponseID))
+			return
+		}
+		conversation = slices.Concat(previous.Items, inputItems)
+	}
+
+	// Transform the Responses request to Gemini format
+	if _, err := transformers.ResponsesRequestToGemini(&request, conversation); err != nil {
+		// The schema error names the field at fault: the output format or a tool's parameters
+		param := "text.format"
+		var schemaErr *transformers.SchemaError
+		if errors.As(err, &schemaErr) {
+			param = schemaErr.Param()
+		}
+		errorData := map[string]interface{}{
+			"error": map[string]interface{}{
+				"message": fmt.Sprintf("Invalid %s: %v", param, err),
+				"type":    "invalid_request_error",
+				"param":   param,
+				"code":    400,
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorData)
+		return
+	}
+
+	// Build the payload for Google API. A fallback model gets its own payload, since the
+	// thinking budget depends on the model.
+	buildPayload := func(model string) map[string]interface{} {
+		modelRequest := request
+		modelRequest.Model = model
+		geminiRequestData, _ := transformers.ResponsesRequestToGemini(&modelRequest, conversation)
+		geminiPayload := client.BuildGeminiPayloadFromOpenAI(geminiRequestData)
+		if apiKeyID != "" {
+			geminiPayload[client.APIKeyIDField] = apiKeyID
+		}
+		return geminiPayload
+	}
+
+	if request.Stream {
+		handleStreamingResponses(w, r, &request, &route, buildPayload, conversation, apiKeyID)
+	} else {
+		handleNonStreamingResponses(w, r, &request, &route, buildPayload, conversation, apiKeyID)
+	}
+}
+
+// handleStoredResponse serves GET and DELETE /v1/responses/{id}
+func handleStoredResponse(w http.ResponseWriter, r *http.Request, identity string, responseID string) {
+	store := conversations.GetStore()
+
+	stored, ok := store.Get(responseID)
+	if !ok || stored.Owner != responsesOwner(identity) {
+		writeOpenAIError(w, http.StatusNotFound, "invalid_request_error", fmt.Sprintf("Response with id '%s' not found.", responseID))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stored.Response)
+	case http.MethodDelete:
+		store.Delete(responseID)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      responseID,
+			"object":  "response",
+			"deleted": true,
+		})
+	default:
+		writeOpenAIError(w, http.StatusMethodNotAllowed, "invalid_request_error", "Method not allowed")
+	}
+}
+
+// responsesOwner returns the API key ID stored responses are attributed to, matching what
+// apikeys.Authorize returns for the identity: the key ID for named keys, "" otherwise
+func responsesOwner(identity string) string {
+	if !apikeys.IsKeyID(identity) {
+		return ""
+	}
+	if key, exists := apikeys.GetKeyStore().Get(identity); exists {
+		return key.ID
+	}
+	return ""
+}
+
+// storeResponse keeps a finished response so it can be continued with previous_response_id,
+// unless the request set store to false
+func storeResponse(request *models.ResponsesRequest, response map[string]interface{}, conversation []map[string]interface{}, owner string) {
+	if request.Store != nil && !*request.Store {
+		return
+	}
+
+	output, _ := response["output"].([]map[string]interface{})
+	responseID, _ := response["id"].(string)
+	conversations.GetStore().Save(responseID, &conversations.Conversation{
+		Response: response,
+		Items:    slices.Concat(conversation, output),
+		Owner:    owner,
+	})
+}
+
+func handleNonStreamingResponses(w http.ResponseWriter, r *http.Request, request *models.ResponsesRequest, route *modelRoute, buildPayload func(model string) map[string]interface{}, conversation []map[string]interface{}, owner string) {
+	result, err := sendWithFallback(r.Context(), route, false, buildPayload)
+	request.Model = route.Model
+	reportServedModel(w, r, route)
+	if err != nil {
+		writeOpenAIError(w, http.StatusInternalServerError, "api_error", fmt.Sprintf("Request failed: %v", err))
+		return
+	}
+
+	geminiResponse, ok := result.(map[string]interface{})
+	if !ok {
+		writeOpenAIError(w, http.StatusInternalServerError, "api_error", "Invalid response from API")
+		return
+	}
+
+	// Check for error in response
+	if errObj, ok := geminiResponse["error"]; ok {
+		status := http.StatusInternalServerError
+		if errMap, ok := errObj.(map[string]interface{}); ok {
+			if code, ok := errMap["code"].(float64); ok {
+				status = int(code)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": errObj})
+		return
+	}
+
+	response := transformers.GeminiResponseToResponses(geminiResponse, request, transformers.NewResponsesID("resp"), time.Now().Unix())
+	storeResponse(request, response, conversation, owner)
+
+	log.Printf("Successfully processed non-streaming responses response for model: %s", request.Model)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// responsesStreamWriter emits the typed Responses API SSE events, opening and closing output items
+// as the type of the streamed Gemini parts changes
+type responsesStreamWriter struct {
+	w        http.ResponseWriter
+	flusher  http.Flusher
+	sequence int
+	output   []map[string]interface{} // Completed output items
+	openType string                   // Content type of the open item ("output_text" or "summary_text"), empty if none
+	openID   string
+	openText strings.Builder
+}
+
+func (sw *responsesStreamWriter) send(event string, data map[string]interface{}) {
+	data["type"] = event
+	data["sequence_number"] = sw.sequence
+	sw.sequence++
+
+	jsonData, _ := json.Marshal(data)
+	fmt.Fprintf(sw.w, "event: %s\ndata: %s\n\n", event, string(jsonData))
+	sw.flusher.Flush()
+}
+
+// closeItem finishes the open message or reasoning item, if any
+func (sw *responsesStreamWriter) closeItem() {
+	if sw.openType == "" {
+		return
+	}
+
+	index := len(sw.output)
+	text := sw.openText.String()
+
+	var item map[string]interface{}
+	switch sw.openType {
+	case "summary_text":
+		sw.send("response.reasoning_summary_text.done", map[string]interface{}{
+			"item_id":       sw.openID,
+			"output_index":  index,
+			"summary_index": 0,
+			"text":          text,
+		})
+		sw.send("response.reasoning_summary_part.done", map[string]interface{}{
+			"item_id":       sw.openID,
+			"output_index":  index,
+			"summary_index": 0,
+			"part":          map[string]interface{}{"type": "summary_text", "text": text},
+		})
+		item = transformers.ResponsesReasoningItem(sw.openID, text)
+	default:
+		sw.send("response.output_text.done", map[string]interface{}{
+			"item_id":       sw.openID,
+			"output_index":  index,
+			"content_index": 0,
+			"text":          text,
+			"logprobs":      []interface{}{},
+		})
+		sw.send("response.content_part.done", map[string]interface{}{
+			"item_id":       sw.openID,
+			"output_index":  index,
+			"content_index": 0,
+			"part":          transformers.ResponsesOutputTextPart(text),
+		})
+		item = transformers.ResponsesMessageItem(sw.openID, text, "completed")
+	}
+
+	sw.send("response.output_item.done", map[string]interface{}{
+		"output_index": index,
+		"item":         item,
+	})
+	sw.output = append(sw.output, item)
+	sw.openType = ""
+	sw.openID = ""
+	sw.openText.Reset()
+}
+
+// writeFunctionCall streams a function_call item; Gemini sends the arguments whole, so it is closed right away
+func (sw *responsesStreamWriter) writeFunctionCall(call map[string]interface{}) {
+	sw.closeItem()
+
+	index := len(sw.output)
+	itemID := transformers.NewResponsesID("fc")
+	item := transformers.ResponsesFunctionCallItem(itemID, call, "completed")
+
+	sw.send("response.output_item.added", map[string]interface{}{
+		"output_index": index,
+		"item":         transformers.ResponsesFunctionCallItem(itemID, call, "in_progress"),
+	})
+	sw.send("response.function_call_arguments.delta", map[string]interface{}{
+		"item_id":      itemID,
+		"output_index": index,
+		"delta":        item["arguments"],
+	})
+	sw.send("response.function_call_arguments.done", map[string]interface{}{
+		"item_id":      itemID,
+		"output_index": index,
+		"arguments":    item["arguments"],
+	})
+	sw.send("response.output_item.done", map[string]interface{}{
+		"output_index": index,
+		"item":         item,
+	})
+	sw.output = append(sw.output, item)
+}
+
+// writeOutput streams a piece of output content, continuing the open item when the type matches
+func (sw *responsesStreamWriter) writeOutput(output map[string]interface{}) {
+	outputType, _ := output["type"].(string)
+	if outputType == "function_call" {
+		sw.writeFunctionCall(output)
+		return
+	}
+
+	if outputType != sw.openType {
+		sw.closeItem()
+		index := len(sw.output)
+
+		switch outputType {
+		case "summary_text":
+			sw.openID = transformers.NewResponsesID("rs")
+			sw.send("response.output_item.added", map[string]interface{}{
+				"output_index": index,
+				"item":         transformers.ResponsesReasoningItem(sw.openID, ""),
+			})
+			sw.send("response.reasoning_summary_part.added", map[string]interface{}{
+				"item_id":       sw.openID,
+				"output_index":  index,
+				"summary_index": 0,
+				"part":          map[string]interface{}{"type": "summary_text", "text": ""},
+			})
+		default:
+			sw.openID = transformers.NewResponsesID("msg")
+			sw.send("response.output_item.added", map[string]interface{}{
+				"output_index": index,
+				"item":         transformers.ResponsesMessageItem(sw.openID, "", "in_progress"),
+			})
+			sw.send("response.content_part.added", map[string]interface{}{
+				"item_id":       sw.openID,
+				"output_index":  index,
+				"content_index": 0,
+				"part":          transformers.ResponsesOutputTextPart(""),
+			})
+		}
+		sw.openType = outputType
+	}
+
+	text, _ := output["text"].(string)
+	sw.openText.WriteString(text)
+
+	index := len(sw.output)
+	if outputType == "summary_text" {
+		sw.send("response.reasoning_summary_text.delta", map[string]interface{}{
+			"item_id":       sw.openID,
+			"output_index":  index,
+			"summary_index": 0,
+			"delta":         text,
+		})
+	} else {
+		sw.send("response.output_text.delta", map[string]interface{}{
+			"item_id":       sw.openID,
+			"output_index":  index,
+			"content_index": 0,
+			"delta":         text,
+			"logprobs":      []interface{}{},
+		})
+	}
+}
+
+func handleStreamingResponses(w http.ResponseWriter, r *http.Request, request *models.ResponsesRequest, route *modelRoute, buildPayload func(model string) map[string]interface{}, conversation []map[string]interface{}, owner string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeOpenAIError(w, http.StatusInternalServerError, "api_error", "Streaming not supported")
+		return
+	}
+
+	// Send request before writing SSE headers so upstream failures keep a proper status code
+	result, err := sendWithFallback(r.Context(), route, true, buildPayload)
+	request.Model = route.Model
+	reportServedModel(w, r, route)
+	if err != nil {
+		writeOpenAIError(w, http.StatusInternalServerError, "api_error", fmt.Sprintf("Request failed: %v", err))
+		return
+	}
+
+	streamChan, ok := result.(chan string)
+	if !ok {
+		writeOpenAIError(w, http.StatusInternalServerError, "api_error", "Streaming request failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	responseID := transformers.NewResponsesID("resp")
+	log.Printf("Starting streaming responses response: %s", responseID)
+
+	response := transformers.NewResponsesObject(responseID, request, time.Now().Unix())
+	includeReasoning := transformers.IncludeReasoningSummary(request)
+
+	sw := &responsesStreamWriter{w: w, flusher: flusher}
+	sw.send("response.created", map[string]interface{}{"response": response})
+	sw.send("response.in_progress", map[string]interface{}{"response": response})
+
+	finishReason := ""
+	var usageMetadata map[string]interface{}
+
+	for chunk := range streamChan {
+		var geminiChunk map[string]interface{}
+		if err := json.Unmarshal([]byte(chunk), &geminiChunk); err != nil {
+			continue
+		}
+
+		// Fail the response on error chunks
+		if errObj, ok := geminiChunk["error"].(map[string]interface{}); ok {
+			message, _ := errObj["message"].(string)
+			sw.closeItem()
+			response["status"] = "failed"
+			response["output"] = sw.output
+			response["error"] = map[string]interface{}{
+				"code":    "server_error",
+				"message": message,
+			}
+			sw.send("response.failed", map[string]interface{}{"response": response})
+			log.Printf("Streaming responses response failed: %s: %s", responseID, message)
+			return
+		}
+
+		if metadata, ok := geminiChunk["usageMetadata"].(map[string]interface{}); ok {
+			usageMetadata = metadata
+		}
+
+		// A response has a single output; use the first candidate
+		candidates, _ := geminiChunk["candidates"].([]interface{})
+		if len(candidates) == 0 {
+			continue
+		}
+		candMap, _ := candidates[0].(map[string]interface{})
+		content, _ := candMap["content"].(map[string]interface{})
+		parts, _ := content["parts"].([]interface{})
+
+		for _, output := range transformers.GeminiPartsToResponsesOutput(parts, includeReasoning) {
+			sw.writeOutput(output)
+		}
+
+		if reason, ok := candMap["finishReason"].(string); ok && reason != "" {
+			finishReason = reason
+		}
+	}
+
+	// The stream also ends when the client goes away; there is nothing to finish or store then
+	if r.Context().Err() != nil {
+		log.Printf("Client disconnected during streaming responses response: %s", responseID)
+		return
+	}
+
+	sw.closeItem()
+
+	transformers.CompleteResponsesObject(response, sw.output, finishReason, usageMetadata)
+	storeResponse(request, response, conversation, owner)
+
+	event := "response.completed"
+	if response["status"] == "incomplete" {
+		event = "response.incomplete"
+	}
+	sw.send(event, map[string]interface{}{"response": response})
+
+	log.Printf("Completed streaming responses response: %s", responseID)
+}
@@ -0,0 +1,67 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gcli2apigo/internal/client"
+)
+
+// Response headers reporting upstream retries, for debugging
+const (
+	RetriesHeader      = "X-Gcli-Retries"
+	RetryReasonsHeader = "X-Gcli-Retry-Reasons"
+)
+
+// retryHeaderWriter adds the retry headers just before the response header is written
+type retryHeaderWriter struct {
+	http.ResponseWriter
+	stats       *client.RetryStats
+	wroteHeader bool
+}
+
+func (rw *retryHeaderWriter) setHeaders() {
+	if rw.wroteHeader {
+		return
+	}
+	rw.wroteHeader = true
+
+	header := rw.ResponseWriter.Header()
+	header.Set(RetriesHeader, strconv.Itoa(rw.stats.Count()))
+	if reasons := rw.stats.Reasons(); len(reasons) > 0 {
+		header.Set(RetryReasonsHeader, strings.Join(reasons, ","))
+	}
+}
+
+func (rw *retryHeaderWriter) WriteHeader(status int) {
+	rw.setHeaders()
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *retryHeaderWriter) Write(b []byte) (int, error) {
+	rw.setHeaders()
+	return rw.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming responses working through the wrapper
+func (rw *retryHeaderWriter) Flush() {
+	rw.setHeaders()
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *retryHeaderWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// WithRetryHeaders wraps an API handler so its responses report the upstream retries made while
+// serving them in the X-Gcli-Retries and X-Gcli-Retry-Reasons headers
+func WithRetryHeaders(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, stats := client.WithRetryStats(r.Context())
+		next(&retryHeaderWriter{ResponseWriter: w, stats: stats}, r.WithContext(ctx))
+	}
+}
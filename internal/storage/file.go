@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"gcli2apigo/internal/config"
+	"gcli2apigo/internal/credcrypt"
+)
+
+// reservedFiles lists JSON files kept in the credentials folder that are not OAuth credentials
+var reservedFiles = map[string]bool{
+	StateBanList + ".json":  true,
+	StateUsage + ".json":    true,
+	StateKeyUsage + ".json": true,
+	StateAPIKeys + ".json":  true,
+
+	config.ModelRegistryFile: true,
+}
+
+// FileStore keeps each credential and state document in its own JSON file in a folder.
+// Every file is replaced atomically, but documents saved together are written one by one.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex // Serializes writes so concurrent saves cannot interleave
+}
+
+// NewFileStore creates a store for the given folder
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// String describes the store for logs
+func (fs *FileStore) String() string {
+	return fmt.Sprintf("file storage in %s", fs.dir)
+}
+
+// ListCredentials reads every credential file in the folder
+func (fs *FileStore) ListCredentials() ([]Credential, error) {
+	files, err := os.ReadDir(fs.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials folder: %w", err)
+	}
+
+	credentials := make([]Credential, 0, len(files))
+	for _, file := range files {
+		if file.IsDir() || ValidateCredentialName(file.Name()) != nil {
+			continue
+		}
+		credential, err := fs.GetCredential(file.Name())
+		if err != nil {
+			credential = &Credential{Name: file.Name(), Err: err}
+		}
+		credentials = append(credentials, *credential)
+	}
+	return credentials, nil
+}
+
+// GetCredential reads a credential file, decrypting it if it is encrypted
+func (fs *FileStore) GetCredential(name string) (*Credential, error) {
+	if err := ValidateCredentialName(name); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(fs.dir, name)
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, err := credcrypt.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Credential{Name: name, Data: data, ModTime: info.ModTime()}, nil
+}
+
+// PutCredential writes a credential file, encrypted when a key is configured
+func (fs *FileStore) PutCredential(name string, data []byte) error {
+	if err := ValidateCredentialName(name); err != nil {
+		return err
+	}
+	sealed, err := credcrypt.Seal(data)
+	if err != nil {
+		return err
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.writeFile(name, sealed)
+}
+
+// DeleteCredential removes a credential file
+func (fs *FileStore) DeleteCredential(name string) error {
+	if err := ValidateCredentialName(name); err != nil {
+		return err
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	err := os.Remove(filepath.Join(fs.dir, name))
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// CredentialStamps returns the size and modification time of every credential file
+func (fs *FileStore) CredentialStamps() (map[string]string, error) {
+	stamps := make(map[string]string)
+	files, err := os.ReadDir(fs.dir)
+	if err != nil {
+		return stamps, err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || ValidateCredentialName(file.Name()) != nil {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		stamps[file.Name()] = fmt.Sprintf("%d@%d", info.Size(), info.ModTime().UnixNano())
+	}
+	return stamps, nil
+}
+
+// LoadState reads a state document from {name}.json
+func (fs *FileStore) LoadState(name string) ([]byte, time.Time, error) {
+	path := filepath.Join(fs.dir, name+".json")
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, time.Time{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return data, info.ModTime(), nil
+}
+
+// SaveState writes each state document to {name}.json
+func (fs *FileStore) SaveState(states map[string][]byte) error {
+	names := make([]string, 0, len(states))
+	for name := range states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	for _, name := range names {
+		if err := fs.writeFile(name+".json", states[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close does nothing; files are closed after every operation
+func (fs *FileStore) Close() error {
+	return nil
+}
+
+// writeFile writes a file in the folder with 0600 permissions. The data goes to a temporary
+// file that is synced and renamed into place, so a crash or a concurrent reader never sees
+// a partially written file.
+func (fs *FileStore) writeFile(name string, data []byte) error {
+	if err := os.MkdirAll(fs.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create folder: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(fs.dir, name+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	_, writeErr := tmp.Write(data)
+	syncErr := tmp.Sync()
+	closeErr := tmp.Close()
+	if err := errors.Join(writeErr, syncErr, closeErr); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(fs.dir, name)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
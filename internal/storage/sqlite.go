@@ -0,0 +1,337 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"gcli2apigo/internal/credcrypt"
+
+	_ "modernc.org/sqlite" // Pure-Go driver, registered as "sqlite"
+)
+
+// sqliteSchema creates the tables on first use
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS credentials (
+	name       TEXT PRIMARY KEY,
+	data       BLOB NOT NULL,
+	updated_at INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS state (
+	name       TEXT PRIMARY KEY,
+	data       BLOB NOT NULL,
+	updated_at INTEGER NOT NULL
+);`
+
+// SQLiteStore keeps credentials and state documents in a SQLite database.
+// Every write runs in a transaction, so saving several documents is all-or-nothing.
+type SQLiteStore struct {
+	db   *sql.DB
+	path string
+}
+
+// OpenSQLiteStore opens (or creates) the database at path. A new database is seeded with the
+// credentials and state found in importDir, so switching from file storage keeps everything.
+func OpenSQLiteStore(path string, importDir string) (*SQLiteStore, error) {
+	_, statErr := os.Stat(path)
+	isNew := os.IsNotExist(statErr)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create database folder: %w", err)
+	}
+	// Create the file up front so SQLite gives the journal files the same 0600 permissions
+	if isNew {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create database: %w", err)
+		}
+		file.Close()
+	}
+
+	// WAL lets the dashboard read while a save is in progress; immediate transactions take
+	// the write lock up front instead of failing halfway through
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=synchronous(FULL)&_txlock=immediate"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create tables: %w", err)
+	}
+
+	store := &SQLiteStore{db: db, path: path}
+	if isNew && importDir != "" {
+		credentials, states, err := store.Import(NewFileStore(importDir))
+		if err != nil {
+			db.Close()
+			os.Remove(path)
+			return nil, fmt.Errorf("failed to import %s: %w", importDir, err)
+		}
+		if credentials > 0 || states > 0 {
+			log.Printf("[INFO] Imported %d credential(s) and %d state file(s) from %s into %s; the files are no longer used",
+				credentials, states, importDir, path)
+		}
+	}
+	return store, nil
+}
+
+// String describes the store for logs
+func (s *SQLiteStore) String() string {
+	return fmt.Sprintf("SQLite storage in %s", s.path)
+}
+
+// ListCredentials reads every credential row
+func (s *SQLiteStore) ListCredentials() ([]Credential, error) {
+	rows, err := s.db.Query(`SELECT name, data, updated_at FROM credentials ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list credentials: %w", err)
+	}
+	defer rows.Close()
+
+	var credentials []Credential
+	for rows.Next() {
+		var name string
+		var data []byte
+		var updatedAt int64
+		if err := rows.Scan(&name, &data, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to list credentials: %w", err)
+		}
+		credential := Credential{Name: name, ModTime: time.Unix(0, updatedAt)}
+		credential.Data, credential.Err = credcrypt.Open(data)
+		credentials = append(credentials, credential)
+	}
+	return credentials, rows.Err()
+}
+
+// GetCredential reads a credential row, decrypting it if it is encrypted
+func (s *SQLiteStore) GetCredential(name string) (*Credential, error) {
+	var data []byte
+	var updatedAt int64
+	err := s.db.QueryRow(`SELECT data, updated_at FROM credentials WHERE name = ?`, name).Scan(&data, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credential: %w", err)
+	}
+	plaintext, err := credcrypt.Open(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Credential{Name: name, Data: plaintext, ModTime: time.Unix(0, updatedAt)}, nil
+}
+
+// PutCredential writes a credential row, encrypted when a key is configured
+func (s *SQLiteStore) PutCredential(name string, data []byte) error {
+	if err := ValidateCredentialName(name); err != nil {
+		return err
+	}
+	sealed, err := credcrypt.Seal(data)
+	if err != nil {
+		return err
+	}
+	return s.inTx(func(tx *sql.Tx) error {
+		return putCredential(tx, name, sealed)
+	})
+}
+
+// putCredential upserts a credential row that is already sealed
+func putCredential(tx *sql.Tx, name string, sealed []byte) error {
+	_, err := tx.Exec(`INSERT INTO credentials (name, data, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`,
+		name, sealed, time.Now().UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to write credential: %w", err)
+	}
+	return nil
+}
+
+// DeleteCredential removes a credential row
+func (s *SQLiteStore) DeleteCredential(name string) error {
+	result, err := s.db.Exec(`DELETE FROM credentials WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete credential: %w", err)
+	}
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CredentialStamps returns the last write time of every credential row
+func (s *SQLiteStore) CredentialStamps() (map[string]string, error) {
+	stamps := make(map[string]string)
+	rows, err := s.db.Query(`SELECT name, updated_at FROM credentials`)
+	if err != nil {
+		return stamps, fmt.Errorf("failed to list credentials: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		var updatedAt int64
+		if err := rows.Scan(&name, &updatedAt); err != nil {
+			return stamps, fmt.Errorf("failed to list credentials: %w", err)
+		}
+		stamps[name] = strconv.FormatInt(updatedAt, 10)
+	}
+	return stamps, rows.Err()
+}
+
+// LoadState reads a state document row
+func (s *SQLiteStore) LoadState(name string) ([]byte, time.Time, error) {
+	var data []byte
+	var updatedAt int64
+	err := s.db.QueryRow(`SELECT data, updated_at FROM state WHERE name = ?`, name).Scan(&data, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, time.Time{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return data, time.Unix(0, updatedAt), nil
+}
+
+// SaveState writes every state document in one transaction
+func (s *SQLiteStore) SaveState(states map[string][]byte) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		now := time.Now().UnixNano()
+		for name, data := range states {
+			_, err := tx.Exec(`INSERT INTO state (name, data, updated_at) VALUES (?, ?, ?)
+				ON CONFLICT(name) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`,
+				name, data, now)
+			if err != nil {
+				return fmt.Errorf("failed to write %s: %w", name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Close checkpoints and closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// Import copies every credential and state document of another store in one transaction
+func (s *SQLiteStore) Import(from Store) (credentials int, states int, err error) {
+	listed, err := from.ListCredentials()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	err = s.inTx(func(tx *sql.Tx) error {
+		for _, credential := range listed {
+			if credential.Err != nil {
+				return fmt.Errorf("failed to read credential %s: %w", credential.Name, credential.Err)
+			}
+			sealed, err := credcrypt.Seal(credential.Data)
+			if err != nil {
+				return err
+			}
+			if err := putCredential(tx, credential.Name, sealed); err != nil {
+				return err
+			}
+			credentials++
+		}
+
+		for _, name := range stateNames {
+			data, modTime, err := from.LoadState(name)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", name, err)
+			}
+			if _, err := tx.Exec(`INSERT OR REPLACE INTO state (name, data, updated_at) VALUES (?, ?, ?)`,
+				name, data, modTime.UnixNano()); err != nil {
+				return fmt.Errorf("failed to write %s: %w", name, err)
+			}
+			states++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return credentials, states, nil
+}
+
+// Reseal rewrites every credential encrypted with the configured key, or decrypted
+// when encrypt is false, and returns how many rows changed
+func (s *SQLiteStore) Reseal(encrypt bool) (int, error) {
+	key, err := credcrypt.LoadKey()
+	if err != nil {
+		return 0, err
+	}
+	if key == nil {
+		return 0, credcrypt.ErrKeyNotConfigured
+	}
+
+	changed := 0
+	err = s.inTx(func(tx *sql.Tx) error {
+		rows, err := tx.Query(`SELECT name, data FROM credentials`)
+		if err != nil {
+			return err
+		}
+		resealed := make(map[string][]byte)
+		for rows.Next() {
+			var name string
+			var data []byte
+			if err := rows.Scan(&name, &data); err != nil {
+				rows.Close()
+				return err
+			}
+			if credcrypt.IsEncrypted(data) == encrypt {
+				continue
+			}
+			if encrypt {
+				data, err = credcrypt.Encrypt(data, key)
+			} else {
+				data, err = credcrypt.Decrypt(data, key)
+			}
+			if err != nil {
+				rows.Close()
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			resealed[name] = data
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for name, data := range resealed {
+			if err := putCredential(tx, name, data); err != nil {
+				return err
+			}
+		}
+		changed = len(resealed)
+		return nil
+	})
+	return changed, err
+}
+
+// inTx runs fn in a transaction that is committed only if fn succeeds
+func (s *SQLiteStore) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
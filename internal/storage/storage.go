@@ -0,0 +1,114 @@
+// Package storage persists OAuth credentials and the state kept about them: bans, usage
+// statistics and API keys.
+//
+// The default backend keeps the historical layout of JSON files in the credentials folder.
+// STORAGE_BACKEND=sqlite keeps everything in a single SQLite database instead, where every
+// save is one transaction, so concurrent writers and crashes cannot leave partial state.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gcli2apigo/internal/config"
+)
+
+// Names of the state documents kept next to the credentials
+const (
+	StateBanList  = "banlist"
+	StateUsage    = "usage_stats"
+	StateKeyUsage = "key_usage"
+	StateAPIKeys  = "api_keys"
+)
+
+// stateNames lists every state document, in the order they are imported
+var stateNames = []string{StateBanList, StateUsage, StateKeyUsage, StateAPIKeys}
+
+// ErrNotFound is returned when a credential or state document does not exist
+var ErrNotFound = errors.New("not found")
+
+// Credential is a stored OAuth credential
+type Credential struct {
+	Name    string    // File name in the credentials folder, e.g. "my-project.json"
+	Data    []byte    // Plaintext JSON, decrypted if it is stored encrypted; nil when Err is set
+	ModTime time.Time // Time of the last write
+	Err     error     // Set when the credential could not be read or decrypted
+}
+
+// Store persists credentials and state documents.
+// Credential data is encrypted at rest when a credential encryption key is configured.
+type Store interface {
+	// ListCredentials returns every stored credential, sorted by name. A credential that
+	// cannot be read is returned with Err set rather than failing the whole listing.
+	ListCredentials() ([]Credential, error)
+	// GetCredential returns a credential by name, or ErrNotFound
+	GetCredential(name string) (*Credential, error)
+	// PutCredential creates or replaces a credential
+	PutCredential(name string, data []byte) error
+	// DeleteCredential removes a credential, or returns ErrNotFound
+	DeleteCredential(name string) error
+	// CredentialStamps returns a version stamp per credential name, which changes whenever
+	// the credential is written, to detect changes made outside this process
+	CredentialStamps() (map[string]string, error)
+
+	// LoadState returns a state document and the time it was last saved, or ErrNotFound
+	LoadState(name string) ([]byte, time.Time, error)
+	// SaveState saves one or more state documents together
+	SaveState(states map[string][]byte) error
+
+	// String describes the backend and its location for logs
+	String() string
+	// Close releases the backend's resources
+	Close() error
+}
+
+var (
+	globalStore Store
+	storeOnce   sync.Once
+)
+
+// GetStore returns the global store for the configured backend (STORAGE_BACKEND).
+// A SQLite database that cannot be opened stops the process, since falling back to files
+// would silently split credentials and state between two places.
+func GetStore() Store {
+	storeOnce.Do(func() {
+		switch backend := config.GetStorageBackend(); backend {
+		case "sqlite":
+			store, err := OpenSQLiteStore(config.GetSQLitePath(), config.OAuthCredsFolder)
+			if err != nil {
+				log.Fatalf("[ERROR] Failed to open SQLite storage: %v", err)
+			}
+			globalStore = store
+		default:
+			if backend != "file" {
+				log.Printf("[WARN] Unknown STORAGE_BACKEND %q, using file storage", backend)
+			}
+			globalStore = NewFileStore(config.OAuthCredsFolder)
+		}
+		log.Printf("[INFO] Using %s", globalStore)
+	})
+	return globalStore
+}
+
+// ValidateCredentialName checks that a credential name is a plain .json file name that does
+// not collide with a state document or the model registry
+func ValidateCredentialName(name string) error {
+	if filepath.Ext(name) != ".json" || strings.ContainsAny(name, "/\\\x00") || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid credential name %q", name)
+	}
+	if reservedFiles[name] {
+		return fmt.Errorf("credential name %q is reserved", name)
+	}
+	return nil
+}
+
+// CredentialPath returns the path identifying a credential in logs and in the credential pool.
+// For the SQLite backend the path does not exist on disk.
+func CredentialPath(name string) string {
+	return filepath.Join(config.OAuthCredsFolder, name)
+}
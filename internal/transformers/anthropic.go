@@ -0,0 +1,475 @@
+package transformers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"gcli2apigo/internal/config"
+	"gcli2apigo/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// AnthropicRequestToGemini transforms an Anthropic Messages API request to Gemini format.
+// The result has the same shape as OpenAIRequestToGemini so it can be passed to
+// client.BuildGeminiPayloadFromOpenAI. It returns an error for content that cannot be
+// sent upstream, which is reported to the client as an invalid_request_error.
+func AnthropicRequestToGemini(req *models.AnthropicMessagesRequest) (map[string]interface{}, error) {
+	contents := make([]map[string]interface{}, 0, len(req.Messages))
+
+	// Map tool_use IDs to function names so tool_result blocks can be attributed
+	toolUseNames := make(map[string]string)
+
+	for messageIndex, message := range req.Messages {
+		role := "user"
+		if message.Role == "assistant" {
+			role = "model"
+		}
+
+		parts := make([]map[string]interface{}, 0)
+
+		switch content := message.Content.(type) {
+		case string:
+			parts = append(parts, map[string]interface{}{"text": content})
+
+		case []interface{}:
+			for blockIndex, block := range content {
+				blockMap, ok := block.(map[string]interface{})
+				if !ok {
+					continue
+				}
+
+				switch blockType, _ := blockMap["type"].(string); blockType {
+				case "text":
+					if text, ok := blockMap["text"].(string); ok {
+						parts = append(parts, map[string]interface{}{"text": text})
+					}
+
+				case "image", "document":
+					imagePart, err := anthropicSourceToGeminiPart(blockMap)
+					if err != nil {
+						return nil, fmt.Errorf("messages.%d.content.%d.source: %w", messageIndex, blockIndex, err)
+					}
+					if imagePart != nil {
+						parts = append(parts, imagePart)
+					}
+
+				case "tool_use":
+					id, _ := blockMap["id"].(string)
+					name, _ := blockMap["name"].(string)
+					if id != "" {
+						toolUseNames[id] = name
+					}
+					args, _ := blockMap["input"].(map[string]interface{})
+					if args == nil {
+						args = map[string]interface{}{}
+					}
+					part := map[string]interface{}{
+						"functionCall": map[string]interface{}{
+							"name": name,
+							"args": args,
+						},
+					}
+					if signature := thoughtSignatures.get(id); signature != "" {
+						part["thoughtSignature"] = signature
+					}
+					parts = append(parts, part)
+
+				case "tool_result":
+					toolUseID, _ := blockMap["tool_use_id"].(string)
+					functionName := toolUseNames[toolUseID]
+					if functionName == "" {
+						functionName = toolUseID
+					}
+					parts = append(parts, anthropicToolResultToFunctionResponse(blockMap, functionName))
+
+				case "thinking", "redacted_thinking":
+					// Previous thinking is not replayed upstream; Gemini regenerates it per turn
+					continue
+
+				default:
+					if config.IsDebugEnabled() {
+						log.Printf("[DEBUG] Skipping unsupported Anthropic content block type: %s", blockType)
+					}
+				}
+			}
+		}
+
+		if len(parts) == 0 {
+			parts = append(parts, map[string]interface{}{"text": ""})
+		}
+
+		contents = append(contents, map[string]interface{}{
+			"role":  role,
+			"parts": parts,
+		})
+	}
+
+	// Map Anthropic generation parameters to Gemini format
+	generationConfig := make(map[string]interface{})
+
+	thinkingConfig := map[string]interface{}{
+		"thinkingBudget": config.GetThinkingBudget(req.Model),
+	}
+	if req.Thinking != nil && req.Thinking.Type == "enabled" {
+		if req.Thinking.BudgetTokens > 0 {
+			thinkingConfig["thinkingBudget"] = req.Thinking.BudgetTokens
+		}
+		thinkingConfig["includeThoughts"] = true
+	}
+	generationConfig["thinkingConfig"] = thinkingConfig
+
+	if req.MaxTokens > 0 {
+		generationConfig["maxOutputTokens"] = req.MaxTokens
+	} else {
+		generationConfig["maxOutputTokens"] = 65535
+	}
+	if req.Temperature != nil {
+		generationConfig["temperature"] = *req.Temperature
+	}
+	if req.TopP != nil {
+		generationConfig["topP"] = *req.TopP
+	}
+	if req.TopK != nil {
+		generationConfig["topK"] = *req.TopK
+	}
+	if len(req.StopSequences) > 0 {
+		generationConfig["stopSequences"] = req.StopSequences
+	}
+
+	requestPayload := map[string]interface{}{
+		"contents":         contents,
+		"generationConfig": generationConfig,
+		"safetySettings":   config.DefaultSafetySettings,
+		"model":            req.Model,
+	}
+
+	if systemInstruction := anthropicSystemToGemini(req.System); systemInstruction != nil {
+		requestPayload["systemInstruction"] = systemInstruction
+	}
+	tools, err := anthropicToolsToGemini(req.Tools)
+	if err != nil {
+		return nil, err
+	}
+	if tools != nil {
+		requestPayload["tools"] = tools
+	}
+	if toolConfig := anthropicToolChoiceToGemini(req.ToolChoice); toolConfig != nil {
+		requestPayload["toolConfig"] = toolConfig
+	}
+
+	return requestPayload, nil
+}
+
+// anthropicSystemToGemini converts the system prompt (string or text blocks) into a Gemini systemInstruction
+func anthropicSystemToGemini(system interface{}) map[string]interface{} {
+	var text string
+	switch s := system.(type) {
+	case string:
+		text = s
+	case []interface{}:
+		texts := make([]string, 0, len(s))
+		for _, block := range s {
+			if blockMap, ok := block.(map[string]interface{}); ok {
+				if blockText, ok := blockMap["text"].(string); ok {
+					texts = append(texts, blockText)
+				}
+			}
+		}
+		text = strings.Join(texts, "\n\n")
+	}
+
+	if text == "" {
+		return nil
+	}
+
+	return map[string]interface{}{
+		"parts": []map[string]interface{}{{"text": text}},
+	}
+}
+
+// anthropicSourceToGeminiPart converts an image or document block with a base64 source into a Gemini part.
+// URL sources are rejected: Code Assist does not fetch arbitrary URLs.
+func anthropicSourceToGeminiPart(block map[string]interface{}) (map[string]interface{}, error) {
+	source, ok := block["source"].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	switch sourceType, _ := source["type"].(string); sourceType {
+	case "base64":
+		mediaType, _ := source["media_type"].(string)
+		data, _ := source["data"].(string)
+		if data == "" {
+			return nil, nil
+		}
+		return map[string]interface{}{
+			"inlineData": map[string]interface{}{
+				"mimeType": mediaType,
+				"data":     data,
+			},
+		}, nil
+	case "url":
+		return nil, errors.New("url sources are not supported, send the content as a base64 source instead")
+	default:
+		log.Printf("[WARN] Unsupported Anthropic source type: %s", sourceType)
+		return nil, nil
+	}
+}
+
+// anthropicToolResultToFunctionResponse converts a tool_result block into a Gemini functionResponse part
+func anthropicToolResultToFunctionResponse(block map[string]interface{}, functionName string) map[string]interface{} {
+	var text string
+	switch content := block["content"].(type) {
+	case string:
+		text = content
+	case []interface{}:
+		texts := make([]string, 0, len(content))
+		for _, item := range content {
+			if itemMap, ok := item.(map[string]interface{}); ok {
+				if itemText, ok := itemMap["text"].(string); ok {
+					texts = append(texts, itemText)
+				}
+			}
+		}
+		text = strings.Join(texts, "")
+	}
+
+	// Gemini requires the response to be an object; pass JSON objects through as-is
+	response := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(text), &response); err != nil || len(response) == 0 {
+		response = map[string]interface{}{"content": text}
+	}
+	if isError, _ := block["is_error"].(bool); isError {
+		response = map[string]interface{}{"error": text}
+	}
+
+	return map[string]interface{}{
+		"functionResponse": map[string]interface{}{
+			"name":     functionName,
+			"response": response,
+		},
+	}
+}
+
+// anthropicToolsToGemini converts Anthropic tool definitions into Gemini functionDeclarations
+func anthropicToolsToGemini(tools []models.AnthropicTool) ([]map[string]interface{}, error) {
+	declarations := make([]map[string]interface{}, 0, len(tools))
+
+	for i, tool := range tools {
+		if tool.Name == "" {
+			continue
+		}
+
+		declaration := map[string]interface{}{
+			"name": tool.Name,
+		}
+		if tool.Description != "" {
+			declaration["description"] = tool.Description
+		}
+		if len(tool.InputSchema) > 0 {
+			parameters, err := toolParametersToGemini(tool.InputSchema, fmt.Sprintf("tools[%d].input_schema", i))
+			if err != nil {
+				return nil, err
+			}
+			declaration["parameters"] = parameters
+		}
+
+		declarations = append(declarations, declaration)
+	}
+
+	if len(declarations) == 0 {
+		return nil, nil
+	}
+
+	return []map[string]interface{}{
+		{"functionDeclarations": declarations},
+	}, nil
+}
+
+// anthropicToolChoiceToGemini converts Anthropic tool_choice into a Gemini toolConfig
+func anthropicToolChoiceToGemini(toolChoice map[string]interface{}) map[string]interface{} {
+	if toolChoice == nil {
+		return nil
+	}
+
+	var callingConfig map[string]interface{}
+	switch choiceType, _ := toolChoice["type"].(string); choiceType {
+	case "auto":
+		callingConfig = map[string]interface{}{"mode": "AUTO"}
+	case "any":
+		callingConfig = map[string]interface{}{"mode": "ANY"}
+	case "none":
+		callingConfig = map[string]interface{}{"mode": "NONE"}
+	case "tool":
+		name, _ := toolChoice["name"].(string)
+		if name == "" {
+			return nil
+		}
+		callingConfig = map[string]interface{}{
+			"mode":                 "ANY",
+			"allowedFunctionNames": []string{name},
+		}
+	default:
+		return nil
+	}
+
+	return map[string]interface{}{
+		"functionCallingConfig": callingConfig,
+	}
+}
+
+// GeminiPartsToAnthropicBlocks converts Gemini response parts into Anthropic content blocks.
+// Thought parts are only returned as thinking blocks when includeThinking is set.
+func GeminiPartsToAnthropicBlocks(parts []interface{}, includeThinking bool) []map[string]interface{} {
+	blocks := make([]map[string]interface{}, 0, len(parts))
+
+	for _, part := range parts {
+		partMap, _ := part.(map[string]interface{})
+
+		if text, ok := partMap["text"].(string); ok {
+			if thought, _ := partMap["thought"].(bool); thought {
+				if includeThinking {
+					signature, _ := partMap["thoughtSignature"].(string)
+					blocks = append(blocks, map[string]interface{}{
+						"type":      "thinking",
+						"thinking":  text,
+						"signature": signature,
+					})
+				}
+				continue
+			}
+			if text == "" {
+				continue
+			}
+			blocks = append(blocks, map[string]interface{}{
+				"type": "text",
+				"text": text,
+			})
+			continue
+		}
+
+		if functionCall, ok := partMap["functionCall"].(map[string]interface{}); ok {
+			name, _ := functionCall["name"].(string)
+			input, _ := functionCall["args"].(map[string]interface{})
+			if input == nil {
+				input = map[string]interface{}{}
+			}
+			id, _ := functionCall["id"].(string)
+			if id == "" {
+				id = "toolu_" + strings.ReplaceAll(uuid.New().String(), "-", "")
+			}
+			// tool_use blocks have no field for it, so the signature is replayed by ID
+			if signature, _ := partMap["thoughtSignature"].(string); signature != "" {
+				thoughtSignatures.put(id, signature)
+			}
+			blocks = append(blocks, map[string]interface{}{
+				"type":  "tool_use",
+				"id":    id,
+				"name":  name,
+				"input": input,
+			})
+		}
+	}
+
+	return blocks
+}
+
+// MapFinishReasonToAnthropic maps a Gemini finish reason to an Anthropic stop_reason
+func MapFinishReasonToAnthropic(geminiReason string, hasToolUse bool) string {
+	switch geminiReason {
+	case "MAX_TOKENS":
+		return "max_tokens"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII":
+		return "refusal"
+	default:
+		if hasToolUse {
+			return "tool_use"
+		}
+		return "end_turn"
+	}
+}
+
+// AnthropicUsage converts Gemini usageMetadata into Anthropic usage counters
+func AnthropicUsage(usageMetadata map[string]interface{}) map[string]interface{} {
+	inputTokens, _ := usageMetadata["promptTokenCount"].(float64)
+	outputTokens, _ := usageMetadata["candidatesTokenCount"].(float64)
+	thoughtsTokens, _ := usageMetadata["thoughtsTokenCount"].(float64)
+
+	return map[string]interface{}{
+		"input_tokens":  int(inputTokens),
+		"output_tokens": int(outputTokens + thoughtsTokens),
+	}
+}
+
+// GeminiResponseToAnthropic transforms a Gemini API response to an Anthropic Messages API response
+func GeminiResponseToAnthropic(geminiResp map[string]interface{}, model string, includeThinking bool) map[string]interface{} {
+	content := make([]map[string]interface{}, 0)
+	finishReason := ""
+
+	// Anthropic messages carry a single response; use the first candidate
+	if candidates, ok := geminiResp["candidates"].([]interface{}); ok && len(candidates) > 0 {
+		candMap, _ := candidates[0].(map[string]interface{})
+		if candContent, ok := candMap["content"].(map[string]interface{}); ok {
+			parts, _ := candContent["parts"].([]interface{})
+			content = mergeAnthropicBlocks(GeminiPartsToAnthropicBlocks(parts, includeThinking))
+		}
+		finishReason, _ = candMap["finishReason"].(string)
+	}
+
+	hasToolUse := false
+	for _, block := range content {
+		if block["type"] == "tool_use" {
+			hasToolUse = true
+			break
+		}
+	}
+
+	usageMetadata, _ := geminiResp["usageMetadata"].(map[string]interface{})
+
+	return map[string]interface{}{
+		"id":            NewAnthropicMessageID(),
+		"type":          "message",
+		"role":          "assistant",
+		"model":         model,
+		"content":       content,
+		"stop_reason":   MapFinishReasonToAnthropic(finishReason, hasToolUse),
+		"stop_sequence": nil,
+		"usage":         AnthropicUsage(usageMetadata),
+	}
+}
+
+// mergeAnthropicBlocks joins adjacent text blocks and adjacent thinking blocks
+func mergeAnthropicBlocks(blocks []map[string]interface{}) []map[string]interface{} {
+	merged := make([]map[string]interface{}, 0, len(blocks))
+
+	for _, block := range blocks {
+		if len(merged) > 0 {
+			last := merged[len(merged)-1]
+			if last["type"] == block["type"] {
+				switch block["type"] {
+				case "text":
+					last["text"] = last["text"].(string) + block["text"].(string)
+					continue
+				case "thinking":
+					last["thinking"] = last["thinking"].(string) + block["thinking"].(string)
+					if signature, _ := block["signature"].(string); signature != "" {
+						last["signature"] = signature
+					}
+					continue
+				}
+			}
+		}
+		merged = append(merged, block)
+	}
+
+	return merged
+}
+
+// NewAnthropicMessageID returns a message ID in Anthropic's "msg_" format
+func NewAnthropicMessageID() string {
+	return "msg_" + strings.ReplaceAll(uuid.New().String(), "-", "")
+}
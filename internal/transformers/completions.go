@@ -0,0 +1,138 @@
+package transformers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gcli2apigo/internal/models"
+)
+
+// completionInstruction makes a chat model continue the prompt instead of answering it
+const completionInstruction = "You are a text completion engine. Continue the text in the user message from exactly where it ends. " +
+	"Reply with the continuation only: do not repeat the text, add commentary or wrap the output in quotes or code fences."
+
+// insertionInstruction is used instead of completionInstruction when the request has a suffix
+const insertionInstruction = "You are a text completion engine. The user message holds a <prefix> and a <suffix>. " +
+	"Reply with only the text that belongs between them, so that prefix, reply and suffix read as one continuous text. " +
+	"Do not repeat the prefix or the suffix and do not include the tags."
+
+// CompletionPrompts returns the prompts of a text completion request; a string is a single prompt.
+// Token-array prompts cannot be decoded without the OpenAI tokenizer and are rejected.
+func CompletionPrompts(prompt interface{}) ([]string, error) {
+	switch p := prompt.(type) {
+	case string:
+		return []string{p}, nil
+	case []interface{}:
+		if len(p) == 0 {
+			return nil, errors.New("prompt: must not be empty")
+		}
+		prompts := make([]string, 0, len(p))
+		for i, item := range p {
+			text, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("prompt[%d]: token arrays are not supported; send the prompt as text", i)
+			}
+			prompts = append(prompts, text)
+		}
+		return prompts, nil
+	case nil:
+		return nil, errors.New("prompt: Field required")
+	default:
+		return nil, errors.New("prompt: expected a string or an array of strings")
+	}
+}
+
+// CompletionRequestToGemini transforms one prompt of a text completion request to Gemini format.
+// The prompt is sent as a single user turn with an instruction to continue it, or to fill the gap up to
+// the suffix. The sampling parameters go through OpenAIRequestToGemini, so they are mapped exactly as
+// for chat completions and the result can be passed to client.BuildGeminiPayloadFromOpenAI.
+func CompletionRequestToGemini(req *models.OpenAICompletionRequest, prompt string) (map[string]interface{}, error) {
+	instruction := completionInstruction
+	content := prompt
+	if req.Suffix != "" {
+		instruction = insertionInstruction
+		content = fmt.Sprintf("<prefix>%s</prefix>\n<suffix>%s</suffix>", prompt, req.Suffix)
+	}
+
+	chatRequest := &models.OpenAIChatCompletionRequest{
+		Model:            req.Model,
+		Messages:         []models.OpenAIChatMessage{{Role: "user", Content: content}},
+		Temperature:      req.Temperature,
+		TopP:             req.TopP,
+		MaxTokens:        req.MaxTokens,
+		Stop:             req.Stop,
+		FrequencyPenalty: req.FrequencyPenalty,
+		PresencePenalty:  req.PresencePenalty,
+		N:                req.N,
+		Seed:             req.Seed,
+	}
+
+	requestPayload, err := OpenAIRequestToGemini(chatRequest)
+	if err != nil {
+		return nil, err
+	}
+	requestPayload["systemInstruction"] = map[string]interface{}{
+		"parts": []map[string]interface{}{{"text": instruction}},
+	}
+
+	return requestPayload, nil
+}
+
+// GeminiResponseToCompletionChoices converts the candidates of a Gemini response or stream chunk into
+// text completion choices numbered from firstIndex. echo is prepended to each text (the prompt when the
+// request set echo). finish_reason is null until the candidate reports one.
+func GeminiResponseToCompletionChoices(geminiResp map[string]interface{}, firstIndex int, echo string) []map[string]interface{} {
+	candidates, _ := geminiResp["candidates"].([]interface{})
+	choices := make([]map[string]interface{}, 0, len(candidates))
+
+	for _, candidate := range candidates {
+		candMap, _ := candidate.(map[string]interface{})
+		content, _ := candMap["content"].(map[string]interface{})
+		parts, _ := content["parts"].([]interface{})
+
+		// Thinking tokens and function calls have no place in a text completion
+		contentParts, _, _ := convertGeminiParts(parts)
+
+		index, _ := candMap["index"].(float64)
+		finishReason, _ := candMap["finishReason"].(string)
+
+		choices = append(choices, map[string]interface{}{
+			"text":          echo + strings.Join(contentParts, ""),
+			"index":         firstIndex + int(index),
+			"logprobs":      nil,
+			"finish_reason": MapFinishReason(finishReason),
+		})
+	}
+
+	return choices
+}
+
+// NewCompletionResponse builds a text_completion response or stream chunk
+func NewCompletionResponse(id string, model string, choices []map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"id":      id,
+		"object":  "text_completion",
+		"created": time.Now().Unix(),
+		"model":   model,
+		"choices": choices,
+	}
+}
+
+// CompletionUsage sums the Gemini usageMetadata of every prompt into an OpenAI usage object
+func CompletionUsage(usageMetadatas []map[string]interface{}) map[string]interface{} {
+	var promptTokens, completionTokens, totalTokens int
+	for _, usageMetadata := range usageMetadatas {
+		usage := GeminiUsageToOpenAI(usageMetadata)
+		promptTokens += usage["prompt_tokens"].(int)
+		completionTokens += usage["completion_tokens"].(int)
+		totalTokens += usage["total_tokens"].(int)
+	}
+
+	return map[string]interface{}{
+		"prompt_tokens":     promptTokens,
+		"completion_tokens": completionTokens,
+		"total_tokens":      totalTokens,
+	}
+}
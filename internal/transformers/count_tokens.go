@@ -0,0 +1,101 @@
+package transformers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// GeminiCountTokensRequest builds the Code Assist countTokens request for model from a Gemini
+// request. Code Assist only accepts model and contents, so the systemInstruction parts and the
+// JSON of the tools are prepended to the first user turn instead of being dropped; estimated
+// reports whether either was folded in, since the model sees them framed differently when
+// generating. generationConfig is not counted. A native countTokens body may wrap the request
+// in generateContentRequest.
+func GeminiCountTokensRequest(geminiRequest map[string]interface{}, model string) (request map[string]interface{}, estimated bool, err error) {
+	if wrapped, ok := geminiRequest["generateContentRequest"].(map[string]interface{}); ok {
+		geminiRequest = wrapped
+	}
+
+	contents := make([]interface{}, 0)
+	switch c := geminiRequest["contents"].(type) {
+	case []interface{}:
+		contents = append(contents, c...)
+	case []map[string]interface{}:
+		for _, content := range c {
+			contents = append(contents, content)
+		}
+	}
+
+	if len(contents) == 0 {
+		return nil, false, errors.New("contents: must not be empty")
+	}
+
+	var leading []interface{}
+	if systemInstruction, ok := geminiRequest["systemInstruction"]; ok && systemInstruction != nil {
+		leading = append(leading, partList(contentParts(systemInstruction))...)
+	}
+	if tools, ok := geminiRequest["tools"]; ok && tools != nil {
+		toolsJSON, err := json.Marshal(tools)
+		if err != nil {
+			return nil, false, fmt.Errorf("tools: %w", err)
+		}
+		leading = append(leading, map[string]interface{}{"text": string(toolsJSON)})
+	}
+
+	if len(leading) > 0 {
+		estimated = true
+		first, _ := contents[0].(map[string]interface{})
+		if role, _ := first["role"].(string); first != nil && (role == "" || role == "user") {
+			// Copy the first turn so the caller's request is left untouched
+			merged := make(map[string]interface{}, len(first))
+			for k, v := range first {
+				merged[k] = v
+			}
+			merged["parts"] = append(leading, partList(first["parts"])...)
+			contents[0] = merged
+		} else {
+			contents = append([]interface{}{map[string]interface{}{
+				"role":  "user",
+				"parts": leading,
+			}}, contents...)
+		}
+	}
+
+	return map[string]interface{}{
+		"model":    "models/" + model,
+		"contents": contents,
+	}, estimated, nil
+}
+
+// contentParts returns the parts of a Gemini content, which may be a decoded JSON object or a
+// map built by the transformers
+func contentParts(content interface{}) interface{} {
+	switch c := content.(type) {
+	case map[string]interface{}:
+		return c["parts"]
+	case string:
+		// The native API also accepts a bare string as systemInstruction
+		return []interface{}{map[string]interface{}{"text": c}}
+	}
+	return nil
+}
+
+// partList returns parts as a generic slice, whether they were decoded from JSON or built by the
+// transformers
+func partList(parts interface{}) []interface{} {
+	switch p := parts.(type) {
+	case []interface{}:
+		return p
+	case []map[string]interface{}:
+		list := make([]interface{}, 0, len(p))
+		for _, part := range p {
+			list = append(list, part)
+		}
+		return list
+	case map[string]interface{}:
+		// The native API also accepts a single part object
+		return []interface{}{p}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package transformers
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	"gcli2apigo/internal/config"
+	"gcli2apigo/internal/models"
+)
+
+// ResolveEmbeddingModel maps OpenAI embedding model names onto the configured Gemini embedding model
+func ResolveEmbeddingModel(model string) string {
+	model = strings.TrimPrefix(model, "models/")
+	if model == "" || strings.HasPrefix(model, "text-embedding-3") || strings.HasPrefix(model, "text-embedding-ada") {
+		return config.GetDefaultEmbeddingModel()
+	}
+	return model
+}
+
+// OpenAIEmbeddingInputs extracts the list of texts from an OpenAI embeddings input.
+// Token-array inputs are rejected since Gemini only embeds text.
+func OpenAIEmbeddingInputs(input interface{}) ([]string, error) {
+	switch v := input.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		if len(v) == 0 {
+			return nil, fmt.Errorf("input must not be empty")
+		}
+		texts := make([]string, 0, len(v))
+		for _, item := range v {
+			text, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("input must be a string or an array of strings; token arrays are not supported")
+			}
+			texts = append(texts, text)
+		}
+		return texts, nil
+	default:
+		return nil, fmt.Errorf("input must be a string or an array of strings")
+	}
+}
+
+// OpenAIEmbeddingRequestToGemini builds the Gemini embedding request for the given texts.
+// A single text uses embedContent; several texts use batchEmbedContents.
+func OpenAIEmbeddingRequestToGemini(req *models.OpenAIEmbeddingRequest, model string, texts []string) (string, map[string]interface{}) {
+	buildRequest := func(text string) map[string]interface{} {
+		request := map[string]interface{}{
+			"content": map[string]interface{}{
+				"parts": []map[string]interface{}{{"text": text}},
+			},
+		}
+		if req.Dimensions != nil && *req.Dimensions > 0 {
+			request["outputDimensionality"] = *req.Dimensions
+		}
+		return request
+	}
+
+	if len(texts) == 1 {
+		return "embedContent", buildRequest(texts[0])
+	}
+
+	requests := make([]map[string]interface{}, 0, len(texts))
+	for _, text := range texts {
+		request := buildRequest(text)
+		request["model"] = "models/" + model
+		requests = append(requests, request)
+	}
+
+	return "batchEmbedContents", map[string]interface{}{
+		"requests": requests,
+	}
+}
+
+// GeminiEmbeddingsToOpenAI transforms an embedContent or batchEmbedContents response to OpenAI format
+func GeminiEmbeddingsToOpenAI(geminiResp map[string]interface{}, model string, texts []string, encodingFormat string) (map[string]interface{}, error) {
+	var embeddings []interface{}
+	if embedding, ok := geminiResp["embedding"]; ok {
+		embeddings = []interface{}{embedding}
+	} else if list, ok := geminiResp["embeddings"].([]interface{}); ok {
+		embeddings = list
+	}
+
+	if len(embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(embeddings))
+	}
+
+	data := make([]map[string]interface{}, 0, len(embeddings))
+	for i, embedding := range embeddings {
+		embeddingMap, _ := embedding.(map[string]interface{})
+		rawValues, _ := embeddingMap["values"].([]interface{})
+
+		values := make([]float64, 0, len(rawValues))
+		for _, value := range rawValues {
+			if f, ok := value.(float64); ok {
+				values = append(values, f)
+			}
+		}
+
+		var encoded interface{} = values
+		if encodingFormat == "base64" {
+			encoded = encodeEmbeddingBase64(values)
+		}
+
+		data = append(data, map[string]interface{}{
+			"object":    "embedding",
+			"index":     i,
+			"embedding": encoded,
+		})
+	}
+
+	// Gemini embedding responses carry no token counts; estimate ~4 characters per token
+	promptTokens := 0
+	for _, text := range texts {
+		promptTokens += (len(text) + 3) / 4
+	}
+
+	return map[string]interface{}{
+		"object": "list",
+		"data":   data,
+		"model":  model,
+		"usage": map[string]interface{}{
+			"prompt_tokens": promptTokens,
+			"total_tokens":  promptTokens,
+		},
+	}, nil
+}
+
+// encodeEmbeddingBase64 encodes values as little-endian float32, matching OpenAI's base64 encoding_format
+func encodeEmbeddingBase64(values []float64) string {
+	buf := make([]byte, 4*len(values))
+	for i, value := range values {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(float32(value)))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
@@ -0,0 +1,587 @@
+package transformers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SchemaError reports a response_format or tool parameter schema that cannot be translated for Gemini.
+// Handlers return it to the client as a 400 invalid_request_error.
+type SchemaError struct {
+	Path    string // JSON path of the offending schema node, e.g. response_format.json_schema.schema.properties.tags
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Param returns the request field the schema belongs to, e.g. response_format, text.format or tools
+func (e *SchemaError) Param() string {
+	if strings.HasPrefix(e.Path, "text.format") {
+		return "text.format"
+	}
+	if i := strings.IndexAny(e.Path, ".["); i >= 0 {
+		return e.Path[:i]
+	}
+	return e.Path
+}
+
+// schemaAnnotationKeys are JSON Schema keywords that do not constrain output and are dropped
+var schemaAnnotationKeys = map[string]bool{
+	"$schema":     true,
+	"$id":         true,
+	"$comment":    true,
+	"$anchor":     true,
+	"strict":      true,
+	"readOnly":    true,
+	"writeOnly":   true,
+	"deprecated":  true,
+	"$defs":       true, // Inlined through $ref
+	"definitions": true,
+}
+
+// schemaUnsupportedKeys are JSON Schema keywords Gemini's responseSchema cannot express.
+// Requests using them are rejected rather than silently producing unconstrained output.
+var schemaUnsupportedKeys = map[string]bool{
+	"multipleOf":            true,
+	"uniqueItems":           true,
+	"contains":              true,
+	"minContains":           true,
+	"maxContains":           true,
+	"prefixItems":           true,
+	"additionalItems":       true,
+	"unevaluatedItems":      true,
+	"patternProperties":     true,
+	"propertyNames":         true,
+	"unevaluatedProperties": true,
+	"dependentRequired":     true,
+	"dependentSchemas":      true,
+	"dependencies":          true,
+	"if":                    true,
+	"then":                  true,
+	"else":                  true,
+	"not":                   true,
+	"$dynamicRef":           true,
+	"$recursiveRef":         true,
+}
+
+// schemaPassthroughKeys are copied to responseSchema unchanged
+var schemaPassthroughKeys = map[string]bool{
+	"title":         true,
+	"description":   true,
+	"pattern":       true,
+	"minLength":     true,
+	"maxLength":     true,
+	"minimum":       true,
+	"maximum":       true,
+	"minItems":      true,
+	"maxItems":      true,
+	"minProperties": true,
+	"maxProperties": true,
+	"required":      true,
+	"default":       true,
+	"example":       true,
+	"nullable":      true,
+}
+
+// geminiSchemaFormats lists the formats responseSchema accepts per type; other formats are
+// moved into the description so the model still sees them
+var geminiSchemaFormats = map[string]map[string]bool{
+	"STRING":  {"enum": true, "date-time": true},
+	"NUMBER":  {"float": true, "double": true},
+	"INTEGER": {"int32": true, "int64": true},
+}
+
+// OpenAIResponseFormatToGemini translates an OpenAI response_format into Gemini generationConfig fields.
+// "json_object" requests JSON output; "json_schema" also converts the JSON Schema into a Gemini
+// responseSchema. It returns nil for plain text output and a *SchemaError for formats or
+// schema features that cannot be translated.
+func OpenAIResponseFormatToGemini(responseFormat map[string]interface{}) (map[string]interface{}, error) {
+	if responseFormat == nil {
+		return nil, nil
+	}
+
+	formatType, _ := responseFormat["type"].(string)
+	switch formatType {
+	case "", "text":
+		return nil, nil
+	case "json_object":
+		return map[string]interface{}{"responseMimeType": "application/json"}, nil
+	case "json_schema":
+	default:
+		return nil, &SchemaError{Path: "response_format.type", Message: fmt.Sprintf("unsupported response format type %q", formatType)}
+	}
+
+	jsonSchema, ok := responseFormat["json_schema"].(map[string]interface{})
+	if !ok {
+		return nil, &SchemaError{Path: "response_format.json_schema", Message: "field required for type json_schema"}
+	}
+
+	fields := map[string]interface{}{"responseMimeType": "application/json"}
+	schema, ok := jsonSchema["schema"].(map[string]interface{})
+	if !ok {
+		// A schema is optional in the OpenAI API; without one the output is only required to be JSON
+		return fields, nil
+	}
+
+	converter := &schemaConverter{root: schema}
+	converted, err := converter.convert(schema, "response_format.json_schema.schema", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// The schema name and description are not part of responseSchema; keep the description as guidance
+	if description, _ := jsonSchema["description"].(string); description != "" {
+		if _, exists := converted["description"]; !exists {
+			converted["description"] = description
+		}
+	}
+
+	fields["responseSchema"] = converted
+	return fields, nil
+}
+
+// toolSchemaDroppedKeys are removed from function declaration parameters, which reject them.
+// The client validates the arguments it receives, so dropping them only loosens the model's guidance.
+var toolSchemaDroppedKeys = map[string]bool{
+	"additionalProperties": true,
+	"default":              true,
+	"examples":             true,
+	"example":              true,
+}
+
+// schemaConverter converts an OpenAI JSON Schema into Gemini's OpenAPI-style Schema, used for
+// responseSchema and for function declaration parameters
+type schemaConverter struct {
+	root map[string]interface{} // Document that local $ref pointers resolve against
+
+	// tools converts function parameters: constraints Gemini cannot express are kept as hints in
+	// the description instead of being rejected, since the client validates the arguments anyway
+	tools bool
+}
+
+// resolveRef returns the schema a local JSON pointer such as "#/$defs/Address" refers to
+func (sc *schemaConverter) resolveRef(ref string, path string) (map[string]interface{}, error) {
+	pointer, ok := strings.CutPrefix(ref, "#/")
+	if !ok {
+		if ref == "#" {
+			return nil, &SchemaError{Path: path, Message: "recursive $ref to the root schema is not supported"}
+		}
+		return nil, &SchemaError{Path: path, Message: fmt.Sprintf("only local $ref pointers are supported, got %q", ref)}
+	}
+
+	var node interface{} = sc.root
+	for _, token := range strings.Split(pointer, "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, &SchemaError{Path: path, Message: fmt.Sprintf("$ref %q does not resolve", ref)}
+		}
+		if node, ok = object[token]; !ok {
+			return nil, &SchemaError{Path: path, Message: fmt.Sprintf("$ref %q does not resolve", ref)}
+		}
+	}
+
+	resolved, ok := node.(map[string]interface{})
+	if !ok {
+		return nil, &SchemaError{Path: path, Message: fmt.Sprintf("$ref %q does not point to a schema", ref)}
+	}
+	return resolved, nil
+}
+
+// expandRef resolves a $ref for inlining. expanding holds the refs already being expanded on the
+// current branch; a ref that refers back to one of them is recursive, which responseSchema cannot express.
+func (sc *schemaConverter) expandRef(ref string, path string, expanding map[string]bool) (map[string]interface{}, map[string]bool, error) {
+	if expanding[ref] {
+		return nil, nil, &SchemaError{Path: path, Message: fmt.Sprintf("recursive $ref %q is not supported", ref)}
+	}
+	target, err := sc.resolveRef(ref, path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nested := make(map[string]bool, len(expanding)+1)
+	for r := range expanding {
+		nested[r] = true
+	}
+	nested[ref] = true
+	return target, nested, nil
+}
+
+// convert translates one schema node. expanding holds the $refs inlined on the current branch.
+func (sc *schemaConverter) convert(schema map[string]interface{}, path string, expanding map[string]bool) (map[string]interface{}, error) {
+	// $ref is inlined; sibling keywords (allowed since JSON Schema 2019-09) are merged over the target
+	if ref, ok := schema["$ref"].(string); ok {
+		target, nested, err := sc.expandRef(ref, path+".$ref", expanding)
+		if err != nil {
+			return nil, err
+		}
+		merged := make(map[string]interface{}, len(target)+len(schema))
+		for key, value := range target {
+			merged[key] = value
+		}
+		for key, value := range schema {
+			if key != "$ref" {
+				merged[key] = value
+			}
+		}
+		return sc.convert(merged, path, nested)
+	}
+
+	// allOf is merged into a single schema
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		merged, nested, err := sc.mergeAllOf(schema, allOf, path, expanding)
+		if err != nil {
+			return nil, err
+		}
+		return sc.convert(merged, path, nested)
+	}
+
+	converted := make(map[string]interface{})
+	var notes []string
+
+	for _, key := range sortedSchemaKeys(schema) {
+		value := schema[key]
+		keyPath := path + "." + key
+
+		switch {
+		case schemaAnnotationKeys[key]:
+			continue
+		case sc.tools && toolSchemaDroppedKeys[key]:
+			continue
+		case sc.tools && schemaUnsupportedKeys[key]:
+			if isScalarSchemaValue(value) {
+				notes = append(notes, fmt.Sprintf("%s: %v", key, value))
+			}
+			continue
+		case schemaUnsupportedKeys[key]:
+			return nil, &SchemaError{Path: keyPath, Message: fmt.Sprintf("JSON Schema keyword %q is not supported by Gemini structured output", key)}
+		case schemaPassthroughKeys[key]:
+			converted[key] = value
+			continue
+		}
+
+		switch key {
+		case "type":
+			if err := convertSchemaType(value, converted, keyPath); err != nil {
+				return nil, err
+			}
+
+		case "format":
+			// Checked against the type once all keywords are converted
+			converted["format"] = value
+
+		case "enum":
+			values, ok := value.([]interface{})
+			if !ok {
+				return nil, &SchemaError{Path: keyPath, Message: "enum must be an array"}
+			}
+			if err := convertStringEnum(values, converted, keyPath); err != nil {
+				if !sc.tools {
+					return nil, err
+				}
+				delete(converted, "enum")
+				notes = append(notes, "one of "+schemaValueJSON(values))
+			}
+
+		case "const":
+			if err := convertStringEnum([]interface{}{value}, converted, keyPath); err != nil {
+				if !sc.tools {
+					return nil, err
+				}
+				delete(converted, "enum")
+				notes = append(notes, "must be "+schemaValueJSON(value))
+			}
+
+		case "examples":
+			if examples, ok := value.([]interface{}); ok && len(examples) > 0 {
+				converted["example"] = examples[0]
+			}
+
+		case "exclusiveMinimum", "exclusiveMaximum":
+			bound, ok := value.(float64)
+			if !ok {
+				// Draft 4 boolean form modifies minimum/maximum; the bound itself is still enforced
+				notes = append(notes, fmt.Sprintf("%s: %v", key, value))
+				continue
+			}
+			// Integer bounds can be made inclusive exactly; other numbers keep a note
+			isInteger := schema["type"] == "integer" && bound == float64(int64(bound))
+			switch {
+			case key == "exclusiveMinimum" && isInteger:
+				converted["minimum"] = bound + 1
+			case key == "exclusiveMaximum" && isInteger:
+				converted["maximum"] = bound - 1
+			case key == "exclusiveMinimum":
+				converted["minimum"] = bound
+				notes = append(notes, fmt.Sprintf("must be greater than %v", bound))
+			default:
+				converted["maximum"] = bound
+				notes = append(notes, fmt.Sprintf("must be less than %v", bound))
+			}
+
+		case "additionalProperties":
+			// Gemini objects only allow their declared properties, which is what strict
+			// schemas ask for with additionalProperties: false
+			if allowed, ok := value.(bool); ok && !allowed {
+				continue
+			}
+			return nil, &SchemaError{Path: keyPath, Message: "only additionalProperties: false is supported; Gemini objects cannot have arbitrary keys"}
+
+		case "properties":
+			properties, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, &SchemaError{Path: keyPath, Message: "properties must be an object"}
+			}
+			convertedProps := make(map[string]interface{}, len(properties))
+			for _, name := range sortedSchemaKeys(properties) {
+				propSchema, ok := properties[name].(map[string]interface{})
+				if !ok {
+					return nil, &SchemaError{Path: keyPath + "." + name, Message: "property schema must be an object"}
+				}
+				convertedProp, err := sc.convert(propSchema, keyPath+"."+name, expanding)
+				if err != nil {
+					return nil, err
+				}
+				convertedProps[name] = convertedProp
+			}
+			converted["properties"] = convertedProps
+
+		case "items":
+			itemSchema, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, &SchemaError{Path: keyPath, Message: "tuple-style items are not supported; items must be a single schema"}
+			}
+			convertedItems, err := sc.convert(itemSchema, keyPath, expanding)
+			if err != nil {
+				return nil, err
+			}
+			converted["items"] = convertedItems
+
+		case "anyOf", "oneOf":
+			// Gemini has no oneOf; generated output matches exactly one branch in practice
+			variants, ok := value.([]interface{})
+			if !ok || len(variants) == 0 {
+				return nil, &SchemaError{Path: keyPath, Message: key + " must be a non-empty array"}
+			}
+			convertedVariants := make([]interface{}, 0, len(variants))
+			for i, variant := range variants {
+				variantSchema, ok := variant.(map[string]interface{})
+				if !ok {
+					return nil, &SchemaError{Path: fmt.Sprintf("%s[%d]", keyPath, i), Message: "schema must be an object"}
+				}
+				// {"type": "null"} variants (e.g. Optional fields) become nullable
+				if variantType, _ := variantSchema["type"].(string); variantType == "null" {
+					converted["nullable"] = true
+					continue
+				}
+				convertedVariant, err := sc.convert(variantSchema, fmt.Sprintf("%s[%d]", keyPath, i), expanding)
+				if err != nil {
+					return nil, err
+				}
+				convertedVariants = append(convertedVariants, convertedVariant)
+			}
+
+			switch len(convertedVariants) {
+			case 0:
+				return nil, &SchemaError{Path: keyPath, Message: `a schema that only allows "null" is not supported`}
+			case 1:
+				// A single remaining variant is inlined
+				for variantKey, variantValue := range convertedVariants[0].(map[string]interface{}) {
+					if _, exists := converted[variantKey]; !exists {
+						converted[variantKey] = variantValue
+					}
+				}
+			default:
+				converted["anyOf"] = convertedVariants
+			}
+
+		default:
+			if sc.tools {
+				// Function parameters are often generated with vendor extensions; they carry no constraint
+				continue
+			}
+			return nil, &SchemaError{Path: keyPath, Message: fmt.Sprintf("JSON Schema keyword %q is not supported by Gemini structured output", key)}
+		}
+	}
+
+	// Formats Gemini does not know become part of the description
+	if format, ok := converted["format"].(string); ok {
+		schemaType, _ := converted["type"].(string)
+		if !geminiSchemaFormats[schemaType][format] {
+			delete(converted, "format")
+			notes = append(notes, "format: "+format)
+		}
+	}
+
+	if len(notes) > 0 {
+		description, _ := converted["description"].(string)
+		if description != "" {
+			description += " "
+		}
+		converted["description"] = description + "(" + strings.Join(notes, "; ") + ")"
+	}
+
+	return converted, nil
+}
+
+// mergeAllOf merges the allOf branches of a schema into one. Object branches are combined by
+// merging their properties and required lists; conflicting keywords are rejected. It also returns
+// the $refs expanded while inlining referenced branches.
+func (sc *schemaConverter) mergeAllOf(schema map[string]interface{}, allOf []interface{}, path string, expanding map[string]bool) (map[string]interface{}, map[string]bool, error) {
+	merged := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		if key != "allOf" {
+			merged[key] = value
+		}
+	}
+
+	for i, branch := range allOf {
+		branchPath := fmt.Sprintf("%s.allOf[%d]", path, i)
+		branchSchema, ok := branch.(map[string]interface{})
+		if !ok {
+			return nil, nil, &SchemaError{Path: branchPath, Message: "schema must be an object"}
+		}
+		// Inline a $ref branch before merging
+		for ref, hasRef := branchSchema["$ref"].(string); hasRef; ref, hasRef = branchSchema["$ref"].(string) {
+			target, nested, err := sc.expandRef(ref, branchPath+".$ref", expanding)
+			if err != nil {
+				return nil, nil, err
+			}
+			branchSchema, expanding = target, nested
+		}
+
+		for key, value := range branchSchema {
+			existing, exists := merged[key]
+			switch {
+			case !exists:
+				merged[key] = value
+			case key == "properties":
+				properties := make(map[string]interface{})
+				for name, prop := range existing.(map[string]interface{}) {
+					properties[name] = prop
+				}
+				branchProps, _ := value.(map[string]interface{})
+				for name, prop := range branchProps {
+					if _, duplicate := properties[name]; duplicate {
+						return nil, nil, &SchemaError{Path: branchPath + ".properties." + name, Message: "property is defined by more than one allOf branch"}
+					}
+					properties[name] = prop
+				}
+				merged[key] = properties
+			case key == "required":
+				existingList, _ := existing.([]interface{})
+				branchList, _ := value.([]interface{})
+				merged[key] = append(append([]interface{}(nil), existingList...), branchList...)
+			case key == "description" || key == "title":
+				// The outermost annotation wins
+			case fmt.Sprint(existing) == fmt.Sprint(value):
+				// Same constraint in several branches
+			default:
+				return nil, nil, &SchemaError{Path: branchPath + "." + key, Message: "conflicting allOf branches cannot be merged"}
+			}
+		}
+	}
+
+	return merged, expanding, nil
+}
+
+// convertSchemaType maps a JSON Schema type (or list of types) onto responseSchema's type and nullable
+func convertSchemaType(value interface{}, converted map[string]interface{}, path string) error {
+	var types []string
+	switch t := value.(type) {
+	case string:
+		types = []string{t}
+	case []interface{}:
+		for _, item := range t {
+			name, ok := item.(string)
+			if !ok {
+				return &SchemaError{Path: path, Message: "type must be a string or an array of strings"}
+			}
+			types = append(types, name)
+		}
+	default:
+		return &SchemaError{Path: path, Message: "type must be a string or an array of strings"}
+	}
+
+	nonNull := make([]string, 0, len(types))
+	for _, name := range types {
+		if name == "null" {
+			converted["nullable"] = true
+			continue
+		}
+		switch name {
+		case "string", "number", "integer", "boolean", "array", "object":
+			nonNull = append(nonNull, strings.ToUpper(name))
+		default:
+			return &SchemaError{Path: path, Message: fmt.Sprintf("unknown type %q", name)}
+		}
+	}
+
+	switch len(nonNull) {
+	case 0:
+		return &SchemaError{Path: path, Message: `a schema that only allows "null" is not supported`}
+	case 1:
+		converted["type"] = nonNull[0]
+	default:
+		// Several primitive types become alternatives
+		variants := make([]interface{}, 0, len(nonNull))
+		for _, name := range nonNull {
+			if name == "ARRAY" || name == "OBJECT" {
+				return &SchemaError{Path: path, Message: "a type list may only combine primitive types with null"}
+			}
+			variants = append(variants, map[string]interface{}{"type": name})
+		}
+		converted["anyOf"] = variants
+	}
+	return nil
+}
+
+// convertStringEnum converts enum or const values; responseSchema enums only hold strings,
+// and a null value is expressed through nullable
+func convertStringEnum(values []interface{}, converted map[string]interface{}, path string) error {
+	enum := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		switch v := value.(type) {
+		case string:
+			enum = append(enum, v)
+		case nil:
+			converted["nullable"] = true
+		default:
+			return &SchemaError{Path: path, Message: fmt.Sprintf("only string values are supported, got %v", value)}
+		}
+	}
+	converted["enum"] = enum
+	return nil
+}
+
+// isScalarSchemaValue reports whether a keyword value is short enough to repeat in a description
+func isScalarSchemaValue(value interface{}) bool {
+	switch value.(type) {
+	case bool, float64, string:
+		return true
+	}
+	return false
+}
+
+// schemaValueJSON formats a schema value for a description hint
+func schemaValueJSON(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// sortedSchemaKeys returns the keys of a schema object in a stable order so errors are deterministic
+func sortedSchemaKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
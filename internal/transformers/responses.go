@@ -0,0 +1,568 @@
+package transformers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"gcli2apigo/internal/config"
+	"gcli2apigo/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// reasoningEffortBudgets maps Responses API reasoning effort levels to Gemini thinking budgets
+var reasoningEffortBudgets = map[string]int{
+	"minimal": 128, // Lowest budget gemini-2.5-pro accepts
+	"low":     1024,
+	"medium":  8192,
+	"high":    24576,
+}
+
+// ResponsesInputItems normalises the input of a Responses API request into a list of items.
+// A string input is a single user message.
+func ResponsesInputItems(input interface{}) ([]map[string]interface{}, error) {
+	switch in := input.(type) {
+	case string:
+		return []map[string]interface{}{
+			{"type": "message", "role": "user", "content": in},
+		}, nil
+	case []interface{}:
+		items := make([]map[string]interface{}, 0, len(in))
+		for i, item := range in {
+			itemMap, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("input[%d]: expected an object", i)
+			}
+			items = append(items, itemMap)
+		}
+		return items, nil
+	case nil:
+		return nil, errors.New("input: Field required")
+	default:
+		return nil, errors.New("input: expected a string or an array of items")
+	}
+}
+
+// IncludeReasoningSummary reports whether a Responses API request asked for reasoning summaries
+func IncludeReasoningSummary(req *models.ResponsesRequest) bool {
+	return req.Reasoning != nil && req.Reasoning.Summary != "" && req.Reasoning.Summary != "none"
+}
+
+// ResponsesRequestToGemini transforms a Responses API request to Gemini format. items is the conversation
+// to send: the items stored for previous_response_id, if any, followed by the request input.
+// The items are mapped to chat messages and sent through OpenAIRequestToGemini, so the result has the same
+// shape and can be passed to client.BuildGeminiPayloadFromOpenAI. It returns a *SchemaError if
+// text.format or a tool's parameters cannot be translated.
+func ResponsesRequestToGemini(req *models.ResponsesRequest, items []map[string]interface{}) (map[string]interface{}, error) {
+	chatRequest := &models.OpenAIChatCompletionRequest{
+		Model:             req.Model,
+		Messages:          responsesItemsToChatMessages(items),
+		Temperature:       req.Temperature,
+		TopP:              req.TopP,
+		MaxTokens:         req.MaxOutputTokens,
+		Tools:             responsesToolsToOpenAI(req.Tools),
+		ToolChoice:        responsesToolChoiceToOpenAI(req.ToolChoice),
+		ParallelToolCalls: req.ParallelToolCalls,
+	}
+	if req.Text != nil {
+		chatRequest.ResponseFormat = responsesFormatToOpenAI(req.Text.Format)
+	}
+
+	requestPayload, err := OpenAIRequestToGemini(chatRequest)
+	if err != nil {
+		var schemaErr *SchemaError
+		if errors.As(err, &schemaErr) {
+			// Report the path in terms of text.format and the flat Responses tools rather than
+			// the chat request fields they were mapped to
+			path := strings.Replace(schemaErr.Path, "response_format.json_schema", "text.format", 1)
+			path = strings.Replace(path, "response_format", "text.format", 1)
+			path = strings.Replace(path, ".function.parameters", ".parameters", 1)
+			return nil, &SchemaError{Path: path, Message: schemaErr.Message}
+		}
+		return nil, err
+	}
+
+	if req.Instructions != "" {
+		requestPayload["systemInstruction"] = map[string]interface{}{
+			"parts": []map[string]interface{}{{"text": req.Instructions}},
+		}
+	}
+
+	if req.Reasoning != nil {
+		generationConfig := requestPayload["generationConfig"].(map[string]interface{})
+		thinkingConfig := generationConfig["thinkingConfig"].(map[string]interface{})
+		if budget, ok := reasoningEffortBudgets[req.Reasoning.Effort]; ok {
+			thinkingConfig["thinkingBudget"] = budget
+		}
+		if IncludeReasoningSummary(req) {
+			thinkingConfig["includeThoughts"] = true
+		}
+	}
+
+	return requestPayload, nil
+}
+
+// responsesItemsToChatMessages maps Responses API input and output items to chat messages
+func responsesItemsToChatMessages(items []map[string]interface{}) []models.OpenAIChatMessage {
+	messages := make([]models.OpenAIChatMessage, 0, len(items))
+
+	for _, item := range items {
+		itemType, _ := item["type"].(string)
+		if itemType == "" && item["role"] != nil {
+			// Shorthand messages ({"role": ..., "content": ...}) have no type
+			itemType = "message"
+		}
+
+		switch itemType {
+		case "message":
+			role, _ := item["role"].(string)
+			if role == "developer" {
+				role = "system"
+			}
+			messages = append(messages, models.OpenAIChatMessage{
+				Role:    role,
+				Content: responsesContentToChat(item["content"]),
+			})
+
+		case "function_call":
+			callID, _ := item["call_id"].(string)
+			name, _ := item["name"].(string)
+			arguments, _ := item["arguments"].(string)
+			toolCall := models.ToolCall{
+				ID:       callID,
+				Type:     "function",
+				Function: models.ToolCallFunction{Name: name, Arguments: arguments},
+			}
+
+			// Parallel calls are separate items; keep them in the same assistant turn
+			if n := len(messages); n > 0 && messages[n-1].Role == "assistant" {
+				messages[n-1].ToolCalls = append(messages[n-1].ToolCalls, toolCall)
+			} else {
+				messages = append(messages, models.OpenAIChatMessage{
+					Role:      "assistant",
+					ToolCalls: []models.ToolCall{toolCall},
+				})
+			}
+
+		case "function_call_output":
+			callID, _ := item["call_id"].(string)
+			messages = append(messages, models.OpenAIChatMessage{
+				Role:       "tool",
+				ToolCallID: callID,
+				Content:    item["output"],
+			})
+
+		case "reasoning":
+			// Previous reasoning is not replayed upstream; Gemini regenerates it per turn
+			continue
+
+		default:
+			if config.IsDebugEnabled() {
+				log.Printf("[DEBUG] Skipping unsupported Responses input item type: %s", itemType)
+			}
+		}
+	}
+
+	return messages
+}
+
+// responsesContentToChat converts message content (a string or a list of input/output content parts)
+// into chat message content
+func responsesContentToChat(content interface{}) interface{} {
+	parts, ok := content.([]interface{})
+	if !ok {
+		return content
+	}
+
+	chatParts := make([]interface{}, 0, len(parts))
+	for _, part := range parts {
+		partMap, ok := part.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		switch partType, _ := partMap["type"].(string); partType {
+		case "input_text", "output_text", "text":
+			text, _ := partMap["text"].(string)
+			chatParts = append(chatParts, map[string]interface{}{"type": "text", "text": text})
+
+		case "refusal":
+			refusal, _ := partMap["refusal"].(string)
+			chatParts = append(chatParts, map[string]interface{}{"type": "text", "text": refusal})
+
+		case "input_image":
+			url, _ := partMap["image_url"].(string)
+			if url == "" {
+				log.Printf("[WARN] Skipping input_image without image_url (file IDs are not supported)")
+				continue
+			}
+			chatParts = append(chatParts, map[string]interface{}{
+				"type":      "image_url",
+				"image_url": map[string]interface{}{"url": url},
+			})
+
+		case "input_file":
+			// Data URIs keep their MIME type when parsed, so files are sent the same way as images
+			fileData, _ := partMap["file_data"].(string)
+			if !strings.HasPrefix(fileData, "data:") {
+				log.Printf("[WARN] Skipping input_file without a data URI in file_data (file IDs and URLs are not supported)")
+				continue
+			}
+			chatParts = append(chatParts, map[string]interface{}{
+				"type":      "image_url",
+				"image_url": map[string]interface{}{"url": fileData},
+			})
+
+		default:
+			if config.IsDebugEnabled() {
+				log.Printf("[DEBUG] Skipping unsupported Responses content part type: %s", partType)
+			}
+		}
+	}
+
+	return chatParts
+}
+
+// responsesToolsToOpenAI converts Responses API tools, whose function fields are flat, into chat tools.
+// Non-function tools are passed on and skipped by OpenAIToolsToGemini.
+func responsesToolsToOpenAI(tools []models.ResponsesTool) []models.OpenAITool {
+	converted := make([]models.OpenAITool, 0, len(tools))
+	for _, tool := range tools {
+		converted = append(converted, models.OpenAITool{
+			Type: tool.Type,
+			Function: models.OpenAIFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+				Strict:      tool.Strict,
+			},
+		})
+	}
+	return converted
+}
+
+// responsesToolChoiceToOpenAI converts a Responses API tool_choice into the chat form.
+// Function choices are flat in the Responses API: {"type": "function", "name": "my_function"}
+func responsesToolChoiceToOpenAI(toolChoice interface{}) interface{} {
+	choice, ok := toolChoice.(map[string]interface{})
+	if !ok {
+		return toolChoice
+	}
+
+	name, _ := choice["name"].(string)
+	if choice["type"] != "function" || name == "" {
+		return nil
+	}
+	return map[string]interface{}{
+		"type":     "function",
+		"function": map[string]interface{}{"name": name},
+	}
+}
+
+// responsesFormatToOpenAI converts text.format into a chat response_format.
+// The json_schema fields (name, schema, strict) are flat in the Responses API.
+func responsesFormatToOpenAI(format map[string]interface{}) map[string]interface{} {
+	if format == nil || format["type"] != "json_schema" {
+		return format
+	}
+
+	jsonSchema := make(map[string]interface{}, len(format))
+	for key, value := range format {
+		if key != "type" {
+			jsonSchema[key] = value
+		}
+	}
+	return map[string]interface{}{
+		"type":        "json_schema",
+		"json_schema": jsonSchema,
+	}
+}
+
+// GeminiPartsToResponsesOutput converts Gemini response parts into Responses API output content, in order:
+// {"type": "output_text"} for message text, {"type": "summary_text"} for reasoning (only when
+// includeReasoning is set) and {"type": "function_call"} for function calls
+func GeminiPartsToResponsesOutput(parts []interface{}, includeReasoning bool) []map[string]interface{} {
+	outputs := make([]map[string]interface{}, 0, len(parts))
+
+	for _, part := range parts {
+		partMap, _ := part.(map[string]interface{})
+
+		if text, ok := partMap["text"].(string); ok {
+			if thought, _ := partMap["thought"].(bool); thought {
+				if includeReasoning && text != "" {
+					outputs = append(outputs, map[string]interface{}{"type": "summary_text", "text": text})
+				}
+				continue
+			}
+			if text != "" {
+				outputs = append(outputs, map[string]interface{}{"type": "output_text", "text": text})
+			}
+			continue
+		}
+
+		if _, ok := partMap["functionCall"].(map[string]interface{}); ok {
+			toolCall := geminiFunctionCallToOpenAI(partMap)
+			function := toolCall["function"].(map[string]interface{})
+			outputs = append(outputs, map[string]interface{}{
+				"type":      "function_call",
+				"call_id":   toolCall["id"],
+				"name":      function["name"],
+				"arguments": function["arguments"],
+			})
+			continue
+		}
+
+		// Inline images are embedded as Markdown data URIs, as in chat completions
+		if inlineData, ok := partMap["inlineData"].(map[string]interface{}); ok {
+			data, _ := inlineData["data"].(string)
+			mimeType, _ := inlineData["mimeType"].(string)
+			if mimeType == "" {
+				mimeType = "image/png"
+			}
+			if data != "" && strings.HasPrefix(mimeType, "image/") {
+				outputs = append(outputs, map[string]interface{}{
+					"type": "output_text",
+					"text": fmt.Sprintf("![image](data:%s;base64,%s)", mimeType, data),
+				})
+			}
+		}
+	}
+
+	return outputs
+}
+
+// ResponsesOutputItems groups output content into output items, joining adjacent text of the same type
+func ResponsesOutputItems(outputs []map[string]interface{}) []map[string]interface{} {
+	items := make([]map[string]interface{}, 0, len(outputs))
+
+	var text strings.Builder
+	textType := ""
+	flushText := func() {
+		switch textType {
+		case "output_text":
+			items = append(items, ResponsesMessageItem(NewResponsesID("msg"), text.String(), "completed"))
+		case "summary_text":
+			items = append(items, ResponsesReasoningItem(NewResponsesID("rs"), text.String()))
+		}
+		text.Reset()
+		textType = ""
+	}
+
+	for _, output := range outputs {
+		outputType, _ := output["type"].(string)
+		if outputType == "function_call" {
+			flushText()
+			items = append(items, ResponsesFunctionCallItem(NewResponsesID("fc"), output, "completed"))
+			continue
+		}
+		if outputType != textType {
+			flushText()
+			textType = outputType
+		}
+		outputText, _ := output["text"].(string)
+		text.WriteString(outputText)
+	}
+	flushText()
+
+	return items
+}
+
+// ResponsesMessageItem builds an assistant message output item holding a single output_text part.
+// Items that are still in progress have no content yet, as in the streamed output_item.added event.
+func ResponsesMessageItem(id string, text string, status string) map[string]interface{} {
+	content := []interface{}{}
+	if status == "completed" {
+		content = append(content, ResponsesOutputTextPart(text))
+	}
+	return map[string]interface{}{
+		"id":      id,
+		"type":    "message",
+		"status":  status,
+		"role":    "assistant",
+		"content": content,
+	}
+}
+
+// ResponsesOutputTextPart builds an output_text content part
+func ResponsesOutputTextPart(text string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "output_text",
+		"text":        text,
+		"annotations": []interface{}{},
+	}
+}
+
+// ResponsesReasoningItem builds a reasoning output item with the thought text as its summary
+func ResponsesReasoningItem(id string, text string) map[string]interface{} {
+	summary := []interface{}{}
+	if text != "" {
+		summary = append(summary, map[string]interface{}{"type": "summary_text", "text": text})
+	}
+	return map[string]interface{}{
+		"id":      id,
+		"type":    "reasoning",
+		"summary": summary,
+	}
+}
+
+// ResponsesFunctionCallItem builds a function_call output item from function_call output content.
+// Arguments are left empty while the item is in progress; they are streamed as deltas.
+func ResponsesFunctionCallItem(id string, call map[string]interface{}, status string) map[string]interface{} {
+	arguments := call["arguments"]
+	if status != "completed" {
+		arguments = ""
+	}
+	return map[string]interface{}{
+		"id":        id,
+		"type":      "function_call",
+		"status":    status,
+		"call_id":   call["call_id"],
+		"name":      call["name"],
+		"arguments": arguments,
+	}
+}
+
+// NewResponsesObject builds a Responses API response object with status "in_progress" and no output,
+// echoing the request settings that clients read back
+func NewResponsesObject(id string, req *models.ResponsesRequest, createdAt int64) map[string]interface{} {
+	var instructions, previousResponseID, maxOutputTokens interface{}
+	if req.Instructions != "" {
+		instructions = req.Instructions
+	}
+	if req.PreviousResponseID != "" {
+		previousResponseID = req.PreviousResponseID
+	}
+	if req.MaxOutputTokens != nil {
+		maxOutputTokens = *req.MaxOutputTokens
+	}
+
+	temperature, topP := 1.0, 1.0
+	if req.Temperature != nil {
+		temperature = *req.Temperature
+	}
+	if req.TopP != nil {
+		topP = *req.TopP
+	}
+
+	tools := req.Tools
+	if tools == nil {
+		tools = []models.ResponsesTool{}
+	}
+	toolChoice := req.ToolChoice
+	if toolChoice == nil {
+		toolChoice = "auto"
+	}
+	text := map[string]interface{}{"format": map[string]interface{}{"type": "text"}}
+	if req.Text != nil && req.Text.Format != nil {
+		text = map[string]interface{}{"format": req.Text.Format}
+	}
+	reasoning := map[string]interface{}{"effort": nil, "summary": nil}
+	if req.Reasoning != nil {
+		if req.Reasoning.Effort != "" {
+			reasoning["effort"] = req.Reasoning.Effort
+		}
+		if req.Reasoning.Summary != "" {
+			reasoning["summary"] = req.Reasoning.Summary
+		}
+	}
+	metadata := req.Metadata
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+
+	return map[string]interface{}{
+		"id":                   id,
+		"object":               "response",
+		"created_at":           createdAt,
+		"status":               "in_progress",
+		"error":                nil,
+		"incomplete_details":   nil,
+		"instructions":         instructions,
+		"max_output_tokens":    maxOutputTokens,
+		"model":                req.Model,
+		"output":               []interface{}{},
+		"parallel_tool_calls":  req.ParallelToolCalls == nil || *req.ParallelToolCalls,
+		"previous_response_id": previousResponseID,
+		"reasoning":            reasoning,
+		"store":                req.Store == nil || *req.Store,
+		"temperature":          temperature,
+		"text":                 text,
+		"tool_choice":          toolChoice,
+		"tools":                tools,
+		"top_p":                topP,
+		"truncation":           "disabled",
+		"usage":                nil,
+		"user":                 nilIfEmpty(req.User),
+		"metadata":             metadata,
+	}
+}
+
+// CompleteResponsesObject sets the output, usage and final status of a response object.
+// Like the Responses API, a response cut off by the token limit or a safety stop is "incomplete".
+func CompleteResponsesObject(response map[string]interface{}, output []map[string]interface{}, finishReason string, usageMetadata map[string]interface{}) {
+	response["output"] = output
+	if usageMetadata != nil {
+		response["usage"] = ResponsesUsage(usageMetadata)
+	}
+
+	switch finishReason {
+	case "MAX_TOKENS":
+		response["status"] = "incomplete"
+		response["incomplete_details"] = map[string]interface{}{"reason": "max_output_tokens"}
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII":
+		response["status"] = "incomplete"
+		response["incomplete_details"] = map[string]interface{}{"reason": "content_filter"}
+	default:
+		response["status"] = "completed"
+	}
+}
+
+// ResponsesUsage converts Gemini usageMetadata into Responses API usage.
+// Thinking tokens are billed as output, so they count towards output_tokens.
+func ResponsesUsage(usageMetadata map[string]interface{}) map[string]interface{} {
+	usage := GeminiUsageToOpenAI(usageMetadata)
+	return map[string]interface{}{
+		"input_tokens":          usage["prompt_tokens"],
+		"input_tokens_details":  usage["prompt_tokens_details"],
+		"output_tokens":         usage["completion_tokens"],
+		"output_tokens_details": usage["completion_tokens_details"],
+		"total_tokens":          usage["total_tokens"],
+	}
+}
+
+// GeminiResponseToResponses transforms a Gemini API response to a Responses API response object
+func GeminiResponseToResponses(geminiResp map[string]interface{}, req *models.ResponsesRequest, id string, createdAt int64) map[string]interface{} {
+	var outputs []map[string]interface{}
+	finishReason := ""
+
+	// A response has a single output; use the first candidate
+	if candidates, ok := geminiResp["candidates"].([]interface{}); ok && len(candidates) > 0 {
+		candMap, _ := candidates[0].(map[string]interface{})
+		if content, ok := candMap["content"].(map[string]interface{}); ok {
+			parts, _ := content["parts"].([]interface{})
+			outputs = GeminiPartsToResponsesOutput(parts, IncludeReasoningSummary(req))
+		}
+		finishReason, _ = candMap["finishReason"].(string)
+	}
+
+	usageMetadata, _ := geminiResp["usageMetadata"].(map[string]interface{})
+
+	response := NewResponsesObject(id, req, createdAt)
+	CompleteResponsesObject(response, ResponsesOutputItems(outputs), finishReason, usageMetadata)
+	return response
+}
+
+// NewResponsesID returns an ID in the Responses API format, e.g. "resp_..." or "msg_..."
+func NewResponsesID(prefix string) string {
+	return prefix + "_" + strings.ReplaceAll(uuid.New().String(), "-", "")
+}
+
+// nilIfEmpty returns nil for an empty string so it is encoded as JSON null
+func nilIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,243 @@
+package transformers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"gcli2apigo/internal/config"
+	"gcli2apigo/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// thoughtSignatureCacheSize bounds how many tool call thought signatures are remembered
+const thoughtSignatureCacheSize = 10000
+
+// thoughtSignatures remembers the thought signature of each function call returned to a client,
+// by tool call ID, for clients that do not send extra_content back with the assistant turn
+var thoughtSignatures = &signatureCache{signatures: make(map[string]string)}
+
+// signatureCache is a bounded map of tool call IDs to thought signatures; the oldest
+// entries are evicted first
+type signatureCache struct {
+	mu         sync.Mutex
+	signatures map[string]string
+	order      []string
+}
+
+func (sc *signatureCache) put(toolCallID, signature string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if _, exists := sc.signatures[toolCallID]; !exists {
+		sc.order = append(sc.order, toolCallID)
+	}
+	sc.signatures[toolCallID] = signature
+	for len(sc.order) > thoughtSignatureCacheSize {
+		delete(sc.signatures, sc.order[0])
+		sc.order = sc.order[1:]
+	}
+}
+
+func (sc *signatureCache) get(toolCallID string) string {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.signatures[toolCallID]
+}
+
+// OpenAIToolsToGemini converts OpenAI tool definitions into Gemini functionDeclarations.
+// It returns a *SchemaError if the parameters of a tool cannot be translated.
+func OpenAIToolsToGemini(tools []models.OpenAITool) ([]map[string]interface{}, error) {
+	declarations := make([]map[string]interface{}, 0, len(tools))
+
+	for i, tool := range tools {
+		if tool.Type != "" && tool.Type != "function" {
+			if config.IsDebugEnabled() {
+				log.Printf("[DEBUG] Skipping unsupported tool type: %s", tool.Type)
+			}
+			continue
+		}
+		if tool.Function.Name == "" {
+			continue
+		}
+
+		declaration := map[string]interface{}{
+			"name": tool.Function.Name,
+		}
+		if tool.Function.Description != "" {
+			declaration["description"] = tool.Function.Description
+		}
+		if len(tool.Function.Parameters) > 0 {
+			parameters, err := toolParametersToGemini(tool.Function.Parameters, fmt.Sprintf("tools[%d].function.parameters", i))
+			if err != nil {
+				return nil, err
+			}
+			declaration["parameters"] = parameters
+		}
+
+		declarations = append(declarations, declaration)
+	}
+
+	if len(declarations) == 0 {
+		return nil, nil
+	}
+
+	return []map[string]interface{}{
+		{"functionDeclarations": declarations},
+	}, nil
+}
+
+// OpenAIToolChoiceToGemini converts an OpenAI tool_choice value into a Gemini toolConfig
+// Returns nil when the default behaviour (AUTO) should be used
+func OpenAIToolChoiceToGemini(toolChoice interface{}) map[string]interface{} {
+	var callingConfig map[string]interface{}
+
+	switch choice := toolChoice.(type) {
+	case string:
+		switch choice {
+		case "none":
+			callingConfig = map[string]interface{}{"mode": "NONE"}
+		case "required":
+			callingConfig = map[string]interface{}{"mode": "ANY"}
+		case "auto":
+			callingConfig = map[string]interface{}{"mode": "AUTO"}
+		}
+	case map[string]interface{}:
+		// {"type": "function", "function": {"name": "my_function"}}
+		if function, ok := choice["function"].(map[string]interface{}); ok {
+			if name, ok := function["name"].(string); ok && name != "" {
+				callingConfig = map[string]interface{}{
+					"mode":                 "ANY",
+					"allowedFunctionNames": []string{name},
+				}
+			}
+		}
+	}
+
+	if callingConfig == nil {
+		return nil
+	}
+
+	return map[string]interface{}{
+		"functionCallingConfig": callingConfig,
+	}
+}
+
+// toolParametersToGemini converts the JSON Schema of a tool's parameters into a Gemini Schema.
+// $ref pointers are inlined and null types become nullable as for response_format; constraints
+// Gemini cannot express are kept as hints in the description. path names the schema in errors.
+func toolParametersToGemini(schema map[string]interface{}, path string) (map[string]interface{}, error) {
+	converter := &schemaConverter{root: schema, tools: true}
+	return converter.convert(schema, path, nil)
+}
+
+// toolCallsToGeminiParts converts assistant tool calls into Gemini functionCall parts, restoring
+// the thought signature from extra_content or from the signatures remembered by tool call ID
+func toolCallsToGeminiParts(toolCalls []models.ToolCall) []map[string]interface{} {
+	parts := make([]map[string]interface{}, 0, len(toolCalls))
+
+	for _, toolCall := range toolCalls {
+		args := make(map[string]interface{})
+		if toolCall.Function.Arguments != "" {
+			if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
+				log.Printf("[WARN] Failed to parse arguments for tool call %s: %v", toolCall.ID, err)
+			}
+		}
+
+		part := map[string]interface{}{
+			"functionCall": map[string]interface{}{
+				"name": toolCall.Function.Name,
+				"args": args,
+			},
+		}
+		if signature := toolCallThoughtSignature(toolCall); signature != "" {
+			part["thoughtSignature"] = signature
+		}
+		parts = append(parts, part)
+	}
+
+	return parts
+}
+
+// toolCallThoughtSignature returns the thought signature to replay with a tool call, if any
+func toolCallThoughtSignature(toolCall models.ToolCall) string {
+	if extra := toolCall.ExtraContent; extra != nil && extra.Google != nil && extra.Google.ThoughtSignature != "" {
+		return extra.Google.ThoughtSignature
+	}
+	if toolCall.ID == "" {
+		return ""
+	}
+	return thoughtSignatures.get(toolCall.ID)
+}
+
+// toolMessageToFunctionResponse converts a "tool" role message into a Gemini functionResponse part
+func toolMessageToFunctionResponse(message models.OpenAIChatMessage, functionName string) map[string]interface{} {
+	var text string
+	switch content := message.Content.(type) {
+	case string:
+		text = content
+	case []interface{}:
+		textParts := make([]string, 0, len(content))
+		for _, part := range content {
+			if partMap, ok := part.(map[string]interface{}); ok {
+				if partText, ok := partMap["text"].(string); ok {
+					textParts = append(textParts, partText)
+				}
+			}
+		}
+		text = strings.Join(textParts, "")
+	}
+
+	// Gemini requires the response to be an object; pass JSON objects through as-is
+	response := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(text), &response); err != nil || len(response) == 0 {
+		response = map[string]interface{}{"content": text}
+	}
+
+	return map[string]interface{}{
+		"functionResponse": map[string]interface{}{
+			"name":     functionName,
+			"response": response,
+		},
+	}
+}
+
+// geminiFunctionCallToOpenAI converts a Gemini functionCall part into an OpenAI tool call.
+// The part's thought signature is returned in extra_content and remembered by tool call ID.
+func geminiFunctionCallToOpenAI(part map[string]interface{}) map[string]interface{} {
+	functionCall, _ := part["functionCall"].(map[string]interface{})
+	name, _ := functionCall["name"].(string)
+
+	args, ok := functionCall["args"]
+	if !ok || args == nil {
+		args = map[string]interface{}{}
+	}
+	argsJSON, err := json.Marshal(args)
+	if err != nil {
+		argsJSON = []byte("{}")
+	}
+
+	id, _ := functionCall["id"].(string)
+	if id == "" {
+		id = "call_" + strings.ReplaceAll(uuid.New().String(), "-", "")
+	}
+
+	toolCall := map[string]interface{}{
+		"id":   id,
+		"type": "function",
+		"function": map[string]interface{}{
+			"name":      name,
+			"arguments": string(argsJSON),
+		},
+	}
+	if signature, _ := part["thoughtSignature"].(string); signature != "" {
+		thoughtSignatures.put(id, signature)
+		toolCall["extra_content"] = map[string]interface{}{
+			"google": map[string]interface{}{"thought_signature": signature},
+		}
+	}
+	return toolCall
+}
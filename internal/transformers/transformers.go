@@ -1,532 +1,652 @@
-package transformers
-
-import (
-	"fmt"
-	"log"
-	"regexp"
-	"strings"
-	"time"
-
-	"gcli2apigo/internal/config"
-	"gcli2apigo/internal/models"
-
-	"github.com/google/uuid"
-)
-
-// OpenAIRequestToGemini transforms an OpenAI chat completion request to Gemini format
-func OpenAIRequestToGemini(req *models.OpenAIChatCompletionRequest) map[string]interface{} {
-	contents := make([]map[string]interface{}, 0)
-
-	// Process each message in the conversation
-	for _, message := range req.Messages {
-		role := message.Role
-
-		// Map OpenAI roles to Gemini roles
-		if role == "assistant" {
-			role = "model"
-		} else if role == "system" {
-			role = "user"
-		}
-
-		parts := make([]map[string]interface{}, 0)
-
-		// Handle different content types
-		switch content := message.Content.(type) {
-		case string:
-			// Simple text content; extract Markdown images
-			parts = extractMarkdownImages(content)
-
-		case []interface{}:
-			// List of content parts
-			for _, part := range content {
-				if partMap, ok := part.(map[string]interface{}); ok {
-					if partType, _ := partMap["type"].(string); partType == "text" {
-						if text, ok := partMap["text"].(string); ok {
-							parts = append(parts, extractMarkdownImages(text)...)
-						}
-					} else if partType == "image_url" {
-						if imageURL, ok := partMap["image_url"].(map[string]interface{}); ok {
-							if url, ok := imageURL["url"].(string); ok {
-								imagePart := parseDataURI(url)
-								if imagePart != nil {
-									parts = append(parts, imagePart)
-								}
-							}
-						}
-					}
-				}
-			}
-		}
-
-		if len(parts) == 0 {
-			parts = append(parts, map[string]interface{}{"text": ""})
-		}
-
-		contents = append(contents, map[string]interface{}{
-			"role":  role,
-			"parts": parts,
-		})
-	}
-
-	// Map OpenAI generation parameters to Gemini format
-	generationConfig := make(map[string]interface{})
-
-	// Set minimum thinking budget for all models
-	generationConfig["thinkingConfig"] = map[string]interface{}{
-		"thinkingBudget": config.GetThinkingBudget(req.Model),
-	}
-
-	if req.Temperature != nil {
-		generationConfig["temperature"] = *req.Temperature
-	}
-	if req.TopP != nil {
-		generationConfig["topP"] = *req.TopP
-	}
-	if req.MaxTokens != nil {
-		generationConfig["maxOutputTokens"] = *req.MaxTokens
-		if config.IsDebugEnabled() {
-			log.Printf("[DEBUG] Using client-specified maxOutputTokens: %d", *req.MaxTokens)
-		}
-	} else {
-		// Set a high default to prevent truncation when client doesn't specify max_tokens
-		// Gemini models support up to 65,535 tokens output
-		generationConfig["maxOutputTokens"] = 65535
-		if config.IsDebugEnabled() {
-			log.Printf("[DEBUG] No max_tokens specified, using default maxOutputTokens: 65535")
-		}
-	}
-	if req.Stop != nil {
-		switch stop := req.Stop.(type) {
-		case string:
-			generationConfig["stopSequences"] = []string{stop}
-		case []interface{}:
-			stopSeqs := make([]string, 0)
-			for _, s := range stop {
-				if str, ok := s.(string); ok {
-					stopSeqs = append(stopSeqs, str)
-				}
-			}
-			generationConfig["stopSequences"] = stopSeqs
-		}
-	}
-	if req.FrequencyPenalty != nil {
-		generationConfig["frequencyPenalty"] = *req.FrequencyPenalty
-	}
-	if req.PresencePenalty != nil {
-		generationConfig["presencePenalty"] = *req.PresencePenalty
-	}
-	if req.N != nil {
-		generationConfig["candidateCount"] = *req.N
-	}
-	if req.Seed != nil {
-		generationConfig["seed"] = *req.Seed
-	}
-	if req.ResponseFormat != nil {
-		if respType, ok := req.ResponseFormat["type"].(string); ok && respType == "json_object" {
-			generationConfig["responseMimeType"] = "application/json"
-		}
-	}
-
-	// Build the request payload
-	requestPayload := map[string]interface{}{
-		"contents":         contents,
-		"generationConfig": generationConfig,
-		"safetySettings":   config.DefaultSafetySettings,
-		"model":            req.Model,
-	}
-
-	return requestPayload
-}
-
-// GeminiResponseToOpenAI transforms a Gemini API response to OpenAI chat completion format
-func GeminiResponseToOpenAI(geminiResp map[string]interface{}, model string) map[string]interface{} {
-	choices := make([]map[string]interface{}, 0)
-
-	candidates, _ := geminiResp["candidates"].([]interface{})
-	for _, candidate := range candidates {
-		candMap, _ := candidate.(map[string]interface{})
-		content, _ := candMap["content"].(map[string]interface{})
-		role, _ := content["role"].(string)
-
-		// Map Gemini roles back to OpenAI roles
-		if role == "model" {
-			role = "assistant"
-		}
-
-		// Extract and separate thinking tokens from regular content
-		parts, _ := content["parts"].([]interface{})
-		contentParts := make([]string, 0)
-		reasoningContent := ""
-
-		for _, part := range parts {
-			partMap, _ := part.(map[string]interface{})
-
-			// Text parts (may include thinking tokens)
-			if text, ok := partMap["text"].(string); ok {
-				if thought, _ := partMap["thought"].(bool); thought {
-					reasoningContent += text
-				} else {
-					contentParts = append(contentParts, text)
-				}
-				continue
-			}
-
-			// Inline image data -> embed as Markdown data URI
-			if inlineData, ok := partMap["inlineData"].(map[string]interface{}); ok {
-				if data, ok := inlineData["data"].(string); ok {
-					mimeType, _ := inlineData["mimeType"].(string)
-					if mimeType == "" {
-						mimeType = "image/png"
-					}
-					if strings.HasPrefix(mimeType, "image/") {
-						contentParts = append(contentParts, fmt.Sprintf("![image](data:%s;base64,%s)", mimeType, data))
-					}
-				}
-			}
-		}
-
-		contentStr := strings.Join(contentParts, "")
-
-		// Build message object
-		message := map[string]interface{}{
-			"role":    role,
-			"content": contentStr,
-		}
-
-		// Add reasoning_content if there are thinking tokens
-		if reasoningContent != "" {
-			message["reasoning_content"] = reasoningContent
-		}
-
-		index, _ := candMap["index"].(float64)
-		finishReason, _ := candMap["finishReason"].(string)
-
-		choices = append(choices, map[string]interface{}{
-			"index":         int(index),
-			"message":       message,
-			"finish_reason": mapFinishReason(finishReason),
-		})
-	}
-
-	return map[string]interface{}{
-		"id":      uuid.New().String(),
-		"object":  "chat.completion",
-		"created": time.Now().Unix(),
-		"model":   model,
-		"choices": choices,
-	}
-}
-
-// GeminiStreamChunkToOpenAI transforms a Gemini streaming response chunk to OpenAI streaming format
-func GeminiStreamChunkToOpenAI(geminiChunk map[string]interface{}, model string, responseID string) map[string]interface{} {
-	choices := make([]map[string]interface{}, 0)
-
-	candidates, _ := geminiChunk["candidates"].([]interface{})
-	for _, candidate := range candidates {
-		candMap, _ := candidate.(map[string]interface{})
-		content, _ := candMap["content"].(map[string]interface{})
-		role, _ := content["role"].(string)
-
-		// Map Gemini roles back to OpenAI roles
-		if role == "model" {
-			role = "assistant"
-		}
-
-		// Extract and separate thinking tokens from regular content
-		parts, _ := content["parts"].([]interface{})
-		contentParts := make([]string, 0)
-		reasoningContent := ""
-
-		for _, part := range parts {
-			partMap, _ := part.(map[string]interface{})
-
-			// Text parts (may include thinking tokens)
-			if text, ok := partMap["text"].(string); ok {
-				if thought, _ := partMap["thought"].(bool); thought {
-					reasoningContent += text
-				} else {
-					contentParts = append(contentParts, text)
-				}
-				continue
-			}
-
-			// Inline image data -> embed as Markdown data URI
-			if inlineData, ok := partMap["inlineData"].(map[string]interface{}); ok {
-				if data, ok := inlineData["data"].(string); ok {
-					mimeType, _ := inlineData["mimeType"].(string)
-					if mimeType == "" {
-						mimeType = "image/png"
-					}
-					if strings.HasPrefix(mimeType, "image/") {
-						contentParts = append(contentParts, fmt.Sprintf("![image](data:%s;base64,%s)", mimeType, data))
-					}
-				}
-			}
-		}
-
-		contentStr := strings.Join(contentParts, "")
-
-		// Build delta object
-		delta := make(map[string]interface{})
-		if contentStr != "" {
-			delta["content"] = contentStr
-		}
-		if reasoningContent != "" {
-			delta["reasoning_content"] = reasoningContent
-		}
-
-		index, _ := candMap["index"].(float64)
-		finishReason, _ := candMap["finishReason"].(string)
-
-		choices = append(choices, map[string]interface{}{
-			"index":         int(index),
-			"delta":         delta,
-			"finish_reason": mapFinishReason(finishReason),
-		})
-	}
-
-	return map[string]interface{}{
-		"id":      responseID,
-		"object":  "chat.completion.chunk",
-		"created": time.Now().Unix(),
-		"model":   model,
-		"choices": choices,
-	}
-}
-
-func MapFinishReason(geminiReason string) interface{} {
-	switch geminiReason {
-	case "STOP":
-		return "stop"
-	case "MAX_TOKENS":
-		return "length"
-	case "SAFETY", "RECITATION":
-		return "content_filter"
-	default:
-		return nil
-	}
-}
-
-// Deprecated: use MapFinishReason instead
-func mapFinishReason(geminiReason string) interface{} {
-	return MapFinishReason(geminiReason)
-}
-
-func extractMarkdownImages(text string) []map[string]interface{} {
-	parts := make([]map[string]interface{}, 0)
-	pattern := regexp.MustCompile(`!\[[^\]]*\]\(([^)]+)\)`)
-	matches := pattern.FindAllStringSubmatchIndex(text, -1)
-
-	if len(matches) == 0 {
-		parts = append(parts, map[string]interface{}{"text": text})
-		return parts
-	}
-
-	lastIdx := 0
-	for _, match := range matches {
-		// match[0] = start of full match, match[1] = end of full match
-		// match[2] = start of URL group, match[3] = end of URL group
-		start, end := match[0], match[1]
-		urlStart, urlEnd := match[2], match[3]
-
-		// Emit text before the image
-		if start > lastIdx {
-			before := text[lastIdx:start]
-			if before != "" {
-				parts = append(parts, map[string]interface{}{"text": before})
-			}
-		}
-
-		// Handle data URI images
-		url := strings.TrimSpace(text[urlStart:urlEnd])
-		url = strings.Trim(url, `"'`)
-
-		if strings.HasPrefix(url, "data:") {
-			imagePart := parseDataURI(url)
-			if imagePart != nil {
-				parts = append(parts, imagePart)
-			} else {
-				// Fallback: keep original markdown as text
-				parts = append(parts, map[string]interface{}{"text": text[start:end]})
-			}
-		} else {
-			// Non-data URIs: keep markdown as text
-			parts = append(parts, map[string]interface{}{"text": text[start:end]})
-		}
-
-		lastIdx = end
-	}
-
-	// Tail text after last image
-	if lastIdx < len(text) {
-		tail := text[lastIdx:]
-		if tail != "" {
-			parts = append(parts, map[string]interface{}{"text": tail})
-		}
-	}
-
-	return parts
-}
-
-func parseDataURI(url string) map[string]interface{} {
-	if !strings.HasPrefix(url, "data:") {
-		return nil
-	}
-
-	parts := strings.SplitN(url, ",", 2)
-	if len(parts) != 2 {
-		return nil
-	}
-
-	header := parts[0]
-	base64Data := parts[1]
-
-	// Extract MIME type from header (e.g., "data:image/png;base64")
-	mimeType := "image/png"
-	if strings.Contains(header, ":") {
-		headerParts := strings.SplitN(header, ":", 2)
-		if len(headerParts) == 2 {
-			mimeTypePart := strings.Split(headerParts[1], ";")[0]
-			if mimeTypePart != "" {
-				mimeType = mimeTypePart
-			}
-		}
-	}
-
-	return map[string]interface{}{
-		"inlineData": map[string]interface{}{
-			"mimeType": mimeType,
-			"data":     base64Data,
-		},
-	}
-}
-
-// AssembleCompleteResponse merges streaming chunks into a single complete response
-// This is used for fake stream mode where chunks are collected internally but returned as a single response
-func AssembleCompleteResponse(chunks []map[string]interface{}, model string) map[string]interface{} {
-	if len(chunks) == 0 {
-		return map[string]interface{}{
-			"id":      uuid.New().String(),
-			"object":  "chat.completion",
-			"created": time.Now().Unix(),
-			"model":   model,
-			"choices": []map[string]interface{}{},
-		}
-	}
-
-	// Accumulate content from all chunks by candidate index
-	candidateMap := make(map[int]*candidateAccumulator)
-
-	for _, chunk := range chunks {
-		candidates, _ := chunk["candidates"].([]interface{})
-		for _, candidate := range candidates {
-			candMap, _ := candidate.(map[string]interface{})
-			index, _ := candMap["index"].(float64)
-			candIndex := int(index)
-
-			// Initialize accumulator for this candidate if not exists
-			if _, exists := candidateMap[candIndex]; !exists {
-				candidateMap[candIndex] = &candidateAccumulator{
-					index:            candIndex,
-					contentParts:     make([]string, 0),
-					reasoningContent: "",
-					finishReason:     "",
-					role:             "",
-				}
-			}
-
-			acc := candidateMap[candIndex]
-
-			// Extract content from this chunk
-			content, _ := candMap["content"].(map[string]interface{})
-			role, _ := content["role"].(string)
-			if role != "" {
-				acc.role = role
-			}
-
-			// Process parts
-			parts, _ := content["parts"].([]interface{})
-			for _, part := range parts {
-				partMap, _ := part.(map[string]interface{})
-
-				// Text parts (may include thinking tokens)
-				if text, ok := partMap["text"].(string); ok {
-					if thought, _ := partMap["thought"].(bool); thought {
-						acc.reasoningContent += text
-					} else {
-						acc.contentParts = append(acc.contentParts, text)
-					}
-					continue
-				}
-
-				// Inline image data -> embed as Markdown data URI
-				if inlineData, ok := partMap["inlineData"].(map[string]interface{}); ok {
-					if data, ok := inlineData["data"].(string); ok {
-						mimeType, _ := inlineData["mimeType"].(string)
-						if mimeType == "" {
-							mimeType = "image/png"
-						}
-						if strings.HasPrefix(mimeType, "image/") {
-							acc.contentParts = append(acc.contentParts, fmt.Sprintf("![image](data:%s;base64,%s)", mimeType, data))
-						}
-					}
-				}
-			}
-
-			// Update finish reason (use the last one)
-			if finishReason, ok := candMap["finishReason"].(string); ok && finishReason != "" {
-				acc.finishReason = finishReason
-			}
-		}
-	}
-
-	// Build choices from accumulated candidates
-	choices := make([]map[string]interface{}, 0)
-	for i := 0; i < len(candidateMap); i++ {
-		if acc, exists := candidateMap[i]; exists {
-			role := acc.role
-			// Map Gemini roles back to OpenAI roles
-			if role == "model" {
-				role = "assistant"
-			}
-
-			// Combine all content parts
-			contentStr := strings.Join(acc.contentParts, "")
-
-			// Build message object
-			message := map[string]interface{}{
-				"role":    role,
-				"content": contentStr,
-			}
-
-			// Add reasoning_content if there are thinking tokens
-			if acc.reasoningContent != "" {
-				message["reasoning_content"] = acc.reasoningContent
-			}
-
-			choices = append(choices, map[string]interface{}{
-				"index":         acc.index,
-				"message":       message,
-				"finish_reason": mapFinishReason(acc.finishReason),
-			})
-		}
-	}
-
-	return map[string]interface{}{
-		"id":      uuid.New().String(),
-		"object":  "chat.completion",
-		"created": time.Now().Unix(),
-		"model":   model,
-		"choices": choices,
-	}
-}
-
-// candidateAccumulator holds accumulated content for a single candidate across chunks
-type candidateAccumulator struct {
-	index            int
-	contentParts     []string
-	reasoningContent string
-	finishReason     string
-	role             string
-}
-
+package transformers
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"gcli2apigo/internal/config"
+	"gcli2apigo/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// OpenAIRequestToGemini transforms an OpenAI chat completion request to Gemini format.
+// It returns a *SchemaError if response_format or a tool's parameters cannot be translated.
+func OpenAIRequestToGemini(req *models.OpenAIChatCompletionRequest) (map[string]interface{}, error) {
+	contents := make([]map[string]interface{}, 0)
+
+	// Map tool call IDs to function names so tool results can be attributed
+	toolCallNames := make(map[string]string)
+	lastWasToolResponse := false
+
+	// Process each message in the conversation
+	for _, message := range req.Messages {
+		role := message.Role
+
+		// Tool results become functionResponse parts; consecutive results are merged
+		// into a single user turn as Gemini expects for parallel function calls
+		if role == "tool" {
+			functionName := message.Name
+			if functionName == "" {
+				functionName = toolCallNames[message.ToolCallID]
+			}
+			if functionName == "" {
+				functionName = message.ToolCallID
+			}
+
+			responsePart := toolMessageToFunctionResponse(message, functionName)
+			if lastWasToolResponse && len(contents) > 0 {
+				lastContent := contents[len(contents)-1]
+				lastContent["parts"] = append(lastContent["parts"].([]map[string]interface{}), responsePart)
+			} else {
+				contents = append(contents, map[string]interface{}{
+					"role":  "user",
+					"parts": []map[string]interface{}{responsePart},
+				})
+			}
+			lastWasToolResponse = true
+			continue
+		}
+		lastWasToolResponse = false
+
+		// Map OpenAI roles to Gemini roles
+		if role == "assistant" {
+			role = "model"
+		} else if role == "system" {
+			role = "user"
+		}
+
+		parts := make([]map[string]interface{}, 0)
+
+		// Handle different content types
+		switch content := message.Content.(type) {
+		case string:
+			// Simple text content; extract Markdown images
+			parts = extractMarkdownImages(content)
+
+		case []interface{}:
+			// List of content parts
+			for _, part := range content {
+				if partMap, ok := part.(map[string]interface{}); ok {
+					if partType, _ := partMap["type"].(string); partType == "text" {
+						if text, ok := partMap["text"].(string); ok {
+							parts = append(parts, extractMarkdownImages(text)...)
+						}
+					} else if partType == "image_url" {
+						if imageURL, ok := partMap["image_url"].(map[string]interface{}); ok {
+							if url, ok := imageURL["url"].(string); ok {
+								imagePart := parseDataURI(url)
+								if imagePart != nil {
+									parts = append(parts, imagePart)
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+
+		// Assistant function calls become functionCall parts
+		if len(message.ToolCalls) > 0 {
+			for _, toolCall := range message.ToolCalls {
+				if toolCall.ID != "" {
+					toolCallNames[toolCall.ID] = toolCall.Function.Name
+				}
+			}
+			// Drop the empty text part produced by a null content alongside tool calls
+			if len(parts) == 1 && parts[0]["text"] == "" {
+				parts = parts[:0]
+			}
+			parts = append(parts, toolCallsToGeminiParts(message.ToolCalls)...)
+		}
+
+		if len(parts) == 0 {
+			parts = append(parts, map[string]interface{}{"text": ""})
+		}
+
+		contents = append(contents, map[string]interface{}{
+			"role":  role,
+			"parts": parts,
+		})
+	}
+
+	// Map OpenAI generation parameters to Gemini format
+	generationConfig := make(map[string]interface{})
+
+	// Set minimum thinking budget for all models
+	generationConfig["thinkingConfig"] = map[string]interface{}{
+		"thinkingBudget": config.GetThinkingBudget(req.Model),
+	}
+
+	if req.Temperature != nil {
+		generationConfig["temperature"] = *req.Temperature
+	}
+	if req.TopP != nil {
+		generationConfig["topP"] = *req.TopP
+	}
+	if req.MaxTokens != nil {
+		generationConfig["maxOutputTokens"] = *req.MaxTokens
+		if config.IsDebugEnabled() {
+			log.Printf("[DEBUG] Using client-specified maxOutputTokens: %d", *req.MaxTokens)
+		}
+	} else {
+		// Set a high default to prevent truncation when client doesn't specify max_tokens
+		// Gemini models support up to 65,535 tokens output
+		generationConfig["maxOutputTokens"] = 65535
+		if config.IsDebugEnabled() {
+			log.Printf("[DEBUG] No max_tokens specified, using default maxOutputTokens: 65535")
+		}
+	}
+	if req.Stop != nil {
+		switch stop := req.Stop.(type) {
+		case string:
+			generationConfig["stopSequences"] = []string{stop}
+		case []interface{}:
+			stopSeqs := make([]string, 0)
+			for _, s := range stop {
+				if str, ok := s.(string); ok {
+					stopSeqs = append(stopSeqs, str)
+				}
+			}
+			generationConfig["stopSequences"] = stopSeqs
+		}
+	}
+	if req.FrequencyPenalty != nil {
+		generationConfig["frequencyPenalty"] = *req.FrequencyPenalty
+	}
+	if req.PresencePenalty != nil {
+		generationConfig["presencePenalty"] = *req.PresencePenalty
+	}
+	if req.N != nil {
+		generationConfig["candidateCount"] = *req.N
+	}
+	if req.Seed != nil {
+		generationConfig["seed"] = *req.Seed
+	}
+	responseFormat, err := OpenAIResponseFormatToGemini(req.ResponseFormat)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range responseFormat {
+		generationConfig[key] = value
+	}
+
+	// Build the request payload
+	requestPayload := map[string]interface{}{
+		"contents":         contents,
+		"generationConfig": generationConfig,
+		"safetySettings":   config.DefaultSafetySettings,
+		"model":            req.Model,
+	}
+
+	// Map function calling tools and tool_choice
+	tools, err := OpenAIToolsToGemini(req.Tools)
+	if err != nil {
+		return nil, err
+	}
+	if tools != nil {
+		requestPayload["tools"] = tools
+	}
+	if toolConfig := OpenAIToolChoiceToGemini(req.ToolChoice); toolConfig != nil {
+		requestPayload["toolConfig"] = toolConfig
+	}
+	// Gemini has no switch for parallel calls; it decides per turn, so the flag is accepted and ignored
+	if req.ParallelToolCalls != nil && config.IsDebugEnabled() {
+		log.Printf("[DEBUG] parallel_tool_calls=%v requested (not configurable upstream)", *req.ParallelToolCalls)
+	}
+
+	return requestPayload, nil
+}
+
+// GeminiResponseToOpenAI transforms a Gemini API response to OpenAI chat completion format
+func GeminiResponseToOpenAI(geminiResp map[string]interface{}, model string) map[string]interface{} {
+	choices := make([]map[string]interface{}, 0)
+
+	candidates, _ := geminiResp["candidates"].([]interface{})
+	for _, candidate := range candidates {
+		candMap, _ := candidate.(map[string]interface{})
+		content, _ := candMap["content"].(map[string]interface{})
+		role, _ := content["role"].(string)
+
+		// Map Gemini roles back to OpenAI roles
+		if role == "model" {
+			role = "assistant"
+		}
+
+		// Extract and separate thinking tokens and function calls from regular content
+		parts, _ := content["parts"].([]interface{})
+		contentParts, reasoningContent, toolCalls := convertGeminiParts(parts)
+
+		contentStr := strings.Join(contentParts, "")
+
+		// Build message object
+		message := map[string]interface{}{
+			"role":    role,
+			"content": contentStr,
+		}
+
+		// Add reasoning_content if there are thinking tokens
+		if reasoningContent != "" {
+			message["reasoning_content"] = reasoningContent
+		}
+
+		// Add tool_calls if the model requested function calls
+		if len(toolCalls) > 0 {
+			message["tool_calls"] = toolCalls
+			if contentStr == "" {
+				message["content"] = nil
+			}
+		}
+
+		index, _ := candMap["index"].(float64)
+		finishReason, _ := candMap["finishReason"].(string)
+
+		choices = append(choices, map[string]interface{}{
+			"index":         int(index),
+			"message":       message,
+			"finish_reason": mapToolAwareFinishReason(finishReason, len(toolCalls) > 0),
+		})
+	}
+
+	response := map[string]interface{}{
+		"id":      uuid.New().String(),
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   model,
+		"choices": choices,
+	}
+
+	if usageMetadata, ok := geminiResp["usageMetadata"].(map[string]interface{}); ok {
+		response["usage"] = GeminiUsageToOpenAI(usageMetadata)
+	}
+
+	return response
+}
+
+// GeminiUsageToOpenAI maps Gemini usageMetadata to an OpenAI usage object.
+// Thinking tokens are billed as output, so they count towards completion_tokens.
+func GeminiUsageToOpenAI(usageMetadata map[string]interface{}) map[string]interface{} {
+	promptTokens, _ := usageMetadata["promptTokenCount"].(float64)
+	candidatesTokens, _ := usageMetadata["candidatesTokenCount"].(float64)
+	thoughtsTokens, _ := usageMetadata["thoughtsTokenCount"].(float64)
+	cachedTokens, _ := usageMetadata["cachedContentTokenCount"].(float64)
+
+	completionTokens := candidatesTokens + thoughtsTokens
+	totalTokens, ok := usageMetadata["totalTokenCount"].(float64)
+	if !ok {
+		totalTokens = promptTokens + completionTokens
+	}
+
+	return map[string]interface{}{
+		"prompt_tokens":     int(promptTokens),
+		"completion_tokens": int(completionTokens),
+		"total_tokens":      int(totalTokens),
+		"prompt_tokens_details": map[string]interface{}{
+			"cached_tokens": int(cachedTokens),
+		},
+		"completion_tokens_details": map[string]interface{}{
+			"reasoning_tokens": int(thoughtsTokens),
+		},
+	}
+}
+
+// GeminiStreamChunkToOpenAI transforms a Gemini streaming response chunk to OpenAI streaming format
+func GeminiStreamChunkToOpenAI(geminiChunk map[string]interface{}, model string, responseID string) map[string]interface{} {
+	choices := make([]map[string]interface{}, 0)
+
+	candidates, _ := geminiChunk["candidates"].([]interface{})
+	for _, candidate := range candidates {
+		candMap, _ := candidate.(map[string]interface{})
+		content, _ := candMap["content"].(map[string]interface{})
+		role, _ := content["role"].(string)
+
+		// Map Gemini roles back to OpenAI roles
+		if role == "model" {
+			role = "assistant"
+		}
+
+		// Extract and separate thinking tokens and function calls from regular content
+		parts, _ := content["parts"].([]interface{})
+		contentParts, reasoningContent, toolCalls := convertGeminiParts(parts)
+
+		contentStr := strings.Join(contentParts, "")
+
+		// Build delta object
+		delta := make(map[string]interface{})
+		if contentStr != "" {
+			delta["content"] = contentStr
+		}
+		if reasoningContent != "" {
+			delta["reasoning_content"] = reasoningContent
+		}
+		if len(toolCalls) > 0 {
+			delta["tool_calls"] = indexToolCalls(toolCalls, 0)
+		}
+
+		index, _ := candMap["index"].(float64)
+		finishReason, _ := candMap["finishReason"].(string)
+
+		choices = append(choices, map[string]interface{}{
+			"index":         int(index),
+			"delta":         delta,
+			"finish_reason": mapToolAwareFinishReason(finishReason, len(toolCalls) > 0),
+		})
+	}
+
+	return map[string]interface{}{
+		"id":      responseID,
+		"object":  "chat.completion.chunk",
+		"created": time.Now().Unix(),
+		"model":   model,
+		"choices": choices,
+	}
+}
+
+func MapFinishReason(geminiReason string) interface{} {
+	switch geminiReason {
+	case "STOP":
+		return "stop"
+	case "MAX_TOKENS":
+		return "length"
+	case "SAFETY", "RECITATION":
+		return "content_filter"
+	default:
+		return nil
+	}
+}
+
+// Deprecated: use MapFinishReason instead
+func mapFinishReason(geminiReason string) interface{} {
+	return MapFinishReason(geminiReason)
+}
+
+// mapToolAwareFinishReason maps a Gemini finish reason, reporting "tool_calls"
+// when the model stopped to request function calls
+func mapToolAwareFinishReason(geminiReason string, hasToolCalls bool) interface{} {
+	if hasToolCalls && geminiReason == "STOP" {
+		return "tool_calls"
+	}
+	return MapFinishReason(geminiReason)
+}
+
+// convertGeminiParts splits Gemini response parts into content text, reasoning text and OpenAI tool calls
+func convertGeminiParts(parts []interface{}) ([]string, string, []map[string]interface{}) {
+	contentParts := make([]string, 0)
+	reasoningContent := ""
+	toolCalls := make([]map[string]interface{}, 0)
+
+	for _, part := range parts {
+		partMap, _ := part.(map[string]interface{})
+
+		// Text parts (may include thinking tokens)
+		if text, ok := partMap["text"].(string); ok {
+			if thought, _ := partMap["thought"].(bool); thought {
+				reasoningContent += text
+			} else {
+				contentParts = append(contentParts, text)
+			}
+			continue
+		}
+
+		// Function calls -> OpenAI tool calls
+		if _, ok := partMap["functionCall"].(map[string]interface{}); ok {
+			toolCalls = append(toolCalls, geminiFunctionCallToOpenAI(partMap))
+			continue
+		}
+
+		// Inline image data -> embed as Markdown data URI
+		if inlineData, ok := partMap["inlineData"].(map[string]interface{}); ok {
+			if data, ok := inlineData["data"].(string); ok {
+				mimeType, _ := inlineData["mimeType"].(string)
+				if mimeType == "" {
+					mimeType = "image/png"
+				}
+				if strings.HasPrefix(mimeType, "image/") {
+					contentParts = append(contentParts, fmt.Sprintf("![image](data:%s;base64,%s)", mimeType, data))
+				}
+			}
+		}
+	}
+
+	return contentParts, reasoningContent, toolCalls
+}
+
+// ExtractToolCalls converts the functionCall parts of a streamed Gemini candidate into
+// OpenAI tool call deltas, numbering them from startIndex so calls spread across
+// several chunks keep distinct indexes
+func ExtractToolCalls(parts []interface{}, startIndex int) []map[string]interface{} {
+	_, _, toolCalls := convertGeminiParts(parts)
+	if len(toolCalls) == 0 {
+		return nil
+	}
+	return indexToolCalls(toolCalls, startIndex)
+}
+
+// indexToolCalls adds the "index" field required by streaming tool call deltas
+func indexToolCalls(toolCalls []map[string]interface{}, startIndex int) []map[string]interface{} {
+	for i, toolCall := range toolCalls {
+		toolCall["index"] = startIndex + i
+	}
+	return toolCalls
+}
+
+func extractMarkdownImages(text string) []map[string]interface{} {
+	parts := make([]map[string]interface{}, 0)
+	pattern := regexp.MustCompile(`!\[[^\]]*\]\(([^)]+)\)`)
+	matches := pattern.FindAllStringSubmatchIndex(text, -1)
+
+	if len(matches) == 0 {
+		parts = append(parts, map[string]interface{}{"text": text})
+		return parts
+	}
+
+	lastIdx := 0
+	for _, match := range matches {
+		// match[0] = start of full match, match[1] = end of full match
+		// match[2] = start of URL group, match[3] = end of URL group
+		start, end := match[0], match[1]
+		urlStart, urlEnd := match[2], match[3]
+
+		// Emit text before the image
+		if start > lastIdx {
+			before := text[lastIdx:start]
+			if before != "" {
+				parts = append(parts, map[string]interface{}{"text": before})
+			}
+		}
+
+		// Handle data URI images
+		url := strings.TrimSpace(text[urlStart:urlEnd])
+		url = strings.Trim(url, `"'`)
+
+		if strings.HasPrefix(url, "data:") {
+			imagePart := parseDataURI(url)
+			if imagePart != nil {
+				parts = append(parts, imagePart)
+			} else {
+				// Fallback: keep original markdown as text
+				parts = append(parts, map[string]interface{}{"text": text[start:end]})
+			}
+		} else {
+			// Non-data URIs: keep markdown as text
+			parts = append(parts, map[string]interface{}{"text": text[start:end]})
+		}
+
+		lastIdx = end
+	}
+
+	// Tail text after last image
+	if lastIdx < len(text) {
+		tail := text[lastIdx:]
+		if tail != "" {
+			parts = append(parts, map[string]interface{}{"text": tail})
+		}
+	}
+
+	return parts
+}
+
+func parseDataURI(url string) map[string]interface{} {
+	if !strings.HasPrefix(url, "data:") {
+		return nil
+	}
+
+	parts := strings.SplitN(url, ",", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+
+	header := parts[0]
+	base64Data := parts[1]
+
+	// Extract MIME type from header (e.g., "data:image/png;base64")
+	mimeType := "image/png"
+	if strings.Contains(header, ":") {
+		headerParts := strings.SplitN(header, ":", 2)
+		if len(headerParts) == 2 {
+			mimeTypePart := strings.Split(headerParts[1], ";")[0]
+			if mimeTypePart != "" {
+				mimeType = mimeTypePart
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"inlineData": map[string]interface{}{
+			"mimeType": mimeType,
+			"data":     base64Data,
+		},
+	}
+}
+
+// AssembleCompleteResponse merges streaming chunks into a single complete response
+// This is used for fake stream mode where chunks are collected internally but returned as a single response
+func AssembleCompleteResponse(chunks []map[string]interface{}, model string) map[string]interface{} {
+	if len(chunks) == 0 {
+		return map[string]interface{}{
+			"id":      uuid.New().String(),
+			"object":  "chat.completion",
+			"created": time.Now().Unix(),
+			"model":   model,
+			"choices": []map[string]interface{}{},
+		}
+	}
+
+	// Accumulate content from all chunks by candidate index
+	candidateMap := make(map[int]*candidateAccumulator)
+
+	// Streamed usageMetadata is cumulative, so the last one seen is the total
+	var usageMetadata map[string]interface{}
+
+	for _, chunk := range chunks {
+		if metadata, ok := chunk["usageMetadata"].(map[string]interface{}); ok {
+			usageMetadata = metadata
+		}
+
+		candidates, _ := chunk["candidates"].([]interface{})
+		for _, candidate := range candidates {
+			candMap, _ := candidate.(map[string]interface{})
+			index, _ := candMap["index"].(float64)
+			candIndex := int(index)
+
+			// Initialize accumulator for this candidate if not exists
+			if _, exists := candidateMap[candIndex]; !exists {
+				candidateMap[candIndex] = &candidateAccumulator{
+					index:            candIndex,
+					contentParts:     make([]string, 0),
+					reasoningContent: "",
+					finishReason:     "",
+					role:             "",
+				}
+			}
+
+			acc := candidateMap[candIndex]
+
+			// Extract content from this chunk
+			content, _ := candMap["content"].(map[string]interface{})
+			role, _ := content["role"].(string)
+			if role != "" {
+				acc.role = role
+			}
+
+			// Process parts
+			parts, _ := content["parts"].([]interface{})
+			contentParts, reasoningContent, toolCalls := convertGeminiParts(parts)
+			acc.contentParts = append(acc.contentParts, contentParts...)
+			acc.reasoningContent += reasoningContent
+			acc.toolCalls = append(acc.toolCalls, toolCalls...)
+
+			// Update finish reason (use the last one)
+			if finishReason, ok := candMap["finishReason"].(string); ok && finishReason != "" {
+				acc.finishReason = finishReason
+			}
+		}
+	}
+
+	// Build choices from accumulated candidates
+	choices := make([]map[string]interface{}, 0)
+	for i := 0; i < len(candidateMap); i++ {
+		if acc, exists := candidateMap[i]; exists {
+			role := acc.role
+			// Map Gemini roles back to OpenAI roles
+			if role == "model" {
+				role = "assistant"
+			}
+
+			// Combine all content parts
+			contentStr := strings.Join(acc.contentParts, "")
+
+			// Build message object
+			message := map[string]interface{}{
+				"role":    role,
+				"content": contentStr,
+			}
+
+			// Add reasoning_content if there are thinking tokens
+			if acc.reasoningContent != "" {
+				message["reasoning_content"] = acc.reasoningContent
+			}
+
+			// Add tool_calls if the model requested function calls
+			if len(acc.toolCalls) > 0 {
+				message["tool_calls"] = acc.toolCalls
+				if contentStr == "" {
+					message["content"] = nil
+				}
+			}
+
+			choices = append(choices, map[string]interface{}{
+				"index":         acc.index,
+				"message":       message,
+				"finish_reason": mapToolAwareFinishReason(acc.finishReason, len(acc.toolCalls) > 0),
+			})
+		}
+	}
+
+	response := map[string]interface{}{
+		"id":      uuid.New().String(),
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   model,
+		"choices": choices,
+	}
+
+	if usageMetadata != nil {
+		response["usage"] = GeminiUsageToOpenAI(usageMetadata)
+	}
+
+	return response
+}
+
+// candidateAccumulator holds accumulated content for a single candidate across chunks
+type candidateAccumulator struct {
+	index            int
+	contentParts     []string
+	reasoningContent string
+	toolCalls        []map[string]interface{}
+	finishReason     string
+	role             string
+}
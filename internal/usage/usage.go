@@ -1,396 +1,507 @@
-package usage
-
-import (
-	"encoding/json"
-	"log"
-	"os"
-	"path/filepath"
-	"sync"
-	"time"
-)
-
-// Limits for API usage
-const (
-	ProModelDailyLimit = 100  // gemini-2.5-pro models limit
-	OverallDailyLimit  = 1000 // all models limit
-	ResetHour          = 15   // 3:00 PM
-	ResetMinute        = 0
-	ResetTimezone      = 8 * 60 * 60 // GMT+8 in seconds
-)
-
-// ProjectUsage tracks usage statistics for a single project
-type ProjectUsage struct {
-	ProjectID      string    `json:"project_id"`
-	ProModelCount  int       `json:"pro_model_count"`
-	OverallCount   int       `json:"overall_count"`
-	LastResetTime  time.Time `json:"last_reset_time"`
-	LastUpdateTime time.Time `json:"last_update_time"`
-	LastErrorCode  int       `json:"last_error_code"` // HTTP error code from last API request, 0 if successful
-}
-
-// UsageTracker manages usage statistics for all projects
-type UsageTracker struct {
-	usageMap     map[string]*ProjectUsage
-	mu           sync.RWMutex
-	storePath    string
-	dirty        bool // Tracks if data needs to be saved
-	dirtyMu      sync.Mutex
-	lastSaveTime time.Time
-}
-
-var (
-	globalTracker *UsageTracker
-	trackerOnce   sync.Once
-)
-
-// GetTracker returns the global usage tracker instance
-func GetTracker() *UsageTracker {
-	trackerOnce.Do(func() {
-		globalTracker = NewUsageTracker()
-		globalTracker.Load()
-		go globalTracker.autoResetLoop()
-		go globalTracker.autoSaveLoop() // Batch save every 5 seconds
-	})
-	return globalTracker
-}
-
-// NewUsageTracker creates a new usage tracker
-func NewUsageTracker() *UsageTracker {
-	storePath := filepath.Join("oauth_creds", "usage_stats.json")
-	return &UsageTracker{
-		usageMap:  make(map[string]*ProjectUsage),
-		storePath: storePath,
-	}
-}
-
-// IncrementUsage increments usage counters for a project
-func (ut *UsageTracker) IncrementUsage(projectID string, isProModel bool) {
-	ut.mu.Lock()
-	defer ut.mu.Unlock()
-
-	usage, exists := ut.usageMap[projectID]
-	if !exists {
-		usage = &ProjectUsage{
-			ProjectID:     projectID,
-			LastResetTime: ut.getNextResetTime().Add(-24 * time.Hour), // Set to previous reset
-		}
-		ut.usageMap[projectID] = usage
-	}
-
-	// Check if reset is needed
-	if ut.shouldReset(usage.LastResetTime) {
-		usage.ProModelCount = 0
-		usage.OverallCount = 0
-		usage.LastResetTime = ut.getLastResetTime()
-	}
-
-	// Increment counters
-	usage.OverallCount++
-	if isProModel {
-		usage.ProModelCount++
-	}
-	usage.LastUpdateTime = time.Now()
-
-	// Clear error code on successful request
-	usage.LastErrorCode = 0
-
-	// Mark as dirty for batch save
-	ut.markDirty()
-}
-
-// SetErrorCode sets the error code for a project
-func (ut *UsageTracker) SetErrorCode(projectID string, errorCode int) {
-	ut.mu.Lock()
-	defer ut.mu.Unlock()
-
-	usage, exists := ut.usageMap[projectID]
-	if !exists {
-		usage = &ProjectUsage{
-			ProjectID:     projectID,
-			LastResetTime: ut.getNextResetTime().Add(-24 * time.Hour),
-		}
-		ut.usageMap[projectID] = usage
-	}
-
-	usage.LastErrorCode = errorCode
-	usage.LastUpdateTime = time.Now()
-
-	// Mark as dirty for batch save
-	ut.markDirty()
-}
-
-// GetLastErrorCode returns the last error code for a project
-func (ut *UsageTracker) GetLastErrorCode(projectID string) int {
-	ut.mu.RLock()
-	defer ut.mu.RUnlock()
-
-	usage, exists := ut.usageMap[projectID]
-	if !exists {
-		return 0
-	}
-
-	return usage.LastErrorCode
-}
-
-// GetUsage returns usage statistics for a project
-func (ut *UsageTracker) GetUsage(projectID string) *ProjectUsage {
-	ut.mu.RLock()
-	defer ut.mu.RUnlock()
-
-	usage, exists := ut.usageMap[projectID]
-	if !exists {
-		return &ProjectUsage{
-			ProjectID:     projectID,
-			LastResetTime: ut.getLastResetTime(),
-		}
-	}
-
-	// Check if reset is needed
-	if ut.shouldReset(usage.LastResetTime) {
-		return &ProjectUsage{
-			ProjectID:     projectID,
-			LastResetTime: ut.getNextResetTime().Add(-24 * time.Hour),
-		}
-	}
-
-	// Return a copy
-	return &ProjectUsage{
-		ProjectID:      usage.ProjectID,
-		ProModelCount:  usage.ProModelCount,
-		OverallCount:   usage.OverallCount,
-		LastResetTime:  usage.LastResetTime,
-		LastUpdateTime: usage.LastUpdateTime,
-	}
-}
-
-// GetAllUsage returns usage statistics for all projects
-func (ut *UsageTracker) GetAllUsage() map[string]*ProjectUsage {
-	ut.mu.RLock()
-	defer ut.mu.RUnlock()
-
-	result := make(map[string]*ProjectUsage)
-
-	for projectID, usage := range ut.usageMap {
-		if ut.shouldReset(usage.LastResetTime) {
-			result[projectID] = &ProjectUsage{
-				ProjectID:     projectID,
-				LastResetTime: ut.getLastResetTime(),
-			}
-		} else {
-			result[projectID] = &ProjectUsage{
-				ProjectID:      usage.ProjectID,
-				ProModelCount:  usage.ProModelCount,
-				OverallCount:   usage.OverallCount,
-				LastResetTime:  usage.LastResetTime,
-				LastUpdateTime: usage.LastUpdateTime,
-			}
-		}
-	}
-
-	return result
-}
-
-// shouldReset checks if usage should be reset based on last reset time
-func (ut *UsageTracker) shouldReset(lastResetTime time.Time) bool {
-	// If lastResetTime is zero, it needs reset
-	if lastResetTime.IsZero() {
-		return true
-	}
-
-	// Get the most recent reset time (today or yesterday at 3 PM)
-	lastResetPoint := ut.getLastResetTime()
-
-	// If the last reset was before the most recent reset point, we need to reset
-	return lastResetTime.Before(lastResetPoint)
-}
-
-// getLastResetTime returns the most recent reset time (today or yesterday at 3 PM GMT+8)
-func (ut *UsageTracker) getLastResetTime() time.Time {
-	location := time.FixedZone("GMT+8", ResetTimezone)
-	now := time.Now().In(location)
-
-	resetTime := time.Date(now.Year(), now.Month(), now.Day(), ResetHour, ResetMinute, 0, 0, location)
-
-	if now.Before(resetTime) {
-		// If current time is before today's reset, return yesterday's reset
-		resetTime = resetTime.Add(-24 * time.Hour)
-	}
-
-	return resetTime
-}
-
-// GetNextResetTime returns the next reset time (today or tomorrow at 3 PM GMT+8)
-func (ut *UsageTracker) GetNextResetTime() time.Time {
-	return ut.getNextResetTime()
-}
-
-// getNextResetTime returns the next reset time (today or tomorrow at 3 PM GMT+8)
-func (ut *UsageTracker) getNextResetTime() time.Time {
-	location := time.FixedZone("GMT+8", ResetTimezone)
-	now := time.Now().In(location)
-
-	resetTime := time.Date(now.Year(), now.Month(), now.Day(), ResetHour, ResetMinute, 0, 0, location)
-
-	if now.After(resetTime) || now.Equal(resetTime) {
-		// If current time is after today's reset, return tomorrow's reset
-		resetTime = resetTime.Add(24 * time.Hour)
-	}
-
-	return resetTime
-}
-
-// autoResetLoop runs a background goroutine that resets usage at 3 PM GMT+8 daily
-func (ut *UsageTracker) autoResetLoop() {
-	for {
-		nextReset := ut.getNextResetTime()
-		duration := time.Until(nextReset)
-
-		log.Printf("[INFO] Next usage reset scheduled at: %v (in %v)", nextReset, duration)
-
-		time.Sleep(duration)
-
-		// Perform reset
-		ut.mu.Lock()
-		for _, usage := range ut.usageMap {
-			usage.ProModelCount = 0
-			usage.OverallCount = 0
-			usage.LastResetTime = time.Now()
-		}
-		ut.mu.Unlock()
-
-		log.Printf("[INFO] Usage statistics reset completed at %v", time.Now())
-
-		// Save to disk
-		ut.Save()
-
-		// Sleep a bit to avoid immediate re-trigger
-		time.Sleep(1 * time.Minute)
-	}
-}
-
-// Save persists usage statistics to disk
-func (ut *UsageTracker) Save() error {
-	ut.mu.RLock()
-	defer ut.mu.RUnlock()
-
-	// Ensure directory exists
-	dir := filepath.Dir(ut.storePath)
-	if err := os.MkdirAll(dir, 0700); err != nil {
-		log.Printf("[ERROR] Failed to create usage stats directory: %v", err)
-		return err
-	}
-
-	// Marshal to JSON
-	data, err := json.MarshalIndent(ut.usageMap, "", "  ")
-	if err != nil {
-		log.Printf("[ERROR] Failed to marshal usage stats: %v", err)
-		return err
-	}
-
-	// Write to file
-	if err := os.WriteFile(ut.storePath, data, 0600); err != nil {
-		log.Printf("[ERROR] Failed to write usage stats: %v", err)
-		return err
-	}
-
-	return nil
-}
-
-// Load reads usage statistics from disk
-func (ut *UsageTracker) Load() error {
-	ut.mu.Lock()
-	defer ut.mu.Unlock()
-
-	// Check if file exists
-	if _, err := os.Stat(ut.storePath); os.IsNotExist(err) {
-		log.Printf("[INFO] Usage stats file does not exist, starting fresh")
-		return nil
-	}
-
-	// Read file
-	data, err := os.ReadFile(ut.storePath)
-	if err != nil {
-		log.Printf("[ERROR] Failed to read usage stats: %v", err)
-		return err
-	}
-
-	// Unmarshal JSON
-	if err := json.Unmarshal(data, &ut.usageMap); err != nil {
-		log.Printf("[ERROR] Failed to unmarshal usage stats: %v", err)
-		return err
-	}
-
-	log.Printf("[INFO] Loaded usage statistics for %d projects", len(ut.usageMap))
-	return nil
-}
-
-// CheckAndResetIfNeeded checks all projects and resets usage if the reset time has passed
-// This should be called on startup to handle cases where the program was not running during reset time
-func (ut *UsageTracker) CheckAndResetIfNeeded() {
-	ut.mu.Lock()
-	defer ut.mu.Unlock()
-
-	resetCount := 0
-	nextReset := ut.getNextResetTime()
-
-	for projectID, usage := range ut.usageMap {
-		if ut.shouldReset(usage.LastResetTime) {
-			log.Printf("[INFO] Resetting usage for project %s (last reset: %v, next reset: %v)",
-				projectID, usage.LastResetTime, nextReset)
-			usage.ProModelCount = 0
-			usage.OverallCount = 0
-			usage.LastResetTime = ut.getLastResetTime()
-			resetCount++
-		}
-	}
-
-	if resetCount > 0 {
-		log.Printf("[INFO] Reset usage statistics for %d projects on startup", resetCount)
-		// Save the reset state to disk
-		ut.mu.Unlock()
-		ut.Save()
-		ut.mu.Lock()
-	} else {
-		log.Printf("[INFO] No usage reset needed on startup")
-	}
-}
-
-// IsProModel checks if a model name is a Pro model (gemini-2.5-pro variants)
-func IsProModel(modelName string) bool {
-	// Check if model name contains "pro" (case-insensitive)
-	// This covers: gemini-2.5-pro, gemini-2.5-pro-preview-*, etc.
-	return len(modelName) >= 3 &&
-		(modelName[len(modelName)-3:] == "pro" ||
-			len(modelName) >= 4 && modelName[len(modelName)-4:len(modelName)-1] == "pro-")
-}
-
-// markDirty marks the tracker as having unsaved changes
-func (ut *UsageTracker) markDirty() {
-	ut.dirtyMu.Lock()
-	ut.dirty = true
-	ut.dirtyMu.Unlock()
-}
-
-// autoSaveLoop runs a background goroutine that saves usage stats every 5 seconds if dirty
-// This batches disk writes to improve performance under high load
-func (ut *UsageTracker) autoSaveLoop() {
-	ticker := time.NewTicker(5 * time.Second)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		ut.dirtyMu.Lock()
-		isDirty := ut.dirty
-		ut.dirtyMu.Unlock()
-
-		if isDirty {
-			if err := ut.Save(); err != nil {
-				log.Printf("[ERROR] Auto-save failed: %v", err)
-			} else {
-				ut.dirtyMu.Lock()
-				ut.dirty = false
-				ut.lastSaveTime = time.Now()
-				ut.dirtyMu.Unlock()
-			}
-		}
-	}
-}
+package usage
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"gcli2apigo/internal/config"
+	"gcli2apigo/internal/storage"
+)
+
+// Limits for API usage
+const (
+	ProModelDailyLimit = 100  // gemini-2.5-pro models limit
+	OverallDailyLimit  = 1000 // all models limit
+	ResetHour          = 15   // 3:00 PM
+	ResetMinute        = 0
+	ResetTimezone      = 8 * 60 * 60 // GMT+8 in seconds
+)
+
+// ProjectUsage tracks usage statistics for a single project
+type ProjectUsage struct {
+	ProjectID      string    `json:"project_id"`
+	ProModelCount  int       `json:"pro_model_count"`
+	OverallCount   int       `json:"overall_count"`
+	LastResetTime  time.Time `json:"last_reset_time"`
+	LastUpdateTime time.Time `json:"last_update_time"`
+	LastErrorCode  int       `json:"last_error_code"` // HTTP error code from last API request, 0 if successful
+}
+
+// KeyUsage tracks daily usage for a named API key
+type KeyUsage struct {
+	KeyID          string    `json:"key_id"`
+	RequestCount   int       `json:"request_count"`
+	TokenCount     int       `json:"token_count"`
+	LastResetTime  time.Time `json:"last_reset_time"`
+	LastUpdateTime time.Time `json:"last_update_time"`
+}
+
+// UsageTracker manages usage statistics for all projects
+type UsageTracker struct {
+	usageMap     map[string]*ProjectUsage
+	keyUsageMap  map[string]*KeyUsage // Per API key usage, keyed by key ID
+	mu           sync.RWMutex
+	saveMu       sync.Mutex // Held from snapshot to write so an older snapshot never overwrites a newer one
+	store        storage.Store
+	dirty        bool // Tracks if data needs to be saved
+	dirtyMu      sync.Mutex
+	lastSaveTime time.Time
+}
+
+var (
+	globalTracker *UsageTracker
+	trackerOnce   sync.Once
+)
+
+// GetTracker returns the global usage tracker instance
+func GetTracker() *UsageTracker {
+	trackerOnce.Do(func() {
+		globalTracker = NewUsageTracker()
+		globalTracker.Load()
+		go globalTracker.autoResetLoop()
+		go globalTracker.autoSaveLoop() // Batch save every 5 seconds
+	})
+	return globalTracker
+}
+
+// NewUsageTracker creates a new usage tracker
+func NewUsageTracker() *UsageTracker {
+	return &UsageTracker{
+		usageMap:    make(map[string]*ProjectUsage),
+		keyUsageMap: make(map[string]*KeyUsage),
+		store:       storage.GetStore(),
+	}
+}
+
+// IncrementUsage increments usage counters for a project
+func (ut *UsageTracker) IncrementUsage(projectID string, isProModel bool) {
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+
+	usage, exists := ut.usageMap[projectID]
+	if !exists {
+		usage = &ProjectUsage{
+			ProjectID:     projectID,
+			LastResetTime: ut.getNextResetTime().Add(-24 * time.Hour), // Set to previous reset
+		}
+		ut.usageMap[projectID] = usage
+	}
+
+	// Check if reset is needed
+	if ut.shouldReset(usage.LastResetTime) {
+		usage.ProModelCount = 0
+		usage.OverallCount = 0
+		usage.LastResetTime = ut.getLastResetTime()
+	}
+
+	// Increment counters
+	usage.OverallCount++
+	if isProModel {
+		usage.ProModelCount++
+	}
+	usage.LastUpdateTime = time.Now()
+
+	// Clear error code on successful request
+	usage.LastErrorCode = 0
+
+	// Mark as dirty for batch save
+	ut.markDirty()
+}
+
+// SetErrorCode sets the error code for a project
+func (ut *UsageTracker) SetErrorCode(projectID string, errorCode int) {
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+
+	usage, exists := ut.usageMap[projectID]
+	if !exists {
+		usage = &ProjectUsage{
+			ProjectID:     projectID,
+			LastResetTime: ut.getNextResetTime().Add(-24 * time.Hour),
+		}
+		ut.usageMap[projectID] = usage
+	}
+
+	usage.LastErrorCode = errorCode
+	usage.LastUpdateTime = time.Now()
+
+	// Mark as dirty for batch save
+	ut.markDirty()
+}
+
+// GetLastErrorCode returns the last error code for a project
+func (ut *UsageTracker) GetLastErrorCode(projectID string) int {
+	ut.mu.RLock()
+	defer ut.mu.RUnlock()
+
+	usage, exists := ut.usageMap[projectID]
+	if !exists {
+		return 0
+	}
+
+	return usage.LastErrorCode
+}
+
+// GetUsage returns usage statistics for a project
+func (ut *UsageTracker) GetUsage(projectID string) *ProjectUsage {
+	ut.mu.RLock()
+	defer ut.mu.RUnlock()
+
+	usage, exists := ut.usageMap[projectID]
+	if !exists {
+		return &ProjectUsage{
+			ProjectID:     projectID,
+			LastResetTime: ut.getLastResetTime(),
+		}
+	}
+
+	// Check if reset is needed
+	if ut.shouldReset(usage.LastResetTime) {
+		return &ProjectUsage{
+			ProjectID:     projectID,
+			LastResetTime: ut.getNextResetTime().Add(-24 * time.Hour),
+		}
+	}
+
+	// Return a copy
+	return &ProjectUsage{
+		ProjectID:      usage.ProjectID,
+		ProModelCount:  usage.ProModelCount,
+		OverallCount:   usage.OverallCount,
+		LastResetTime:  usage.LastResetTime,
+		LastUpdateTime: usage.LastUpdateTime,
+	}
+}
+
+// getKeyUsageLocked returns the usage record for a key, creating or resetting it as needed.
+// The caller must hold ut.mu for writing.
+func (ut *UsageTracker) getKeyUsageLocked(keyID string) *KeyUsage {
+	keyUsage, exists := ut.keyUsageMap[keyID]
+	if !exists {
+		keyUsage = &KeyUsage{
+			KeyID:         keyID,
+			LastResetTime: ut.getLastResetTime(),
+		}
+		ut.keyUsageMap[keyID] = keyUsage
+	}
+
+	if ut.shouldReset(keyUsage.LastResetTime) {
+		keyUsage.RequestCount = 0
+		keyUsage.TokenCount = 0
+		keyUsage.LastResetTime = ut.getLastResetTime()
+	}
+
+	return keyUsage
+}
+
+// IncrementKeyUsage counts a successful request against a named API key
+func (ut *UsageTracker) IncrementKeyUsage(keyID string) {
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+
+	keyUsage := ut.getKeyUsageLocked(keyID)
+	keyUsage.RequestCount++
+	keyUsage.LastUpdateTime = time.Now()
+
+	ut.markDirty()
+}
+
+// AddKeyTokens counts tokens consumed by a request against a named API key
+func (ut *UsageTracker) AddKeyTokens(keyID string, tokens int) {
+	if tokens <= 0 {
+		return
+	}
+
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+
+	keyUsage := ut.getKeyUsageLocked(keyID)
+	keyUsage.TokenCount += tokens
+	keyUsage.LastUpdateTime = time.Now()
+
+	ut.markDirty()
+}
+
+// GetKeyUsage returns usage statistics for a named API key
+func (ut *UsageTracker) GetKeyUsage(keyID string) *KeyUsage {
+	ut.mu.RLock()
+	defer ut.mu.RUnlock()
+
+	keyUsage, exists := ut.keyUsageMap[keyID]
+	if !exists || ut.shouldReset(keyUsage.LastResetTime) {
+		return &KeyUsage{
+			KeyID:         keyID,
+			LastResetTime: ut.getLastResetTime(),
+		}
+	}
+
+	// Return a copy
+	copied := *keyUsage
+	return &copied
+}
+
+// GetAllUsage returns usage statistics for all projects
+func (ut *UsageTracker) GetAllUsage() map[string]*ProjectUsage {
+	ut.mu.RLock()
+	defer ut.mu.RUnlock()
+
+	result := make(map[string]*ProjectUsage)
+
+	for projectID, usage := range ut.usageMap {
+		if ut.shouldReset(usage.LastResetTime) {
+			result[projectID] = &ProjectUsage{
+				ProjectID:     projectID,
+				LastResetTime: ut.getLastResetTime(),
+			}
+		} else {
+			result[projectID] = &ProjectUsage{
+				ProjectID:      usage.ProjectID,
+				ProModelCount:  usage.ProModelCount,
+				OverallCount:   usage.OverallCount,
+				LastResetTime:  usage.LastResetTime,
+				LastUpdateTime: usage.LastUpdateTime,
+			}
+		}
+	}
+
+	return result
+}
+
+// shouldReset checks if usage should be reset based on last reset time
+func (ut *UsageTracker) shouldReset(lastResetTime time.Time) bool {
+	// If lastResetTime is zero, it needs reset
+	if lastResetTime.IsZero() {
+		return true
+	}
+
+	// Get the most recent reset time (today or yesterday at 3 PM)
+	lastResetPoint := ut.getLastResetTime()
+
+	// If the last reset was before the most recent reset point, we need to reset
+	return lastResetTime.Before(lastResetPoint)
+}
+
+// getLastResetTime returns the most recent reset time (today or yesterday at 3 PM GMT+8)
+func (ut *UsageTracker) getLastResetTime() time.Time {
+	location := time.FixedZone("GMT+8", ResetTimezone)
+	now := time.Now().In(location)
+
+	resetTime := time.Date(now.Year(), now.Month(), now.Day(), ResetHour, ResetMinute, 0, 0, location)
+
+	if now.Before(resetTime) {
+		// If current time is before today's reset, return yesterday's reset
+		resetTime = resetTime.Add(-24 * time.Hour)
+	}
+
+	return resetTime
+}
+
+// GetNextResetTime returns the next reset time (today or tomorrow at 3 PM GMT+8)
+func (ut *UsageTracker) GetNextResetTime() time.Time {
+	return ut.getNextResetTime()
+}
+
+// getNextResetTime returns the next reset time (today or tomorrow at 3 PM GMT+8)
+func (ut *UsageTracker) getNextResetTime() time.Time {
+	location := time.FixedZone("GMT+8", ResetTimezone)
+	now := time.Now().In(location)
+
+	resetTime := time.Date(now.Year(), now.Month(), now.Day(), ResetHour, ResetMinute, 0, 0, location)
+
+	if now.After(resetTime) || now.Equal(resetTime) {
+		// If current time is after today's reset, return tomorrow's reset
+		resetTime = resetTime.Add(24 * time.Hour)
+	}
+
+	return resetTime
+}
+
+// autoResetLoop runs a background goroutine that resets usage at 3 PM GMT+8 daily
+func (ut *UsageTracker) autoResetLoop() {
+	for {
+		nextReset := ut.getNextResetTime()
+		duration := time.Until(nextReset)
+
+		log.Printf("[INFO] Next usage reset scheduled at: %v (in %v)", nextReset, duration)
+
+		time.Sleep(duration)
+
+		// Perform reset
+		ut.mu.Lock()
+		for _, usage := range ut.usageMap {
+			usage.ProModelCount = 0
+			usage.OverallCount = 0
+			usage.LastResetTime = time.Now()
+		}
+		for _, keyUsage := range ut.keyUsageMap {
+			keyUsage.RequestCount = 0
+			keyUsage.TokenCount = 0
+			keyUsage.LastResetTime = time.Now()
+		}
+		ut.mu.Unlock()
+
+		log.Printf("[INFO] Usage statistics reset completed at %v", time.Now())
+
+		// Save to disk
+		ut.Save()
+
+		// Sleep a bit to avoid immediate re-trigger
+		time.Sleep(1 * time.Minute)
+	}
+}
+
+// Save persists usage statistics to the store. Project and API key usage are saved together.
+func (ut *UsageTracker) Save() error {
+	ut.saveMu.Lock()
+	defer ut.saveMu.Unlock()
+
+	// Marshal to JSON
+	ut.mu.RLock()
+	data, err := json.MarshalIndent(ut.usageMap, "", "  ")
+	if err != nil {
+		ut.mu.RUnlock()
+		log.Printf("[ERROR] Failed to marshal usage stats: %v", err)
+		return err
+	}
+
+	// Per API key usage is kept in its own document so usage_stats.json keeps its format
+	keyData, err := json.MarshalIndent(ut.keyUsageMap, "", "  ")
+	ut.mu.RUnlock()
+	if err != nil {
+		log.Printf("[ERROR] Failed to marshal API key usage: %v", err)
+		return err
+	}
+
+	if err := ut.store.SaveState(map[string][]byte{
+		storage.StateUsage:    data,
+		storage.StateKeyUsage: keyData,
+	}); err != nil {
+		log.Printf("[ERROR] Failed to write usage stats: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// Load reads usage statistics from the store
+func (ut *UsageTracker) Load() error {
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+
+	data, _, err := ut.store.LoadState(storage.StateUsage)
+	if errors.Is(err, storage.ErrNotFound) {
+		log.Printf("[INFO] Usage stats file does not exist, starting fresh")
+		return nil
+	}
+	if err != nil {
+		log.Printf("[ERROR] Failed to read usage stats: %v", err)
+		return err
+	}
+
+	// Unmarshal JSON
+	if err := json.Unmarshal(data, &ut.usageMap); err != nil {
+		log.Printf("[ERROR] Failed to unmarshal usage stats: %v", err)
+		return err
+	}
+
+	log.Printf("[INFO] Loaded usage statistics for %d projects", len(ut.usageMap))
+
+	// Load per API key usage if present
+	if keyData, _, err := ut.store.LoadState(storage.StateKeyUsage); err == nil {
+		if err := json.Unmarshal(keyData, &ut.keyUsageMap); err != nil {
+			log.Printf("[ERROR] Failed to unmarshal API key usage: %v", err)
+		}
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		log.Printf("[ERROR] Failed to read API key usage: %v", err)
+	}
+
+	return nil
+}
+
+// CheckAndResetIfNeeded checks all projects and resets usage if the reset time has passed
+// This should be called on startup to handle cases where the program was not running during reset time
+func (ut *UsageTracker) CheckAndResetIfNeeded() {
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+
+	resetCount := 0
+	nextReset := ut.getNextResetTime()
+
+	for projectID, usage := range ut.usageMap {
+		if ut.shouldReset(usage.LastResetTime) {
+			log.Printf("[INFO] Resetting usage for project %s (last reset: %v, next reset: %v)",
+				projectID, usage.LastResetTime, nextReset)
+			usage.ProModelCount = 0
+			usage.OverallCount = 0
+			usage.LastResetTime = ut.getLastResetTime()
+			resetCount++
+		}
+	}
+	for _, keyUsage := range ut.keyUsageMap {
+		if ut.shouldReset(keyUsage.LastResetTime) {
+			keyUsage.RequestCount = 0
+			keyUsage.TokenCount = 0
+			keyUsage.LastResetTime = ut.getLastResetTime()
+			resetCount++
+		}
+	}
+
+	if resetCount > 0 {
+		log.Printf("[INFO] Reset usage statistics for %d projects on startup", resetCount)
+		// Save the reset state to disk
+		ut.mu.Unlock()
+		ut.Save()
+		ut.mu.Lock()
+	} else {
+		log.Printf("[INFO] No usage reset needed on startup")
+	}
+}
+
+// IsProModel checks if a model counts against the pro quota, using the class set in the model registry
+func IsProModel(modelName string) bool {
+	if entry, ok := config.GetModelRegistry().Lookup(modelName); ok {
+		return entry.Class == config.ModelClassPro
+	}
+
+	// Models missing from the registry are classified by name
+	// This covers: gemini-2.5-pro, gemini-2.5-pro-preview-*, etc.
+	return len(modelName) >= 3 &&
+		(modelName[len(modelName)-3:] == "pro" ||
+			len(modelName) >= 4 && modelName[len(modelName)-4:len(modelName)-1] == "pro-")
+}
+
+// markDirty marks the tracker as having unsaved changes
+func (ut *UsageTracker) markDirty() {
+	ut.dirtyMu.Lock()
+	ut.dirty = true
+	ut.dirtyMu.Unlock()
+}
+
+// autoSaveLoop runs a background goroutine that saves usage stats every 5 seconds if dirty
+// This batches disk writes to improve performance under high load
+func (ut *UsageTracker) autoSaveLoop() {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		// Clear the flag before saving so changes made during the save are saved next time
+		ut.dirtyMu.Lock()
+		isDirty := ut.dirty
+		ut.dirty = false
+		ut.dirtyMu.Unlock()
+
+		if isDirty {
+			if err := ut.Save(); err != nil {
+				log.Printf("[ERROR] Auto-save failed: %v", err)
+				ut.markDirty()
+			} else {
+				ut.dirtyMu.Lock()
+				ut.lastSaveTime = time.Now()
+				ut.dirtyMu.Unlock()
+			}
+		}
+	}
+}
@@ -1,338 +1,527 @@
-package main
-
-import (
-	"encoding/json"
-	"fmt"
-	"log"
-	"net/http"
-	"os"
-	"os/signal"
-	"path/filepath"
-	"strings"
-
-	"gcli2apigo/internal/auth"
-	"gcli2apigo/internal/banlist"
-	"gcli2apigo/internal/config"
-	"gcli2apigo/internal/dashboard"
-	"gcli2apigo/internal/i18n"
-	"gcli2apigo/internal/routes"
-	"gcli2apigo/internal/usage"
-
-	"github.com/joho/godotenv"
-)
-
-func main() {
-	// Configure logging
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
-
-	// Explicitly load .env file
-	// This ensures settings are loaded even if autoload doesn't work
-	if err := godotenv.Load(); err != nil {
-		log.Printf("[WARN] No .env file found or error loading: %v", err)
-		log.Printf("[INFO] Using default configuration or environment variables")
-	} else {
-		log.Printf("[INFO] Loaded configuration from .env file")
-		// Log loaded settings (excluding password for security)
-		log.Printf("[INFO] Configuration: HOST=%s, PORT=%s, MAX_RETRY_ATTEMPTS=%s",
-			os.Getenv("HOST"), os.Getenv("PORT"), os.Getenv("MAX_RETRY_ATTEMPTS"))
-	}
-
-	// Reload config to pick up values from .env
-	config.ReloadConfig()
-
-	// Get server configuration
-	host := os.Getenv("HOST")
-	if host == "" {
-		host = "0.0.0.0"
-	}
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "7860"
-	}
-
-	// Initialize application
-	log.Println("Starting Gemini proxy server...")
-	if err := initializeApplication(); err != nil {
-		log.Printf("Warning: Application initialization error: %v", err)
-		log.Println("Server will continue but some features may not work correctly")
-	}
-
-	// Initialize dashboard handlers
-	dashboardHandlers := dashboard.NewDashboardHandlers()
-	oauthHandler := dashboard.NewOAuthHandler()
-
-	// Setup routes
-	mux := http.NewServeMux()
-
-	// Health check endpoint (no auth required)
-	mux.HandleFunc("/health", handleHealth)
-
-	// Dashboard routes
-	mux.HandleFunc("/dashboard/login", dashboardHandlers.HandleLogin)
-	mux.HandleFunc("/dashboard/logout", dashboardHandlers.HandleLogout)
-	mux.HandleFunc("/dashboard/oauth/start", dashboardHandlers.RequireAuth(oauthHandler.StartOAuthFlow))
-	mux.HandleFunc("/dashboard/oauth/callback", oauthHandler.HandleCallback)
-	mux.HandleFunc("/dashboard/oauth/process", oauthHandler.HandleOAuthProcess)
-	mux.HandleFunc("/dashboard/api/credentials", dashboardHandlers.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet {
-			dashboardHandlers.HandleListCredentials(w, r)
-		} else if r.Method == http.MethodDelete {
-			// This handles DELETE /dashboard/api/credentials (without ID)
-			http.Error(w, "Project ID required", http.StatusBadRequest)
-		} else {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	}))
-
-	// Dashboard API route for uploading credentials
-	mux.HandleFunc("/dashboard/api/credentials/upload", dashboardHandlers.RequireAuth(dashboardHandlers.HandleUploadCredentials))
-
-	// Dashboard API routes for banning/unbanning credentials
-	mux.HandleFunc("/dashboard/api/credentials/ban", dashboardHandlers.RequireAuth(dashboardHandlers.HandleBanCredential))
-	mux.HandleFunc("/dashboard/api/credentials/unban", dashboardHandlers.RequireAuth(dashboardHandlers.HandleUnbanCredential))
-
-	// Dashboard API route for stats
-	mux.HandleFunc("/dashboard/api/stats", dashboardHandlers.RequireAuth(dashboardHandlers.HandleDashboardStats))
-
-	// Dashboard API routes for language
-	mux.HandleFunc("/dashboard/api/language", dashboardHandlers.HandleSetLanguage)
-	mux.HandleFunc("/dashboard/api/translations", dashboardHandlers.HandleGetTranslations)
-
-	// Dashboard API routes for settings
-	mux.HandleFunc("/dashboard/api/settings", dashboardHandlers.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet {
-			dashboardHandlers.HandleGetSettings(w, r)
-		} else if r.Method == http.MethodPost {
-			dashboardHandlers.HandleSaveSettings(w, r)
-		} else {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	}))
-
-	// Dashboard API route for deleting specific credentials
-	// Pattern: /dashboard/api/credentials/{project_id}
-	mux.HandleFunc("/dashboard/api/credentials/", dashboardHandlers.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodDelete {
-			dashboardHandlers.HandleDeleteCredential(w, r)
-		} else {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	}))
-
-	// OpenAI-compatible routes
-	mux.HandleFunc("/v1/chat/completions", routes.HandleChatCompletions)
-	mux.HandleFunc("/v1/models", routes.HandleListModels)
-
-	// Gemini routes
-	mux.HandleFunc("/v1beta/models", routes.HandleGeminiListModels)
-
-	// Google APIs proxy routes
-	mux.HandleFunc("/googleapis", routes.HandleGoogleAPIsInfo)
-	mux.HandleFunc("/googleapis/", routes.HandleGoogleAPIsProxy)
-
-	// Catch-all for Gemini proxy and root
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/" {
-			handleRoot(w, r, dashboardHandlers)
-		} else {
-			routes.HandleGeminiProxy(w, r)
-		}
-	})
-
-	// Wrap with CORS middleware
-	handler := corsMiddleware(mux)
-
-	// Setup graceful shutdown
-	setupGracefulShutdown()
-
-	// Start server
-	addr := fmt.Sprintf("%s:%s", host, port)
-	log.Printf("Server listening on %s", addr)
-	log.Println("Authentication required - Password: see .env file")
-
-	if err := http.ListenAndServe(addr, handler); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
-	}
-}
-
-func handleRoot(w http.ResponseWriter, r *http.Request, dashboardHandlers *dashboard.DashboardHandlers) {
-	if r.URL.Path != "/" {
-		http.NotFound(w, r)
-		return
-	}
-
-	// Content negotiation: check Accept header to determine response type
-	acceptHeader := r.Header.Get("Accept")
-
-	// If client explicitly requests JSON, return API info
-	if strings.Contains(acceptHeader, "application/json") {
-		handleAPIInfo(w, r)
-		return
-	}
-
-	// For browser requests (text/html or no specific Accept header), show dashboard
-	// Check if user is authenticated
-	cookie, err := r.Cookie("session_id")
-	if err != nil || cookie.Value == "" || !dashboardHandlers.GetSessionManager().ValidateSession(cookie.Value) {
-		// Not authenticated, show login page
-		lang := i18n.GetLanguageFromRequest(r)
-		dashboard.RenderLogin(w, "", lang)
-		return
-	}
-
-	// Authenticated, show dashboard
-	dashboardHandlers.HandleDashboard(w, r)
-}
-
-func handleAPIInfo(w http.ResponseWriter, r *http.Request) {
-	response := map[string]any{
-		"name":        "gcli2apigo",
-		"description": "OpenAI-compatible API proxy for Google's Gemini models via gemini-cli with Google APIs proxy support",
-		"purpose":     "Provides both OpenAI-compatible endpoints (/v1/chat/completions), native Gemini API endpoints for accessing Google's Gemini models, and Google APIs proxy functionality",
-		"version":     "1.0.0",
-		"endpoints": map[string]any{
-			"openai_compatible": map[string]string{
-				"chat_completions": "/v1/chat/completions",
-				"models":           "/v1/models",
-			},
-			"native_gemini": map[string]string{
-				"models":   "/v1beta/models",
-				"generate": "/v1beta/models/{model}/generateContent",
-				"stream":   "/v1beta/models/{model}/streamGenerateContent",
-			},
-			"dashboard": map[string]string{
-				"login":       "/dashboard/login",
-				"logout":      "/dashboard/logout",
-				"oauth_start": "/dashboard/oauth/start",
-				"credentials": "/dashboard/api/credentials",
-			},
-			"googleapis_proxy": map[string]string{
-				"info":    "/googleapis",
-				"proxy":   "/googleapis/{api_path}",
-				"example": "/googleapis/storage/v1/b",
-			},
-			"health": "/health",
-		},
-		"authentication": "Required for all endpoints except root and health",
-		"repository":     "https://github.com/Hype3808/gcli2apigo",
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
-
-func handleHealth(w http.ResponseWriter, r *http.Request) {
-	response := map[string]string{
-		"status":  "healthy",
-		"service": "gcli2apigo",
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
-
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, PATCH, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "*")
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
-
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
-
-// initializeApplication initializes all required directories and components
-func initializeApplication() error {
-	// Ensure oauth_creds directory exists
-	credsDir := "oauth_creds"
-	if err := os.MkdirAll(credsDir, 0700); err != nil {
-		log.Printf("Error: Failed to create oauth_creds directory: %v", err)
-		return fmt.Errorf("failed to create oauth_creds directory: %w", err)
-	}
-	log.Printf("Ensured oauth_creds directory exists: %s", credsDir)
-
-	// Initialize credential pool
-	if err := auth.InitializeCredentialPool(); err != nil {
-		log.Printf("Warning: Credential pool initialization error: %v", err)
-		// Don't return error, just log warning
-	}
-
-	// Initialize banlist (this will create banlist.json if needed)
-	banlist := banlist.GetBanList()
-	log.Printf("Initialized banlist with %d banned projects", len(banlist.GetBannedProjects()))
-
-	// Initialize usage tracker (this will create usage_stats.json if needed)
-	tracker := usage.GetTracker()
-	allUsage := tracker.GetAllUsage()
-	log.Printf("Initialized usage tracker with %d project records", len(allUsage))
-
-	// Check and reset usage stats if needed (handles cases where program was not running during reset time)
-	tracker.CheckAndResetIfNeeded()
-
-	// Ensure JSON files exist with empty defaults
-	ensureJSONFiles(credsDir)
-
-	return nil
-}
-
-// ensureJSONFiles creates empty JSON files if they don't exist
-func ensureJSONFiles(credsDir string) {
-	// Ensure banlist.json exists
-	banlistPath := filepath.Join(credsDir, "banlist.json")
-	if _, err := os.Stat(banlistPath); os.IsNotExist(err) {
-		emptyBanlist := make(map[string]bool)
-		if data, err := json.MarshalIndent(emptyBanlist, "", "  "); err == nil {
-			if err := os.WriteFile(banlistPath, data, 0600); err == nil {
-				log.Printf("Created empty banlist.json file")
-			}
-		}
-	}
-
-	// Ensure usage_stats.json exists
-	usageStatsPath := filepath.Join(credsDir, "usage_stats.json")
-	if _, err := os.Stat(usageStatsPath); os.IsNotExist(err) {
-		emptyUsageStats := make(map[string]any)
-		if data, err := json.MarshalIndent(emptyUsageStats, "", "  "); err == nil {
-			if err := os.WriteFile(usageStatsPath, data, 0600); err == nil {
-				log.Printf("Created empty usage_stats.json file")
-			}
-		}
-	}
-}
-
-// setupGracefulShutdown sets up signal handlers for graceful shutdown
-func setupGracefulShutdown() {
-	// Note: On Windows, os.Interrupt is the only signal that works reliably
-	// SIGTERM is not supported on Windows
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt)
-
-	go func() {
-		<-sigChan
-		log.Println("\nReceived interrupt signal, shutting down gracefully...")
-
-		// Save usage stats
-		if err := usage.GetTracker().Save(); err != nil {
-			log.Printf("Warning: Failed to save usage stats: %v", err)
-		} else {
-			log.Println("Usage stats saved successfully")
-		}
-
-		// Save banlist
-		if err := banlist.GetBanList().Save(); err != nil {
-			log.Printf("Warning: Failed to save banlist: %v", err)
-		} else {
-			log.Println("Banlist saved successfully")
-		}
-
-		log.Println("Shutdown complete")
-		os.Exit(0)
-	}()
-}
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+
+	"gcli2apigo/internal/apikeys"
+	"gcli2apigo/internal/auth"
+	"gcli2apigo/internal/banlist"
+	"gcli2apigo/internal/client"
+	"gcli2apigo/internal/config"
+	"gcli2apigo/internal/credcrypt"
+	"gcli2apigo/internal/dashboard"
+	"gcli2apigo/internal/i18n"
+	"gcli2apigo/internal/metrics"
+	"gcli2apigo/internal/routes"
+	"gcli2apigo/internal/storage"
+	"gcli2apigo/internal/usage"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	// Configure logging
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+
+	// Explicitly load .env file
+	// This ensures settings are loaded even if autoload doesn't work
+	if err := godotenv.Load(); err != nil {
+		log.Printf("[WARN] No .env file found or error loading: %v", err)
+		log.Printf("[INFO] Using default configuration or environment variables")
+	} else {
+		log.Printf("[INFO] Loaded configuration from .env file")
+		// Log loaded settings (excluding password for security)
+		log.Printf("[INFO] Configuration: HOST=%s, PORT=%s, MAX_RETRY_ATTEMPTS=%s",
+			os.Getenv("HOST"), os.Getenv("PORT"), os.Getenv("MAX_RETRY_ATTEMPTS"))
+	}
+
+	// Reload config to pick up values from .env
+	config.ReloadConfig()
+
+	// Run a maintenance command instead of the server, e.g. "gcli2apigo credentials encrypt"
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
+	// Get server configuration
+	host := os.Getenv("HOST")
+	if host == "" {
+		host = "0.0.0.0"
+	}
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "7860"
+	}
+
+	// Initialize application
+	log.Println("Starting Gemini proxy server...")
+	if err := initializeApplication(); err != nil {
+		log.Printf("Warning: Application initialization error: %v", err)
+		log.Println("Server will continue but some features may not work correctly")
+	}
+
+	// Initialize dashboard handlers
+	dashboardHandlers := dashboard.NewDashboardHandlers()
+	oauthHandler := dashboard.NewOAuthHandler()
+
+	// Setup routes
+	mux := http.NewServeMux()
+
+	// Health check endpoint (no auth required)
+	mux.HandleFunc("/health", handleHealth)
+
+	// Dashboard routes
+	mux.HandleFunc("/dashboard/login", dashboardHandlers.HandleLogin)
+	mux.HandleFunc("/dashboard/logout", dashboardHandlers.HandleLogout)
+	mux.HandleFunc("/dashboard/oauth/start", dashboardHandlers.RequireAuth(oauthHandler.StartOAuthFlow))
+	mux.HandleFunc("/dashboard/oauth/callback", oauthHandler.HandleCallback)
+	mux.HandleFunc("/dashboard/oauth/process", oauthHandler.HandleOAuthProcess)
+	mux.HandleFunc("/dashboard/api/credentials", dashboardHandlers.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			dashboardHandlers.HandleListCredentials(w, r)
+		} else if r.Method == http.MethodDelete {
+			// This handles DELETE /dashboard/api/credentials (without ID)
+			http.Error(w, "Project ID required", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Dashboard API route for uploading credentials
+	mux.HandleFunc("/dashboard/api/credentials/upload", dashboardHandlers.RequireAuth(dashboardHandlers.HandleUploadCredentials))
+
+	// Dashboard API routes for banning/unbanning credentials
+	mux.HandleFunc("/dashboard/api/credentials/ban", dashboardHandlers.RequireAuth(dashboardHandlers.HandleBanCredential))
+	mux.HandleFunc("/dashboard/api/credentials/unban", dashboardHandlers.RequireAuth(dashboardHandlers.HandleUnbanCredential))
+	mux.HandleFunc("/dashboard/api/credentials/bans", dashboardHandlers.RequireAuth(dashboardHandlers.HandleBanHistory))
+
+	// Dashboard API routes for named API keys
+	mux.HandleFunc("/dashboard/api/keys", dashboardHandlers.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			dashboardHandlers.HandleListAPIKeys(w, r)
+		} else if r.Method == http.MethodPost {
+			dashboardHandlers.HandleCreateAPIKey(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+	mux.HandleFunc("/dashboard/api/keys/update", dashboardHandlers.RequireAuth(dashboardHandlers.HandleUpdateAPIKey))
+	mux.HandleFunc("/dashboard/api/keys/revoke", dashboardHandlers.RequireAuth(dashboardHandlers.HandleRevokeAPIKey))
+	// Pattern: /dashboard/api/keys/{id}
+	mux.HandleFunc("/dashboard/api/keys/", dashboardHandlers.RequireAuth(dashboardHandlers.HandleDeleteAPIKey))
+
+	// Dashboard API route for stats
+	mux.HandleFunc("/dashboard/api/stats", dashboardHandlers.RequireAuth(dashboardHandlers.HandleDashboardStats))
+
+	// Dashboard API routes for language
+	mux.HandleFunc("/dashboard/api/language", dashboardHandlers.HandleSetLanguage)
+	mux.HandleFunc("/dashboard/api/translations", dashboardHandlers.HandleGetTranslations)
+
+	// Dashboard API routes for settings
+	mux.HandleFunc("/dashboard/api/settings", dashboardHandlers.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			dashboardHandlers.HandleGetSettings(w, r)
+		} else if r.Method == http.MethodPost {
+			dashboardHandlers.HandleSaveSettings(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Dashboard API routes for the model registry
+	mux.HandleFunc("/dashboard/api/models", dashboardHandlers.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			dashboardHandlers.HandleGetModels(w, r)
+		} else if r.Method == http.MethodPost {
+			dashboardHandlers.HandleSaveModels(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Dashboard API route for deleting specific credentials
+	// Pattern: /dashboard/api/credentials/{project_id}
+	mux.HandleFunc("/dashboard/api/credentials/", dashboardHandlers.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			dashboardHandlers.HandleDeleteCredential(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// OpenAI-compatible routes
+	mux.HandleFunc("/v1/chat/completions", metrics.Instrument("chat_completions", routes.WithRetryHeaders(routes.HandleChatCompletions)))
+	mux.HandleFunc("/v1/chat/completions/count", metrics.Instrument("chat_completions_count", routes.WithRetryHeaders(routes.HandleChatCompletionsCount)))
+	mux.HandleFunc("/v1/models", routes.HandleListModels)
+	mux.HandleFunc("/v1/completions", metrics.Instrument("completions", routes.WithRetryHeaders(routes.HandleCompletions)))
+	mux.HandleFunc("/v1/embeddings", metrics.Instrument("embeddings", routes.WithRetryHeaders(routes.HandleEmbeddings)))
+	responses := metrics.Instrument("responses", routes.WithRetryHeaders(routes.HandleResponses))
+	mux.HandleFunc("/v1/responses", responses)
+	mux.HandleFunc("/v1/responses/", responses)
+
+	// Anthropic-compatible routes
+	mux.HandleFunc("/v1/messages", metrics.Instrument("messages", routes.WithRetryHeaders(routes.HandleMessages)))
+
+	// Gemini routes
+	mux.HandleFunc("/v1beta/models", routes.HandleGeminiListModels)
+
+	// Google APIs proxy routes
+	mux.HandleFunc("/googleapis", routes.HandleGoogleAPIsInfo)
+	mux.HandleFunc("/googleapis/", metrics.Instrument("googleapis", routes.HandleGoogleAPIsProxy))
+
+	// Prometheus metrics (requires METRICS_TOKEN)
+	mux.HandleFunc("/metrics", metrics.HandleMetrics)
+
+	// Catch-all for Gemini proxy and root
+	geminiProxy := metrics.Instrument("gemini", routes.WithRetryHeaders(routes.HandleGeminiProxy))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			handleRoot(w, r, dashboardHandlers)
+		} else {
+			geminiProxy(w, r)
+		}
+	})
+
+	// Wrap with CORS middleware
+	handler := corsMiddleware(mux)
+
+	// Setup graceful shutdown
+	setupGracefulShutdown()
+
+	// Start server
+	addr := fmt.Sprintf("%s:%s", host, port)
+	log.Printf("Server listening on %s", addr)
+	log.Println("Authentication required - Password: see .env file")
+
+	if err := http.ListenAndServe(addr, handler); err != nil {
+		log.Fatalf("Server failed to start: %v", err)
+	}
+}
+
+func handleRoot(w http.ResponseWriter, r *http.Request, dashboardHandlers *dashboard.DashboardHandlers) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Content negotiation: check Accept header to determine response type
+	acceptHeader := r.Header.Get("Accept")
+
+	// If client explicitly requests JSON, return API info
+	if strings.Contains(acceptHeader, "application/json") {
+		handleAPIInfo(w, r)
+		return
+	}
+
+	// For browser requests (text/html or no specific Accept header), show dashboard
+	// Check if user is authenticated
+	cookie, err := r.Cookie("session_id")
+	if err != nil || cookie.Value == "" || !dashboardHandlers.GetSessionManager().ValidateSession(cookie.Value) {
+		// Not authenticated, show login page
+		lang := i18n.GetLanguageFromRequest(r)
+		dashboard.RenderLogin(w, "", lang)
+		return
+	}
+
+	// Authenticated, show dashboard
+	dashboardHandlers.HandleDashboard(w, r)
+}
+
+func handleAPIInfo(w http.ResponseWriter, r *http.Request) {
+	response := map[string]any{
+		"name":        "gcli2apigo",
+		"description": "OpenAI-compatible API proxy for Google's Gemini models via gemini-cli with Google APIs proxy support",
+		"purpose":     "Provides both OpenAI-compatible endpoints (/v1/chat/completions), native Gemini API endpoints for accessing Google's Gemini models, and Google APIs proxy functionality",
+		"version":     "1.0.0",
+		"endpoints": map[string]any{
+			"openai_compatible": map[string]string{
+				"chat_completions": "/v1/chat/completions",
+				"models":           "/v1/models",
+				"embeddings":       "/v1/embeddings",
+			},
+			"anthropic_compatible": map[string]string{
+				"messages": "/v1/messages",
+			},
+			"native_gemini": map[string]string{
+				"models":   "/v1beta/models",
+				"generate": "/v1beta/models/{model}/generateContent",
+				"stream":   "/v1beta/models/{model}/streamGenerateContent",
+				"embed":    "/v1beta/models/{model}:embedContent",
+			},
+			"dashboard": map[string]string{
+				"login":       "/dashboard/login",
+				"logout":      "/dashboard/logout",
+				"oauth_start": "/dashboard/oauth/start",
+				"credentials": "/dashboard/api/credentials",
+				"api_keys":    "/dashboard/api/keys",
+			},
+			"googleapis_proxy": map[string]string{
+				"info":    "/googleapis",
+				"proxy":   "/googleapis/{api_path}",
+				"example": "/googleapis/storage/v1/b",
+			},
+			"health": "/health",
+		},
+		"authentication": "Required for all endpoints except root and health",
+		"repository":     "https://github.com/Hype3808/gcli2apigo",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+	response := map[string]string{
+		"status":  "healthy",
+		"service": "gcli2apigo",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, PATCH, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "*")
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Expose-Headers", routes.RetriesHeader+", "+routes.RetryReasonsHeader)
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// initializeApplication initializes all required directories and components
+func initializeApplication() error {
+	// Ensure oauth_creds directory exists
+	credsDir := config.OAuthCredsFolder
+	if err := os.MkdirAll(credsDir, 0700); err != nil {
+		log.Printf("Error: Failed to create oauth_creds directory: %v", err)
+		return fmt.Errorf("failed to create oauth_creds directory: %w", err)
+	}
+	log.Printf("Ensured oauth_creds directory exists: %s", credsDir)
+
+	// Report credential encryption; a broken key would otherwise only surface on the first read or write
+	if key, err := credcrypt.LoadKey(); err != nil {
+		log.Printf("[ERROR] Credential encryption key is invalid, encrypted credentials cannot be read or saved: %v", err)
+	} else if key != nil {
+		log.Printf("[INFO] Credential files are encrypted at rest")
+	}
+
+	// Initialize credential pool
+	if err := auth.InitializeCredentialPool(); err != nil {
+		log.Printf("Warning: Credential pool initialization error: %v", err)
+		// Don't return error, just log warning
+	}
+
+	// Initialize banlist (loaded from storage)
+	banlist := banlist.GetBanList()
+	log.Printf("Initialized banlist with %d banned projects", len(banlist.GetBannedProjects()))
+
+	// Initialize named API keys
+	keyStore := apikeys.GetKeyStore()
+	log.Printf("Initialized API key store with %d keys", len(keyStore.List()))
+
+	// Load the model registry (models.json, or the built-in models when it does not exist)
+	modelRegistry := config.GetModelRegistry()
+	log.Printf("Initialized model registry with %d models (source: %s)", len(modelRegistry.Entries()), modelRegistry.Source())
+
+	// Initialize usage tracker (loaded from storage)
+	tracker := usage.GetTracker()
+	allUsage := tracker.GetAllUsage()
+	log.Printf("Initialized usage tracker with %d project records", len(allUsage))
+
+	// Check and reset usage stats if needed (handles cases where program was not running during reset time)
+	tracker.CheckAndResetIfNeeded()
+
+	// Ensure state documents exist with empty defaults
+	ensureStateFiles()
+
+	// Expose credential pool gauges on /metrics
+	registerPoolMetrics()
+
+	// Renew access tokens before they expire, once the ban list is loaded
+	client.StartTokenRefresher()
+
+	return nil
+}
+
+// registerPoolMetrics registers gauges computed from the credential pool and ban list at scrape time
+func registerPoolMetrics() {
+	metrics.NewGaugeFunc("gcli2api_credentials_total",
+		"Credentials loaded in the pool, including banned and cooling down ones.",
+		func() float64 { return float64(auth.GetCredentialPoolTotal()) })
+	metrics.NewGaugeFunc("gcli2api_credentials_available",
+		"Credentials that are neither banned nor cooling down in the circuit breaker.",
+		func() float64 { return float64(auth.GetCredentialPoolSize()) })
+	metrics.NewGaugeFunc("gcli2api_credentials_banned",
+		"Credentials currently on the ban list.",
+		func() float64 { return float64(len(banlist.GetBanList().GetBannedProjects())) })
+}
+
+// runCommand runs a command-line maintenance command and returns the process exit code
+func runCommand(args []string) int {
+	usageText := `Usage:
+  gcli2apigo                                Start the server
+  gcli2apigo credentials genkey             Print a new key for CREDENTIALS_ENCRYPTION_KEY
+  gcli2apigo credentials encrypt [folder]   Encrypt the credential files in a folder
+  gcli2apigo credentials decrypt [folder]   Decrypt the credential files in a folder
+
+The folder defaults to OAUTH_CREDS_FOLDER; with STORAGE_BACKEND=sqlite and no folder,
+the credentials in the SQLite database are converted instead. Encrypting and decrypting use the key
+from CREDENTIALS_ENCRYPTION_KEY or CREDENTIALS_ENCRYPTION_KEY_FILE.`
+
+	if len(args) < 2 || args[0] != "credentials" {
+		fmt.Fprintln(os.Stderr, usageText)
+		return 2
+	}
+
+	switch args[1] {
+	case "genkey":
+		key, err := credcrypt.GenerateKey()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Println(key)
+		return 0
+	case "encrypt", "decrypt":
+		return migrateCredentials(args[1] == "encrypt", args[2:])
+	default:
+		fmt.Fprintln(os.Stderr, usageText)
+		return 2
+	}
+}
+
+// migrateCredentials encrypts or decrypts the credential files in a folder and the legacy credential file
+func migrateCredentials(encrypt bool, args []string) int {
+	folder := config.OAuthCredsFolder
+	if len(args) > 0 {
+		folder = args[0]
+	}
+	action := "Decrypted"
+	if encrypt {
+		action = "Encrypted"
+	}
+
+	// With SQLite storage the credentials live in the database, not in the folder
+	if len(args) == 0 && config.GetStorageBackend() == "sqlite" {
+		store, err := storage.OpenSQLiteStore(config.GetSQLitePath(), config.OAuthCredsFolder)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer store.Close()
+
+		changed, err := store.Reseal(encrypt)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("%s %d credential(s) in %s\n", action, changed, config.GetSQLitePath())
+		return 0
+	}
+
+	result, err := credcrypt.MigrateFolder(folder, encrypt)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	// The legacy credential file lives outside the folder; only migrate it along with the default folder
+	if len(args) == 0 {
+		if _, err := os.Stat(config.CredentialFile); err == nil {
+			changed, err := credcrypt.MigrateFile(config.CredentialFile, encrypt)
+			if err != nil {
+				result.Failed = append(result.Failed, fmt.Sprintf("%s: %v", config.CredentialFile, err))
+			} else if changed {
+				result.Converted++
+			}
+		}
+	}
+
+	fmt.Printf("%s %d credential file(s) in %s (%d already done, %d other files skipped)\n",
+		action, result.Converted, folder, result.Unchanged, result.Skipped)
+	for _, failure := range result.Failed {
+		fmt.Fprintf(os.Stderr, "Failed: %s\n", failure)
+	}
+	if len(result.Failed) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// ensureStateFiles saves empty ban list and usage documents if they don't exist yet
+func ensureStateFiles() {
+	store := storage.GetStore()
+
+	// Ensure the ban list exists
+	if _, _, err := store.LoadState(storage.StateBanList); errors.Is(err, storage.ErrNotFound) {
+		if err := banlist.GetBanList().Save(); err == nil {
+			log.Printf("Created empty ban list")
+		}
+	}
+
+	// Ensure the usage stats exist
+	if _, _, err := store.LoadState(storage.StateUsage); errors.Is(err, storage.ErrNotFound) {
+		if err := usage.GetTracker().Save(); err == nil {
+			log.Printf("Created empty usage stats")
+		}
+	}
+}
+
+// setupGracefulShutdown sets up signal handlers for graceful shutdown
+func setupGracefulShutdown() {
+	// Note: On Windows, os.Interrupt is the only signal that works reliably
+	// SIGTERM is not supported on Windows
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt)
+
+	go func() {
+		<-sigChan
+		log.Println("\nReceived interrupt signal, shutting down gracefully...")
+
+		// Save usage stats
+		if err := usage.GetTracker().Save(); err != nil {
+			log.Printf("Warning: Failed to save usage stats: %v", err)
+		} else {
+			log.Println("Usage stats saved successfully")
+		}
+
+		// Save banlist
+		if err := banlist.GetBanList().Save(); err != nil {
+			log.Printf("Warning: Failed to save banlist: %v", err)
+		} else {
+			log.Println("Banlist saved successfully")
+		}
+
+		// Save API keys
+		if err := apikeys.GetKeyStore().Save(); err != nil {
+			log.Printf("Warning: Failed to save API keys: %v", err)
+		} else {
+			log.Println("API keys saved successfully")
+		}
+
+		// Flush and close the storage backend
+		if err := storage.GetStore().Close(); err != nil {
+			log.Printf("Warning: Failed to close storage: %v", err)
+		}
+
+		log.Println("Shutdown complete")
+		os.Exit(0)
+	}()
+}